@@ -1,63 +1,134 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/huangqi/photo-backend/internal/catalog"
 	"github.com/huangqi/photo-backend/internal/config"
+	"github.com/huangqi/photo-backend/internal/db"
+	"github.com/huangqi/photo-backend/internal/harvest"
 	"github.com/huangqi/photo-backend/internal/mcp"
+	"github.com/huangqi/photo-backend/internal/photo"
 	"github.com/huangqi/photo-backend/internal/server"
+	"github.com/huangqi/photo-backend/internal/storage"
 )
 
 func main() {
 	cfg := config.Load()
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(cfg, os.Args[2:])
+		return
+	}
+
 	if cfg.GinMode != "" {
 		gin.SetMode(cfg.GinMode)
 	}
 
-	// database, err := db.Connect(cfg)
-	// if err != nil {
-	// 	log.Fatalf("failed to connect database: %v", err)
-	// }
+	// 数据库为可选依赖，连接失败时依赖数据库的模块不可用
+	// 未配置 DB_DSN / DB_HOST 时只尝试一次默认地址，不在重试上阻塞启动
+	var dbReason string
+	connectCfg := cfg
+	if !cfg.DBConfigured {
+		connectCfg.DBConnectRetries = 0
+	}
+	database, err := db.ConnectWithRetry(context.Background(), connectCfg)
+	if err == nil && cfg.DBAutoMigrate {
+		var applied []db.Migration
+		applied, err = db.NewMigrator(database).Up(context.Background())
+		for _, m := range applied {
+			log.Printf("migrated: %d %s", m.Version, m.Name)
+		}
+		if err != nil {
+			if sqlDB, dbErr := database.DB(); dbErr == nil {
+				sqlDB.Close()
+			}
+		}
+	}
+	if err != nil {
+		database = nil
+		dbReason = fmt.Sprintf("database unavailable: %v", err)
+		log.Printf("warn: %s", dbReason)
+	}
 
 	var registry *mcp.ClientRegistry
 
-	mcpPath := "mcp.json"
-
-	if mcpCfg, err := config.LoadMCPConfig(mcpPath); err != nil {
-		log.Printf("warn: failed to load MCP config: %v", err)
+	mcpCfg, mcpErr := config.LoadMCPConfig(cfg.MCPConfigPath)
+	if mcpErr != nil {
+		log.Printf("warn: failed to load MCP config: %v", mcpErr)
 	} else {
+		var err error
 		registry, err = mcp.BuildTransportsFromMCPConfig(mcpCfg, &http.Client{Timeout: 30 * time.Second})
 		if err != nil {
 			log.Fatalf("failed to build MCP clients: %v", err)
 		}
 	}
 
-	// var xhsClient mcp.XHSClient
-	// if registry != nil {
-	// 	if client := registry.FindByKeyOrName("xhs"); client != nil {
-	// 		xhsClient = mcp.NewXHSClient(client)
-	// 	} else if client := registry.FindByKeyOrName("xiaohongshu"); client != nil {
-	// 		xhsClient = mcp.NewXHSClient(client)
-	// 	}
-	// }
-	// if xhsClient == nil {
-	// 	log.Fatalf("no XHS MCP client configured: set MCP_CONFIG_PATH")
-	// }
+	xhsClient, xhsReason := resolveXHSClient(cfg, registry, mcpErr)
+	var xhsSession *mcp.XHSSession
+	if xhsClient == nil {
+		log.Printf("warn: XHS module disabled: %s", xhsReason)
+	} else {
+		xhsSession = mcp.NewXHSSession(xhsClient, cfg.XHSSessionTTL)
+		go xhsSession.Run(context.Background(), cfg.XHSSessionCheckInterval)
+	}
 
-	var mapsClient mcp.MapsClient
-	if registry != nil {
-		if client := registry.FindByKeyOrName("maps"); client != nil {
-			mapsClient = mcp.NewMapsClient(client)
+	var catalogReason string
+	queryCatalog, err := catalog.Load(cfg.CatalogPath)
+	if err != nil {
+		catalogReason = fmt.Sprintf("query catalog %s not loaded: %v", cfg.CatalogPath, err)
+		log.Printf("warn: %s", catalogReason)
+	}
+
+	photoStore, err := storage.New(context.Background(), cfg)
+	photosReason := dbReason
+	if err != nil {
+		photosReason = fmt.Sprintf("blob storage unavailable: %v", err)
+		log.Printf("warn: %s", photosReason)
+	}
+
+	// 派生图生成与上传时的感知哈希计算共用解码并发上限
+	photoDecodes := photo.NewDecodeLimiter(cfg.PhotoVariantWorkers)
+	var variants *photo.Pipeline
+	if photoStore != nil {
+		specs, err := photo.ParseVariantSpecs(cfg.PhotoVariants)
+		if err != nil {
+			log.Printf("warn: photo variants disabled: %v", err)
+		} else if len(specs) > 0 {
+			variants = photo.NewPipeline(photoStore, specs, photoDecodes, cfg.PhotoVariantQueue)
+			variants.Run(context.Background())
 		}
 	}
 
+	var (
+		linkStore *db.LinkStore
+		xhsRepo   db.XHSRepository
+		spotRepo  db.SpotRepository
+		photoRepo db.PhotoRepository
+		albumRepo db.AlbumRepository
+	)
+	if database != nil {
+		linkStore = db.NewLinkStore(database)
+		xhsRepo = db.NewXHSRepository(database)
+		spotRepo = db.NewSpotRepository(database)
+		photoRepo = db.NewPhotoRepository(database)
+		albumRepo = db.NewAlbumRepository(database)
+	}
+	harvester, harvestReason := newHarvester(cfg, queryCatalog, xhsClient, xhsSession, linkStore)
+	if harvester != nil {
+		go harvester.Run(context.Background(), cfg.HarvestInterval)
+	} else if cfg.HarvestEnabled {
+		log.Printf("warn: harvest disabled: %s", harvestReason)
+	}
+
 	var baiduMapsClient mcp.BaiduMapsClient
 	if registry != nil {
 		if client := registry.FindByKeyOrName("baidu-maps"); client != nil {
@@ -65,7 +136,48 @@ func main() {
 		}
 	}
 
-	r := server.NewRouter(mapsClient, baiduMapsClient)
+	// 通用 maps 服务需精确配置为 "maps"，模糊匹配会误用 baidu-maps；未配置时用百度地点检索查找附近景点
+	var mapsClient mcp.MapsClient
+	if client := registry.FindByKey("maps"); client != nil {
+		mapsClient = mcp.NewMapsClient(client)
+	} else if baiduMapsClient != nil {
+		mapsClient = mcp.NewBaiduAttractionsClient(baiduMapsClient)
+		log.Printf("nearby attractions: using baidu-maps place search")
+	}
+
+	r := server.NewRouter(server.Deps{
+		Maps:                     mapsClient,
+		BaiduMaps:                baiduMapsClient,
+		XHS:                      xhsClient,
+		XHSSession:               xhsSession,
+		XHSUnavailableReason:     xhsReason,
+		XHSRepo:                  xhsRepo,
+		XHSCacheTTL:              cfg.XHSCacheTTL,
+		XHSCommentPosting:        cfg.XHSCommentPosting,
+		XHSCommentMinInterval:    cfg.XHSCommentMinInterval,
+		Catalog:                  queryCatalog,
+		CatalogUnavailableReason: catalogReason,
+		Harvester:                harvester,
+		Links:                    linkStore,
+		HarvestUnavailableReason: harvestReason,
+		Spots:                    spotRepo,
+		SpotsUnavailableReason:   dbReason,
+		Photos:                   photoRepo,
+		PhotoStore:               photoStore,
+		PhotosUnavailableReason:  photosReason,
+		PhotoMaxBytes:            cfg.UploadMaxBytes,
+		PhotoMaxPixels:           cfg.PhotoMaxPixels,
+		PhotoLocation:            photoLocation(cfg.PhotoTimezone),
+		PhotoVariants:            variants,
+		PhotoDecodes:             photoDecodes,
+		PhotoStripGPS:            cfg.PhotoStripGPS,
+		PhotoDupThreshold:        cfg.PhotoDupThreshold,
+		Albums:                   albumRepo,
+		AlbumsUnavailableReason:  dbReason,
+		DB:                       database,
+		DBUnavailableReason:      dbReason,
+		AdminToken:               cfg.AdminToken,
+	})
 
 	addr := fmt.Sprintf(":%d", cfg.Port)
 	if envPort := os.Getenv("PORT"); envPort != "" {
@@ -77,3 +189,70 @@ func main() {
 	}
 
 }
+
+// resolveXHSClient 按配置查找小红书 MCP 客户端，找不到时返回原因
+func resolveXHSClient(cfg config.Config, registry *mcp.ClientRegistry, mcpErr error) (mcp.XHSClient, string) {
+	if !cfg.XHSEnabled {
+		return nil, "disabled by XHS_ENABLED=false"
+	}
+	if mcpErr != nil {
+		return nil, fmt.Sprintf("MCP config %s not loaded: %v", cfg.MCPConfigPath, mcpErr)
+	}
+
+	keys := []string{"xhs", "xiaohongshu"}
+	if cfg.XHSServer != "" {
+		keys = []string{cfg.XHSServer}
+	}
+	for _, key := range keys {
+		if client := registry.FindByKeyOrName(key); client != nil {
+			return mcp.NewRateLimitedXHSClient(client, mcp.RateLimitConfig{
+				GlobalPerMinute:  cfg.XHSRateGlobalPerMinute,
+				GlobalBurst:      cfg.XHSRateGlobalBurst,
+				KeywordPerMinute: cfg.XHSRateKeywordPerMinute,
+				KeywordBurst:     cfg.XHSRateKeywordBurst,
+				MinSpacing:       cfg.XHSRateMinSpacing,
+				MaxWait:          cfg.XHSRateMaxWait,
+			}), ""
+		}
+	}
+	return nil, fmt.Sprintf("no active MCP server matching %v in %s", keys, cfg.MCPConfigPath)
+}
+
+// newHarvester 所需模块都可用且 HARVEST_ENABLED=true 时创建采集器，否则返回原因
+func newHarvester(cfg config.Config, cat *catalog.Catalog, xhs mcp.XHSClient, session *mcp.XHSSession, links *db.LinkStore) (*harvest.Harvester, string) {
+	switch {
+	case !cfg.HarvestEnabled:
+		return nil, "disabled by HARVEST_ENABLED=false"
+	case cat == nil:
+		return nil, "query catalog not loaded"
+	case xhs == nil:
+		return nil, "xhs module unavailable"
+	case links == nil:
+		return nil, "database unavailable"
+	}
+	return &harvest.Harvester{
+		Catalog: cat,
+		Search: func(ctx context.Context, keyword string, limit int) ([]mcp.XHSPost, error) {
+			var posts []mcp.XHSPost
+			err := session.Do(ctx, func() error {
+				var err error
+				posts, err = xhs.GetPostsByKeyword(ctx, keyword, limit)
+				return err
+			})
+			return posts, err
+		},
+		Store:      links,
+		PerQuery:   cfg.HarvestPerQuery,
+		QueryDelay: cfg.HarvestQueryDelay,
+	}, ""
+}
+
+// photoLocation 加载 PHOTO_TIMEZONE，失败时回退到 UTC+8
+func photoLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("warn: invalid PHOTO_TIMEZONE %q: %v; using UTC+8", name, err)
+		return time.FixedZone("UTC+8", 8*60*60)
+	}
+	return loc
+}
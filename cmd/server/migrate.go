@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/huangqi/photo-backend/internal/config"
+	"github.com/huangqi/photo-backend/internal/db"
+)
+
+const migrateUsage = "usage: server migrate up | down [steps] | status"
+
+// runMigrate 处理 migrate 子命令
+func runMigrate(cfg config.Config, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+
+	database, err := db.ConnectWithRetry(context.Background(), cfg)
+	if err != nil {
+		log.Fatalf("failed to connect database: %v", err)
+	}
+	migrator := db.NewMigrator(database)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("up   %d %s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("migrate up failed: %v", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				log.Fatalf("invalid steps %q", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			fmt.Printf("down %d %s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("migrate down failed: %v", err)
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("migrate status failed: %v", err)
+		}
+		for _, st := range statuses {
+			applied := "pending"
+			if st.AppliedAt != nil {
+				applied = st.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%-6d %-32s %s\n", st.Version, st.Name, applied)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+}
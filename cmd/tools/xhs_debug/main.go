@@ -55,6 +55,16 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
 	defer cancel()
 
+	// 长时间的 login / search_notes 调用期间打印进度与服务端日志
+	ctx = mcp.WithNotificationHandler(ctx, func(n mcp.Notification) {
+		switch {
+		case n.Progress != nil:
+			fmt.Printf("   ⏳ progress %v/%v %s\n", n.Progress.Progress, n.Progress.Total, n.Progress.Message)
+		case n.Log != nil:
+			fmt.Printf("   📋 [%s] %v\n", n.Log.Level, n.Log.Data)
+		}
+	})
+
 	// 先初始化 MCP 客户端
 	fmt.Println("Initializing MCP client...")
 	err := xhsClient.Initialize(ctx, "photo-backend-debug", "0.1.0")
@@ -96,13 +106,12 @@ func main() {
 	// Step 3: 如果找到帖子，尝试获取第一个帖子的内容
 	if len(posts) > 0 && !skipLogin {
 		fmt.Printf("\n📝 Step 3: 获取第一个帖子的内容...\n")
-		noteResult, err := xhsClient.CallTool(ctx, "get_note_content", map[string]any{
-			"url": posts[0].PostURL,
-		})
+		note, err := xhsClient.GetNoteContent(ctx, posts[0].PostURL)
 		if err != nil {
 			log.Printf("⚠️  Get note content failed: %v", err)
 		} else {
-			fmt.Printf("✓ Note content: %s\n", noteResult)
+			fmt.Printf("✓ Note: %s (by %s, %d likes)\n   tags: %v\n   images: %d\n%s\n",
+				note.Title, note.Author, note.Likes, note.Tags, len(note.ImageURLs), note.Content)
 		}
 	}
 
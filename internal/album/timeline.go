@@ -0,0 +1,296 @@
+package album
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/huangqi/photo-backend/internal/db"
+	"github.com/huangqi/photo-backend/internal/geo"
+	"github.com/huangqi/photo-backend/internal/mcp"
+)
+
+// 出行方式，与百度地图路线规划一致
+const (
+	ModeWalking = "walking"
+	ModeDriving = "driving"
+	ModeRiding  = "riding"
+	ModeTransit = "transit"
+)
+
+// Modes 支持的出行方式
+var Modes = []string{ModeWalking, ModeDriving, ModeRiding, ModeTransit}
+
+const (
+	// mergeRadiusKm 未关联地点的照片与上一站距离在此范围内时并入上一站
+	mergeRadiusKm = 0.2
+	// maxConcurrentCalls 生成时间线时并发调用百度地图的上限
+	maxConcurrentCalls = 4
+)
+
+// ErrMapsUnavailable 未配置百度地图，无法逆地理编码与规划路线
+var ErrMapsUnavailable = errors.New("baidu maps not configured")
+
+// Timeline 相册按天的行程：每天若干停留点，相邻停留点之间为一段路线
+type Timeline struct {
+	Mode string `json:"mode"`
+	Days []Day  `json:"days"`
+	// Undated 没有拍摄时间的照片，不参与行程
+	Undated []db.Photo `json:"undated,omitempty"`
+}
+
+// Day 一天的行程，Date 为相册时区下的 YYYY-MM-DD
+type Day struct {
+	Date  string `json:"date"`
+	Stops []Stop `json:"stops"`
+	Legs  []Leg  `json:"legs"`
+	// DistanceM / DurationS 当天各段路线之和，不含规划失败的路段
+	DistanceM int `json:"distance_m"`
+	DurationS int `json:"duration_s"`
+}
+
+// Stop 一个停留点：同一机位、同一百度地点或相距很近的连续照片
+type Stop struct {
+	SpotID   *uint  `json:"spot_id,omitempty"`
+	PlaceUID string `json:"place_uid,omitempty"`
+	Name     string `json:"name"`
+	Address  string `json:"address,omitempty"`
+	City     string `json:"city,omitempty"`
+	District string `json:"district,omitempty"`
+	// BD09 停留点坐标，照片与关联地点都没有坐标时为空
+	BD09      *geo.Point `json:"bd09,omitempty"`
+	ArrivedAt time.Time  `json:"arrived_at"`
+	LeftAt    time.Time  `json:"left_at"`
+	Photos    []db.Photo `json:"photos"`
+	// GeocodeError 逆地理编码失败的原因，Address 等字段可能为空
+	GeocodeError string `json:"geocode_error,omitempty"`
+}
+
+// Leg 同一天相邻两个停留点之间的路线，From/To 为当天 Stops 的下标
+type Leg struct {
+	From int    `json:"from"`
+	To   int    `json:"to"`
+	Mode string `json:"mode"`
+	// StraightKm 直线距离，路线规划失败时可作参考
+	StraightKm float64 `json:"straight_km"`
+	DistanceM  int     `json:"distance_m,omitempty"`
+	DurationS  int     `json:"duration_s,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// Build 由按拍摄时间排好序的相册照片生成时间线。maps 为 nil 时只按已有信息分组，
+// 逆地理编码与路线规划的失败记录在对应的停留点与路段中，不影响整体结果
+func Build(ctx context.Context, maps mcp.BaiduMapsClient, entries []db.AlbumEntry, loc *time.Location, mode string) *Timeline {
+	if loc == nil {
+		loc = time.Local
+	}
+	t := &Timeline{Mode: mode, Days: []Day{}}
+	for _, e := range entries {
+		if e.Photo.TakenAt == nil {
+			t.Undated = append(t.Undated, e.Photo)
+			continue
+		}
+		date := e.Photo.TakenAt.In(loc).Format("2006-01-02")
+		if len(t.Days) == 0 || t.Days[len(t.Days)-1].Date != date {
+			t.Days = append(t.Days, Day{Date: date, Stops: []Stop{}, Legs: []Leg{}})
+		}
+		day := &t.Days[len(t.Days)-1]
+		day.Stops = addToStops(day.Stops, e)
+	}
+
+	for i := range t.Days {
+		day := &t.Days[i]
+		for j := 1; j < len(day.Stops); j++ {
+			a, b := day.Stops[j-1].BD09, day.Stops[j].BD09
+			if a == nil || b == nil {
+				continue
+			}
+			day.Legs = append(day.Legs, Leg{From: j - 1, To: j, Mode: mode, StraightKm: roundKm(geo.DistanceKm(*a, *b))})
+		}
+	}
+	enrich(ctx, maps, t)
+	for i := range t.Days {
+		day := &t.Days[i]
+		for _, leg := range day.Legs {
+			day.DistanceM += leg.DistanceM
+			day.DurationS += leg.DurationS
+		}
+	}
+	return t
+}
+
+// addToStops 照片与上一站属于同一地点时并入，否则新开一站
+func addToStops(stops []Stop, e db.AlbumEntry) []Stop {
+	next := newStop(e)
+	if n := len(stops); n > 0 {
+		last := &stops[n-1]
+		if sameStop(last, &next) {
+			last.Photos = append(last.Photos, e.Photo)
+			last.LeftAt = *e.Photo.TakenAt
+			if last.Address == "" {
+				last.Address, last.City, last.District = next.Address, next.City, next.District
+			}
+			return stops
+		}
+	}
+	return append(stops, next)
+}
+
+func sameStop(last, next *Stop) bool {
+	switch {
+	case next.SpotID != nil:
+		return last.SpotID != nil && *last.SpotID == *next.SpotID
+	case next.PlaceUID != "":
+		return last.SpotID == nil && last.PlaceUID == next.PlaceUID
+	case next.BD09 == nil:
+		// 没有位置信息的照片归入上一站
+		return true
+	}
+	return last.BD09 != nil && geo.DistanceKm(*last.BD09, *next.BD09) <= mergeRadiusKm
+}
+
+// newStop 停留点的名称与坐标优先取关联机位，其次关联的百度地点，最后是照片自身的 GPS 与最近景点
+func newStop(e db.AlbumEntry) Stop {
+	p := e.Photo
+	s := Stop{
+		ArrivedAt: *p.TakenAt,
+		LeftAt:    *p.TakenAt,
+		Photos:    []db.Photo{p},
+		Address:   p.Address,
+		City:      p.City,
+		District:  p.District,
+	}
+	if p.HasGPS {
+		s.BD09 = &geo.Point{Lat: p.BDLat, Lng: p.BDLng}
+	}
+	switch {
+	case e.Spot != nil:
+		s.SpotID = &e.Spot.ID
+		s.Name = e.Spot.Name
+		s.BD09 = &geo.Point{Lat: e.Spot.BDLat, Lng: e.Spot.BDLng}
+		if e.Spot.Address != "" {
+			s.Address, s.City = e.Spot.Address, e.Spot.City
+		}
+	case e.PlaceUID != "":
+		s.PlaceUID = e.PlaceUID
+		s.Name = e.PlaceName
+		if s.Name == "" && e.PlaceUID == p.POIUID {
+			s.Name = p.POIName
+		}
+		if e.PlaceBDLat != 0 || e.PlaceBDLng != 0 {
+			s.BD09 = &geo.Point{Lat: e.PlaceBDLat, Lng: e.PlaceBDLng}
+		}
+	default:
+		s.Name = p.POIName
+	}
+	return s
+}
+
+// enrich 对缺少地址的停留点逆地理编码，并规划每段路线
+func enrich(ctx context.Context, maps mcp.BaiduMapsClient, t *Timeline) {
+	sem := make(chan struct{}, maxConcurrentCalls)
+	var wg sync.WaitGroup
+	run := func(fn func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-sem }()
+			fn()
+		}()
+	}
+
+	for i := range t.Days {
+		day := &t.Days[i]
+		for j := range day.Stops {
+			stop := &day.Stops[j]
+			if stop.BD09 == nil || (stop.Address != "" && stop.Name != "") {
+				continue
+			}
+			if maps == nil {
+				stop.GeocodeError = ErrMapsUnavailable.Error()
+				continue
+			}
+			run(func() { reverseGeocode(ctx, maps, stop) })
+		}
+		for j := range day.Legs {
+			leg := &day.Legs[j]
+			if maps == nil {
+				leg.Error = ErrMapsUnavailable.Error()
+				continue
+			}
+			from, to := *day.Stops[leg.From].BD09, *day.Stops[leg.To].BD09
+			run(func() { directions(ctx, maps, leg, from, to) })
+		}
+	}
+	wg.Wait()
+
+	// 等待并发额度时 ctx 结束的调用没有执行
+	if err := ctx.Err(); err != nil {
+		for i := range t.Days {
+			for j := range t.Days[i].Legs {
+				leg := &t.Days[i].Legs[j]
+				if leg.DistanceM == 0 && leg.Error == "" {
+					leg.Error = err.Error()
+				}
+			}
+		}
+	}
+}
+
+func reverseGeocode(ctx context.Context, maps mcp.BaiduMapsClient, stop *Stop) {
+	rev, err := maps.ReverseGeocode(ctx, stop.BD09.Lat, stop.BD09.Lng)
+	if err == nil && rev.Status != 0 {
+		err = fmt.Errorf("reverse geocode status %d: %s", rev.Status, rev.Message)
+	}
+	if err != nil {
+		stop.GeocodeError = err.Error()
+		return
+	}
+	comp := rev.Result.AddressComponent
+	if stop.Address == "" {
+		stop.Address, stop.City, stop.District = rev.Result.FormattedAddress, comp.City, comp.District
+	}
+	if stop.Name == "" {
+		stop.Name = firstNonEmpty(rev.Result.Business, comp.Street, comp.District, rev.Result.FormattedAddress)
+	}
+}
+
+func directions(ctx context.Context, maps mcp.BaiduMapsClient, leg *Leg, from, to geo.Point) {
+	res, err := maps.GetDirections(ctx, formatPoint(from), formatPoint(to), leg.Mode)
+	if err != nil {
+		leg.Error = err.Error()
+		return
+	}
+	if len(res.Result.Routes) == 0 {
+		leg.Error = "no route found"
+		return
+	}
+	route := res.Result.Routes[0]
+	leg.DistanceM, leg.DurationS = route.Distance, route.Duration
+}
+
+// formatPoint 百度地图路线规划接受的 "纬度,经度"（BD-09）
+func formatPoint(p geo.Point) string {
+	return strconv.FormatFloat(p.Lat, 'f', 6, 64) + "," + strconv.FormatFloat(p.Lng, 'f', 6, 64)
+}
+
+func roundKm(km float64) float64 {
+	return float64(int(km*100+0.5)) / 100
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
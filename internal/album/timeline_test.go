@@ -0,0 +1,178 @@
+package album
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/huangqi/photo-backend/internal/db"
+	"github.com/huangqi/photo-backend/internal/geo"
+	"github.com/huangqi/photo-backend/internal/mcp"
+)
+
+// fakeMaps 逆地理编码按坐标返回固定结果，路线距离为直线距离的 1.5 倍，failTo 为终点时规划失败
+type fakeMaps struct {
+	mcp.BaiduMapsClient
+
+	mu       sync.Mutex
+	geocodes map[string]string
+	failTo   string
+	calls    []string
+}
+
+func (f *fakeMaps) ReverseGeocode(_ context.Context, lat, lng float64) (*mcp.ReverseGeocodeResult, error) {
+	key := formatPoint(geo.Point{Lat: lat, Lng: lng})
+	f.mu.Lock()
+	f.calls = append(f.calls, "geocode "+key)
+	f.mu.Unlock()
+	business, ok := f.geocodes[key]
+	if !ok {
+		return nil, errors.New("geocode failed")
+	}
+	var res mcp.ReverseGeocodeResult
+	res.Result.FormattedAddress = "北京市东城区" + business
+	res.Result.Business = business
+	res.Result.AddressComponent.City = "北京市"
+	res.Result.AddressComponent.District = "东城区"
+	return &res, nil
+}
+
+func (f *fakeMaps) GetDirections(_ context.Context, origin, destination, mode string) (*mcp.DirectionsResult, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, "directions "+origin+" "+destination+" "+mode)
+	f.mu.Unlock()
+	if destination == f.failTo {
+		return nil, errors.New("no route")
+	}
+	var from, to geo.Point
+	fmt.Sscanf(origin, "%f,%f", &from.Lat, &from.Lng)
+	fmt.Sscanf(destination, "%f,%f", &to.Lat, &to.Lng)
+	m := int(geo.DistanceKm(from, to) * 1500)
+	var res mcp.DirectionsResult
+	if err := json.Unmarshal([]byte(fmt.Sprintf(`{"result":{"routes":[{"distance":%d,"duration":%d}]}}`, m, m)), &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+var shanghai = time.FixedZone("CST", 8*3600)
+
+func at(day, hour, minute int) *time.Time {
+	t := time.Date(2024, 5, day, hour, minute, 0, 0, shanghai).UTC()
+	return &t
+}
+
+func gpsPhoto(id uint, taken *time.Time, lat, lng float64) db.Photo {
+	return db.Photo{ID: id, TakenAt: taken, HasGPS: true, BDLat: lat, BDLng: lng}
+}
+
+var (
+	palace   = geo.Point{Lat: 39.924091, Lng: 116.403414}
+	jingshan = geo.Point{Lat: 39.930946, Lng: 116.403046}
+	beihai   = geo.Point{Lat: 39.931953, Lng: 116.396232}
+)
+
+func TestBuild(t *testing.T) {
+	spot := &db.PhotoSpot{ID: 7, Name: "故宫角楼", BDLat: palace.Lat, BDLng: palace.Lng, Address: "景山前街4号", City: "北京市"}
+	spotID := spot.ID
+	entries := []db.AlbumEntry{
+		// 5 月 1 日：机位两张、附近 100m 内一张、没有 GPS 的一张并入上一站，之后去景山
+		{AlbumPhoto: db.AlbumPhoto{SpotID: &spotID}, Photo: db.Photo{ID: 1, TakenAt: at(1, 17, 0)}, Spot: spot},
+		{AlbumPhoto: db.AlbumPhoto{SpotID: &spotID}, Photo: db.Photo{ID: 2, TakenAt: at(1, 17, 20)}, Spot: spot},
+		{Photo: gpsPhoto(3, at(1, 17, 40), palace.Lat+0.0005, palace.Lng)},
+		{Photo: db.Photo{ID: 4, TakenAt: at(1, 17, 45)}},
+		{Photo: gpsPhoto(5, at(1, 18, 30), jingshan.Lat, jingshan.Lng)},
+		// 当地时间 5 月 2 日凌晨，UTC 仍是 5 月 1 日
+		{AlbumPhoto: db.AlbumPhoto{PlaceUID: "beihai", PlaceName: "北海公园", PlaceBDLat: beihai.Lat, PlaceBDLng: beihai.Lng}, Photo: db.Photo{ID: 6, TakenAt: at(2, 6, 0)}},
+		{Photo: gpsPhoto(7, at(2, 7, 0), jingshan.Lat, jingshan.Lng)},
+		{Photo: db.Photo{ID: 8}},
+	}
+	maps := &fakeMaps{geocodes: map[string]string{formatPoint(jingshan): "景山"}}
+	got := Build(context.Background(), maps, entries, shanghai, ModeWalking)
+
+	if got.Mode != ModeWalking || len(got.Undated) != 1 || got.Undated[0].ID != 8 {
+		t.Errorf("mode = %q, undated = %+v", got.Mode, got.Undated)
+	}
+	if len(got.Days) != 2 || got.Days[0].Date != "2024-05-01" || got.Days[1].Date != "2024-05-02" {
+		t.Fatalf("days = %+v, want 2024-05-01 and 2024-05-02", got.Days)
+	}
+
+	day := got.Days[0]
+	if len(day.Stops) != 2 {
+		t.Fatalf("day 1 stops = %+v, want spot and 景山", day.Stops)
+	}
+	first := day.Stops[0]
+	if first.SpotID == nil || *first.SpotID != 7 || first.Name != "故宫角楼" || first.Address != "景山前街4号" || len(first.Photos) != 4 {
+		t.Errorf("first stop = %+v, want the spot with photos 1-4", first)
+	}
+	if !first.ArrivedAt.Equal(*at(1, 17, 0)) || !first.LeftAt.Equal(*at(1, 17, 45)) {
+		t.Errorf("first stop from %v to %v", first.ArrivedAt, first.LeftAt)
+	}
+	second := day.Stops[1]
+	if second.Name != "景山" || second.City != "北京市" || second.District != "东城区" || second.GeocodeError != "" {
+		t.Errorf("second stop = %+v, want reverse-geocoded 景山", second)
+	}
+	if len(day.Legs) != 1 {
+		t.Fatalf("day 1 legs = %+v", day.Legs)
+	}
+	leg := day.Legs[0]
+	straight := geo.DistanceKm(palace, jingshan)
+	if leg.From != 0 || leg.To != 1 || leg.Mode != ModeWalking || leg.StraightKm != roundKm(straight) || leg.DistanceM != int(straight*1500) {
+		t.Errorf("day 1 leg = %+v", leg)
+	}
+	if day.DistanceM != leg.DistanceM || day.DurationS != leg.DurationS {
+		t.Errorf("day 1 totals = %d m, %d s; want the single leg", day.DistanceM, day.DurationS)
+	}
+
+	day = got.Days[1]
+	if len(day.Stops) != 2 || day.Stops[0].PlaceUID != "beihai" || day.Stops[0].Name != "北海公园" || day.Stops[1].Name != "景山" {
+		t.Fatalf("day 2 stops = %+v", day.Stops)
+	}
+	if len(day.Legs) != 1 || day.Legs[0].DistanceM == 0 || day.Legs[0].Error != "" {
+		t.Errorf("day 2 legs = %+v", day.Legs)
+	}
+
+	// 已有名称与地址的机位不做逆地理编码
+	for _, call := range maps.calls {
+		if call == "geocode "+formatPoint(palace) {
+			t.Errorf("spot with an address was reverse geocoded")
+		}
+	}
+}
+
+func TestBuildFailures(t *testing.T) {
+	entries := []db.AlbumEntry{
+		{Photo: gpsPhoto(1, at(1, 9, 0), palace.Lat, palace.Lng)},
+		{Photo: gpsPhoto(2, at(1, 10, 0), jingshan.Lat, jingshan.Lng)},
+		{Photo: gpsPhoto(3, at(1, 11, 0), beihai.Lat, beihai.Lng)},
+	}
+	maps := &fakeMaps{
+		geocodes: map[string]string{formatPoint(jingshan): "景山", formatPoint(beihai): "北海"},
+		failTo:   formatPoint(jingshan),
+	}
+	got := Build(context.Background(), maps, entries, shanghai, ModeDriving)
+	day := got.Days[0]
+	if day.Stops[0].GeocodeError == "" || day.Stops[1].GeocodeError != "" {
+		t.Errorf("geocode errors = %q, %q", day.Stops[0].GeocodeError, day.Stops[1].GeocodeError)
+	}
+	if len(day.Legs) != 2 || day.Legs[0].Error != "no route" || day.Legs[0].StraightKm == 0 || day.Legs[1].Error != "" {
+		t.Fatalf("legs = %+v, want the first to fail", day.Legs)
+	}
+	if day.DistanceM != day.Legs[1].DistanceM {
+		t.Errorf("day distance = %d, want only the planned leg %d", day.DistanceM, day.Legs[1].DistanceM)
+	}
+
+	// 未配置百度地图时照常分组，失败原因写在停留点与路段中
+	got = Build(context.Background(), nil, entries, shanghai, ModeDriving)
+	day = got.Days[0]
+	if len(day.Stops) != 3 || len(day.Legs) != 2 {
+		t.Fatalf("without maps: %d stops, %d legs", len(day.Stops), len(day.Legs))
+	}
+	if day.Stops[0].GeocodeError != ErrMapsUnavailable.Error() || day.Legs[0].Error != ErrMapsUnavailable.Error() {
+		t.Errorf("without maps: stop error %q, leg error %q", day.Stops[0].GeocodeError, day.Legs[0].Error)
+	}
+}
@@ -0,0 +1,106 @@
+package catalog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Query 一条搜索短语，PinnedURLs 为人工挑选的结果链接
+type Query struct {
+	Text       string   `json:"text"`
+	PinnedURLs []string `json:"pinned_urls,omitempty"`
+}
+
+// Category 一个分类（姿势类型或城市）
+type Category struct {
+	Name    string  `json:"name"`
+	Queries []Query `json:"queries"`
+}
+
+// Catalog 由 query.txt 解析出的搜索短语目录
+type Catalog struct {
+	Categories []Category `json:"categories"`
+}
+
+// Load 从文件加载目录
+func Load(path string) (*Catalog, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse 解析 query.txt 格式：
+//   - 顶格行为分类标题
+//   - 缩进行为该分类下的搜索短语
+//   - 双引号包裹的链接（可带行尾逗号）为上一条搜索短语的置顶结果
+//   - 空行与只有空白的行被忽略
+func Parse(r io.Reader) (*Catalog, error) {
+	cat := &Catalog{}
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		raw := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(raw)
+		if trimmed == "" {
+			continue
+		}
+
+		if url, ok := pinnedURL(trimmed); ok {
+			cur := cat.lastCategory()
+			if cur == nil || len(cur.Queries) == 0 {
+				return nil, fmt.Errorf("line %d: pinned url without a preceding query", lineNo)
+			}
+			q := &cur.Queries[len(cur.Queries)-1]
+			q.PinnedURLs = append(q.PinnedURLs, url)
+			continue
+		}
+
+		if raw[0] != ' ' && raw[0] != '\t' {
+			cat.Categories = append(cat.Categories, Category{Name: trimmed, Queries: []Query{}})
+			continue
+		}
+
+		cur := cat.lastCategory()
+		if cur == nil {
+			return nil, fmt.Errorf("line %d: query %q before any category", lineNo, trimmed)
+		}
+		cur.Queries = append(cur.Queries, Query{Text: trimmed})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return cat, nil
+}
+
+func pinnedURL(line string) (string, bool) {
+	s := strings.TrimSuffix(line, ",")
+	s = strings.Trim(s, `"`)
+	if strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://") {
+		return s, true
+	}
+	return "", false
+}
+
+func (c *Catalog) lastCategory() *Category {
+	if len(c.Categories) == 0 {
+		return nil
+	}
+	return &c.Categories[len(c.Categories)-1]
+}
+
+// Category 按名称查找分类
+func (c *Catalog) Category(name string) (*Category, bool) {
+	for i := range c.Categories {
+		if c.Categories[i].Name == name {
+			return &c.Categories[i], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,126 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/huangqi/photo-backend/internal/mcp"
+)
+
+func TestLoad(t *testing.T) {
+	cat, err := Load("testdata/query.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Catalog{Categories: []Category{
+		{Name: "经常搜的", Queries: []Query{
+			{Text: "旅行拍照姿势大全", PinnedURLs: []string{
+				"https://www.xiaohongshu.com/search_result/6639eb29000000001e03aa87?xsec_token=ABwr&xsec_source=",
+				"https://www.xiaohongshu.com/explore/67d38bf9000000000903b32f",
+			}},
+			{Text: "海边旅行拍照姿势合集"},
+		}},
+		{Name: "北京", Queries: []Query{{Text: "故宫角楼 机位"}, {Text: "北京 日落 拍照"}}},
+		{Name: "empty", Queries: []Query{}},
+	}}
+	if !reflect.DeepEqual(cat, want) {
+		t.Errorf("Load = %+v\nwant %+v", cat, want)
+	}
+
+	if c, ok := cat.Category("北京"); !ok || len(c.Queries) != 2 {
+		t.Errorf("Category(北京) = %+v, %v", c, ok)
+	}
+	if _, ok := cat.Category("上海"); ok {
+		t.Error("Category(上海) found a missing category")
+	}
+	if _, err := Load("testdata/missing.txt"); err == nil {
+		t.Error("Load of a missing file succeeded")
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	tests := []struct {
+		name, input, wantErr string
+	}{
+		{"query before category", " 旅行拍照\n", "line 1: query"},
+		{"pinned url before any query", "北京\n\"https://www.xiaohongshu.com/explore/1\"\n", "line 2: pinned url"},
+		{"pinned url before any category", "\n\"https://www.xiaohongshu.com/explore/1\",\n", "line 2: pinned url"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+	if cat, err := Parse(strings.NewReader("")); err != nil || len(cat.Categories) != 0 {
+		t.Errorf("Parse(empty) = %+v, %v", cat, err)
+	}
+}
+
+func TestCategorySearch(t *testing.T) {
+	c := &Category{Name: "北京", Queries: []Query{
+		{Text: "故宫", PinnedURLs: []string{"https://www.xiaohongshu.com/explore/6639eb29000000001e03aa87?xsec_token=t1"}},
+		{Text: "天坛"},
+		{Text: "景山"},
+		{Text: "北海"},
+	}}
+	results := map[string][]mcp.XHSPost{
+		"故宫": {{ID: "6639eb29000000001e03aa87", Title: "角楼"}, {ID: "bbb", Title: "午门"}},
+		"天坛": {{ID: "bbb"}, {PostURL: "https://example.com/c", Title: "祈年殿"}},
+		"景山": nil,
+	}
+	var calls []string
+	search := func(ctx context.Context, keyword string, limit int) ([]mcp.XHSPost, error) {
+		calls = append(calls, keyword)
+		if limit != 5 {
+			t.Errorf("limit = %d, want 5", limit)
+		}
+		if keyword == "景山" {
+			return nil, errors.New("timeout")
+		}
+		return results[keyword], nil
+	}
+
+	res := c.Search(context.Background(), search, 5, 3)
+	if !reflect.DeepEqual(calls, []string{"故宫", "天坛", "景山"}) {
+		t.Errorf("searched %v, want the first 3 queries in order", calls)
+	}
+	type post struct {
+		key, title string
+		queries    []string
+		pinned     bool
+	}
+	var got []post
+	for _, p := range res.Posts {
+		key := p.ID
+		if key == "" {
+			key = p.PostURL
+		}
+		got = append(got, post{key, p.Title, p.Queries, p.Pinned})
+	}
+	want := []post{
+		// 置顶链接在前，搜索结果补上标题
+		{"6639eb29000000001e03aa87", "角楼", []string{"故宫"}, true},
+		{"bbb", "午门", []string{"故宫", "天坛"}, false},
+		{"https://example.com/c", "祈年殿", []string{"天坛"}, false},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("posts = %+v\nwant %+v", got, want)
+	}
+	if len(res.Errors) != 1 || res.Errors[0].Query != "景山" {
+		t.Errorf("errors = %+v, want one for 景山", res.Errors)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls = nil
+	res = c.Search(ctx, search, 5, 0)
+	if len(calls) != 0 || len(res.Errors) != 4 {
+		t.Errorf("cancelled search: %d calls, %d errors; want 0, 4", len(calls), len(res.Errors))
+	}
+}
@@ -0,0 +1,88 @@
+package catalog
+
+import (
+	"context"
+
+	"github.com/huangqi/photo-backend/internal/mcp"
+)
+
+// SearchFunc 执行一次关键词搜索，通常为带登录处理的 XHSClient.GetPostsByKeyword
+type SearchFunc func(ctx context.Context, keyword string, limit int) ([]mcp.XHSPost, error)
+
+// MergedPost 合并去重后的帖子，Queries 为命中该帖子的搜索短语
+type MergedPost struct {
+	mcp.XHSPost
+	Queries []string `json:"queries"`
+	Pinned  bool     `json:"pinned"`
+}
+
+// QueryError 单条搜索短语失败
+type QueryError struct {
+	Query string `json:"query"`
+	Error string `json:"error"`
+}
+
+// SearchResult 分类搜索结果
+type SearchResult struct {
+	Category string       `json:"category"`
+	Posts    []MergedPost `json:"posts"`
+	Errors   []QueryError `json:"errors,omitempty"`
+}
+
+// Search 依次执行分类下的搜索短语（最多 maxQueries 条，<=0 表示全部），
+// 置顶链接排在最前，其余结果按首次出现顺序合并，并按笔记 ID（无 ID 时按链接）去重。
+// 搜索串行执行，避免短时间内对小红书发起大量请求。
+func (c *Category) Search(ctx context.Context, search SearchFunc, perQuery, maxQueries int) *SearchResult {
+	res := &SearchResult{Category: c.Name, Posts: []MergedPost{}}
+	index := make(map[string]int)
+	add := func(p mcp.XHSPost, query string, pinned bool) {
+		key := p.ID
+		if key == "" {
+			key = p.PostURL
+		}
+		if i, ok := index[key]; ok {
+			res.Posts[i].Queries = appendQuery(res.Posts[i].Queries, query)
+			if p.Title != "" && res.Posts[i].Title == "" {
+				res.Posts[i].XHSPost = p
+			}
+			return
+		}
+		index[key] = len(res.Posts)
+		res.Posts = append(res.Posts, MergedPost{XHSPost: p, Queries: []string{query}, Pinned: pinned})
+	}
+
+	queries := c.Queries
+	if maxQueries > 0 && len(queries) > maxQueries {
+		queries = queries[:maxQueries]
+	}
+	for _, q := range queries {
+		for _, u := range q.PinnedURLs {
+			id, token := mcp.ParseXHSNoteURL(u)
+			add(mcp.XHSPost{ID: id, XsecToken: token, PostURL: u}, q.Text, true)
+		}
+	}
+	for _, q := range queries {
+		if ctx.Err() != nil {
+			res.Errors = append(res.Errors, QueryError{Query: q.Text, Error: ctx.Err().Error()})
+			continue
+		}
+		posts, err := search(ctx, q.Text, perQuery)
+		if err != nil {
+			res.Errors = append(res.Errors, QueryError{Query: q.Text, Error: err.Error()})
+			continue
+		}
+		for _, p := range posts {
+			add(p, q.Text, false)
+		}
+	}
+	return res
+}
+
+func appendQuery(list []string, q string) []string {
+	for _, existing := range list {
+		if existing == q {
+			return list
+		}
+	}
+	return append(list, q)
+}
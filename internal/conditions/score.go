@@ -0,0 +1,303 @@
+// Package conditions 结合逐小时天气预报与黄金/蓝调时刻，为接下来的拍摄时段打分
+package conditions
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/huangqi/photo-backend/internal/mcp"
+	"github.com/huangqi/photo-backend/internal/solar"
+)
+
+// 评分因素
+const (
+	FactorLight  = "light"
+	FactorClouds = "clouds"
+	FactorRain   = "rain"
+	FactorHaze   = "haze"
+	FactorWind   = "wind"
+)
+
+// weights 各因素权重，和为 1
+var weights = map[string]float64{
+	FactorLight:  0.35,
+	FactorClouds: 0.2,
+	FactorRain:   0.25,
+	FactorHaze:   0.1,
+	FactorWind:   0.1,
+}
+
+const (
+	// forecastLayout 百度天气逐小时预报的时间格式，为当地时间
+	forecastLayout = "2006-01-02 15:04:05"
+	// nowWindow 实况能见度与 AQI 只用于这段时间内的预报
+	nowWindow = 3 * time.Hour
+	// rainCap 降水概率很高或有降水时总分不超过降水因素得分加上该值
+	rainCap = 20
+)
+
+// Factor 单个因素的得分（0-100）与说明
+type Factor struct {
+	Name   string  `json:"name"`
+	Score  int     `json:"score"`
+	Weight float64 `json:"weight"`
+	Detail string  `json:"detail"`
+}
+
+// Slot 一个小时的拍摄时段
+type Slot struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Score int       `json:"score"`
+	// Phase 时段内主要的光线阶段，黄金时刻优先于蓝调时刻
+	Phase string `json:"phase"`
+	// GoldenMinutes / BlueMinutes 时段与黄金、蓝调时刻重叠的分钟数
+	GoldenMinutes int `json:"golden_minutes"`
+	BlueMinutes   int `json:"blue_minutes"`
+	// Sun 时段中点的太阳位置
+	Sun     solar.Position `json:"sun"`
+	Weather string         `json:"weather"`
+	Clouds  int            `json:"clouds"`
+	Pop     int            `json:"pop"`
+	Factors []Factor       `json:"factors"`
+}
+
+// Rank 为 now 之后 horizon 内太阳高度不低于 -6° 的每个预报小时打分，按得分从高到低排序，
+// 同分按时间先后。lat/lng 用于计算太阳位置，loc 为预报时间所在的时区
+func Rank(w *mcp.WeatherResult, lat, lng float64, loc *time.Location, now time.Time, horizon time.Duration) []Slot {
+	if loc == nil {
+		loc = time.Local
+	}
+	days := map[string]*solar.Light{}
+	slots := []Slot{}
+	for _, fh := range w.Result.ForecastHours {
+		start, err := time.ParseInLocation(forecastLayout, fh.DataTime, loc)
+		if err != nil {
+			continue
+		}
+		end := start.Add(time.Hour)
+		if !end.After(now) || start.After(now.Add(horizon)) {
+			continue
+		}
+		date := start.Format("2006-01-02")
+		light, ok := days[date]
+		if !ok {
+			y, m, d := start.Date()
+			light = solar.Compute(time.Date(y, m, d, 0, 0, 0, 0, loc), lat, lng)
+			days[date] = light
+		}
+
+		s := Slot{
+			Start:         start,
+			End:           end,
+			Sun:           solar.PositionAt(start.Add(30*time.Minute), lat, lng),
+			Weather:       fh.Text,
+			Clouds:        fh.Clouds,
+			Pop:           fh.Pop,
+			GoldenMinutes: overlapMinutes(start, end, light.GoldenHour),
+			BlueMinutes:   overlapMinutes(start, end, light.BlueHour),
+		}
+		switch {
+		case s.GoldenMinutes > 0:
+			s.Phase = solar.PhaseGoldenHour
+		case s.BlueMinutes > 0:
+			s.Phase = solar.PhaseBlueHour
+		case s.Sun.Elevation >= solar.AltitudeGoldenHigh:
+			s.Phase = solar.PhaseDaylight
+		default:
+			// 夜间
+			continue
+		}
+
+		evening := start.Add(30 * time.Minute).After(light.SolarNoon.Time)
+		s.Factors = []Factor{
+			lightFactor(s),
+			cloudFactor(s.Phase, fh.Clouds, evening),
+			rainFactor(fh),
+			hazeFactor(fh, w, start.Sub(now) < nowWindow),
+			windFactor(fh.WindClass),
+		}
+		s.Score = total(s.Factors)
+		slots = append(slots, s)
+	}
+	sort.SliceStable(slots, func(i, j int) bool {
+		if slots[i].Score != slots[j].Score {
+			return slots[i].Score > slots[j].Score
+		}
+		return slots[i].Start.Before(slots[j].Start)
+	})
+	return slots
+}
+
+func total(factors []Factor) int {
+	var sum float64
+	rain := 100
+	for _, f := range factors {
+		sum += float64(f.Score) * f.Weight
+		if f.Name == FactorRain {
+			rain = f.Score
+		}
+	}
+	score := int(math.Round(sum))
+	// 下雨时光线再好也拍不了
+	return min(score, rain+rainCap)
+}
+
+func factor(name string, score int, detail string) Factor {
+	return Factor{Name: name, Score: max(0, min(100, score)), Weight: weights[name], Detail: detail}
+}
+
+func lightFactor(s Slot) Factor {
+	switch {
+	case s.GoldenMinutes > 0:
+		score := 70 + s.GoldenMinutes/2
+		return factor(FactorLight, score, fmt.Sprintf("黄金时刻 %d 分钟，太阳高度 %.1f°，光线柔和偏暖", s.GoldenMinutes, s.Sun.Elevation))
+	case s.BlueMinutes > 0:
+		score := 60 + s.BlueMinutes
+		return factor(FactorLight, score, fmt.Sprintf("蓝调时刻 %d 分钟，适合城市夜景与剪影", s.BlueMinutes))
+	case s.Sun.Elevation < 20:
+		return factor(FactorLight, 65, fmt.Sprintf("太阳高度 %.1f°，侧光明显，阴影较长", s.Sun.Elevation))
+	case s.Sun.Elevation < 45:
+		return factor(FactorLight, 50, fmt.Sprintf("太阳高度 %.1f°，光线偏硬", s.Sun.Elevation))
+	}
+	return factor(FactorLight, 35, fmt.Sprintf("太阳高度 %.1f°，顶光强烈，人像面部阴影重", s.Sun.Elevation))
+}
+
+// cloudFactor 黄金/蓝调时刻中等云量最容易出现朝霞晚霞；白天薄云可柔化光线
+func cloudFactor(phase string, clouds int, evening bool) Factor {
+	glow := "朝霞"
+	if evening {
+		glow = "晚霞"
+	}
+	if phase == solar.PhaseGoldenHour || phase == solar.PhaseBlueHour {
+		switch {
+		case clouds > 85:
+			return factor(FactorClouds, 20, fmt.Sprintf("云量 %d%%，阴天，太阳可能被完全遮挡", clouds))
+		case clouds > 70:
+			return factor(FactorClouds, 50, fmt.Sprintf("云量 %d%%，偏多，%s机会一般", clouds, glow))
+		case clouds >= 30:
+			return factor(FactorClouds, 100, fmt.Sprintf("云量 %d%%，中等云量，有%s/火烧云的可能", clouds, glow))
+		case clouds >= 10:
+			return factor(FactorClouds, 75, fmt.Sprintf("云量 %d%%，少云，可能有淡淡的%s", clouds, glow))
+		}
+		return factor(FactorClouds, 60, fmt.Sprintf("云量 %d%%，晴空，通透但天空缺少层次", clouds))
+	}
+	switch {
+	case clouds > 85:
+		return factor(FactorClouds, 50, fmt.Sprintf("云量 %d%%，阴天，光线平淡", clouds))
+	case clouds >= 20 && clouds <= 60:
+		return factor(FactorClouds, 90, fmt.Sprintf("云量 %d%%，云层柔化光线，天空有层次", clouds))
+	case clouds < 20:
+		return factor(FactorClouds, 70, fmt.Sprintf("云量 %d%%，晴朗，光比大", clouds))
+	}
+	return factor(FactorClouds, 70, fmt.Sprintf("云量 %d%%，多云", clouds))
+}
+
+func rainFactor(fh mcp.WeatherForecastHour) Factor {
+	score := 100 - fh.Pop
+	detail := fmt.Sprintf("降水概率 %d%%", fh.Pop)
+	if fh.Prec1h > 0 {
+		score = min(score, 30-int(fh.Prec1h*10))
+		detail += fmt.Sprintf("，预计降水 %.1fmm", fh.Prec1h)
+	}
+	if strings.Contains(fh.Text, "雨") || strings.Contains(fh.Text, "雪") {
+		score = min(score, 30)
+		detail += "，天气" + fh.Text
+	}
+	return factor(FactorRain, score, detail)
+}
+
+// hazeFactor 预报中的雾霾沙尘与相对湿度；临近时段再参考实况能见度与 AQI
+func hazeFactor(fh mcp.WeatherForecastHour, w *mcp.WeatherResult, nearNow bool) Factor {
+	score := 100
+	var notes []string
+	note := func(s int, text string) {
+		score = min(score, s)
+		notes = append(notes, text)
+	}
+	for _, kw := range []string{"雾", "霾", "沙", "尘"} {
+		if strings.Contains(fh.Text, kw) {
+			note(20, "天气"+fh.Text+"，能见度差")
+			break
+		}
+	}
+	switch {
+	case fh.Rh >= 95:
+		note(40, fmt.Sprintf("相对湿度 %d%%，容易起雾", fh.Rh))
+	case fh.Rh >= 85:
+		note(65, fmt.Sprintf("相对湿度 %d%%，远景可能发灰", fh.Rh))
+	}
+	if nearNow {
+		now := w.Result.Now
+		switch {
+		case now.Aqi > 200:
+			note(20, fmt.Sprintf("当前 AQI %d，重度污染", now.Aqi))
+		case now.Aqi > 150:
+			note(40, fmt.Sprintf("当前 AQI %d，空气发灰", now.Aqi))
+		case now.Aqi > 100:
+			note(65, fmt.Sprintf("当前 AQI %d，轻度污染", now.Aqi))
+		}
+		switch {
+		case now.Vis > 0 && now.Vis < 2000:
+			note(30, fmt.Sprintf("当前能见度 %dm", now.Vis))
+		case now.Vis > 0 && now.Vis < 5000:
+			note(60, fmt.Sprintf("当前能见度 %dm", now.Vis))
+		}
+	}
+	if len(notes) == 0 {
+		return factor(FactorHaze, score, "空气通透")
+	}
+	return factor(FactorHaze, score, strings.Join(notes, "；"))
+}
+
+func windFactor(windClass string) Factor {
+	level, ok := windLevel(windClass)
+	if !ok {
+		return factor(FactorWind, 80, "风力未知")
+	}
+	switch {
+	case level <= 3:
+		return factor(FactorWind, 100, "风力 "+windClass+"，影响不大")
+	case level == 4:
+		return factor(FactorWind, 80, "风力 "+windClass+"，头发与裙摆会被吹动")
+	case level == 5:
+		return factor(FactorWind, 60, "风力 "+windClass+"，长曝光需稳固三脚架")
+	}
+	return factor(FactorWind, 30, "风力 "+windClass+"，大风，不宜户外拍摄")
+}
+
+// windLevel 解析 "3级"、"<3级"、"3-4级" 这类风力等级，取最大值
+func windLevel(windClass string) (int, bool) {
+	level, found := 0, false
+	for _, part := range strings.FieldsFunc(windClass, func(r rune) bool { return r < '0' || r > '9' }) {
+		if n, err := strconv.Atoi(part); err == nil {
+			level, found = max(level, n), true
+		}
+	}
+	return level, found
+}
+
+// overlapMinutes [start, end) 与上午、傍晚两个窗口重叠的分钟数
+func overlapMinutes(start, end time.Time, w solar.Windows) int {
+	var d time.Duration
+	for _, win := range []*solar.Window{w.Morning, w.Evening} {
+		if win == nil {
+			continue
+		}
+		from, to := start, end
+		if win.Start.After(from) {
+			from = win.Start
+		}
+		if win.End.Before(to) {
+			to = win.End
+		}
+		if to.After(from) {
+			d += to.Sub(from)
+		}
+	}
+	return int(d.Round(time.Minute).Minutes())
+}
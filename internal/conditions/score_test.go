@@ -0,0 +1,219 @@
+package conditions
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/huangqi/photo-backend/internal/mcp"
+	"github.com/huangqi/photo-backend/internal/solar"
+)
+
+func TestLightFactor(t *testing.T) {
+	tests := []struct {
+		name string
+		slot Slot
+		want int
+	}{
+		{"full golden hour", Slot{GoldenMinutes: 60, Sun: solar.Position{Elevation: 2}}, 100},
+		{"partial golden hour", Slot{GoldenMinutes: 20, BlueMinutes: 40}, 80},
+		{"blue hour", Slot{BlueMinutes: 15, Sun: solar.Position{Elevation: -5}}, 75},
+		{"low sun", Slot{Sun: solar.Position{Elevation: 12}}, 65},
+		{"mid sun", Slot{Sun: solar.Position{Elevation: 30}}, 50},
+		{"high sun", Slot{Sun: solar.Position{Elevation: 70}}, 35},
+	}
+	for _, tt := range tests {
+		if got := lightFactor(tt.slot); got.Score != tt.want || got.Name != FactorLight || got.Weight != weights[FactorLight] {
+			t.Errorf("%s: lightFactor = %+v, want score %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCloudFactor(t *testing.T) {
+	tests := []struct {
+		phase   string
+		clouds  int
+		evening bool
+		want    int
+		detail  string
+	}{
+		{solar.PhaseGoldenHour, 95, true, 20, "阴天"},
+		{solar.PhaseGoldenHour, 80, true, 50, "晚霞机会一般"},
+		{solar.PhaseGoldenHour, 50, true, 100, "晚霞/火烧云"},
+		{solar.PhaseBlueHour, 50, false, 100, "朝霞/火烧云"},
+		{solar.PhaseGoldenHour, 20, false, 75, "朝霞"},
+		{solar.PhaseGoldenHour, 0, false, 60, "晴空"},
+		{solar.PhaseDaylight, 95, false, 50, "光线平淡"},
+		{solar.PhaseDaylight, 40, false, 90, "柔化光线"},
+		{solar.PhaseDaylight, 10, false, 70, "光比大"},
+		{solar.PhaseDaylight, 75, false, 70, "多云"},
+	}
+	for _, tt := range tests {
+		got := cloudFactor(tt.phase, tt.clouds, tt.evening)
+		if got.Score != tt.want || !strings.Contains(got.Detail, tt.detail) {
+			t.Errorf("cloudFactor(%s, %d, %v) = %d %q, want %d containing %q", tt.phase, tt.clouds, tt.evening, got.Score, got.Detail, tt.want, tt.detail)
+		}
+	}
+}
+
+func TestRainFactor(t *testing.T) {
+	tests := []struct {
+		fh   mcp.WeatherForecastHour
+		want int
+	}{
+		{mcp.WeatherForecastHour{Text: "晴", Pop: 10}, 90},
+		{mcp.WeatherForecastHour{Text: "多云", Pop: 40, Prec1h: 1.5}, 15},
+		{mcp.WeatherForecastHour{Text: "暴雨", Pop: 90, Prec1h: 8}, 0},
+		{mcp.WeatherForecastHour{Text: "小雨", Pop: 0}, 30},
+		{mcp.WeatherForecastHour{Text: "小雪", Pop: 20}, 30},
+	}
+	for _, tt := range tests {
+		if got := rainFactor(tt.fh); got.Score != tt.want {
+			t.Errorf("rainFactor(%+v) = %d %q, want %d", tt.fh, got.Score, got.Detail, tt.want)
+		}
+	}
+}
+
+func TestHazeFactor(t *testing.T) {
+	weather := func(aqi, vis int) *mcp.WeatherResult {
+		w := &mcp.WeatherResult{}
+		w.Result.Now.Aqi, w.Result.Now.Vis = aqi, vis
+		return w
+	}
+	tests := []struct {
+		name    string
+		fh      mcp.WeatherForecastHour
+		w       *mcp.WeatherResult
+		nearNow bool
+		want    int
+	}{
+		{"clear", mcp.WeatherForecastHour{Text: "晴", Rh: 40}, weather(30, 20000), true, 100},
+		{"fog", mcp.WeatherForecastHour{Text: "大雾", Rh: 98}, weather(30, 0), false, 20},
+		{"humid", mcp.WeatherForecastHour{Text: "多云", Rh: 96}, weather(30, 0), false, 40},
+		{"damp", mcp.WeatherForecastHour{Text: "多云", Rh: 88}, weather(30, 0), false, 65},
+		{"heavy pollution now", mcp.WeatherForecastHour{Text: "晴"}, weather(250, 0), true, 20},
+		{"moderate pollution now", mcp.WeatherForecastHour{Text: "晴"}, weather(160, 0), true, 40},
+		{"light pollution now", mcp.WeatherForecastHour{Text: "晴"}, weather(120, 0), true, 65},
+		{"pollution later is ignored", mcp.WeatherForecastHour{Text: "晴"}, weather(250, 1000), false, 100},
+		{"low visibility now", mcp.WeatherForecastHour{Text: "晴"}, weather(30, 1500), true, 30},
+		{"hazy visibility now", mcp.WeatherForecastHour{Text: "晴"}, weather(30, 4000), true, 60},
+	}
+	for _, tt := range tests {
+		if got := hazeFactor(tt.fh, tt.w, tt.nearNow); got.Score != tt.want {
+			t.Errorf("%s: hazeFactor = %d %q, want %d", tt.name, got.Score, got.Detail, tt.want)
+		}
+	}
+}
+
+func TestWindFactor(t *testing.T) {
+	tests := []struct {
+		windClass string
+		want      int
+	}{
+		{"<3级", 100},
+		{"3级", 100},
+		{"3-4级", 80},
+		{"5级", 60},
+		{"6-7级", 30},
+		{"", 80},
+		{"微风", 80},
+	}
+	for _, tt := range tests {
+		if got := windFactor(tt.windClass); got.Score != tt.want {
+			t.Errorf("windFactor(%q) = %d %q, want %d", tt.windClass, got.Score, got.Detail, tt.want)
+		}
+	}
+}
+
+func TestTotal(t *testing.T) {
+	all := func(light, clouds, rain, haze, wind int) []Factor {
+		return []Factor{factor(FactorLight, light, ""), factor(FactorClouds, clouds, ""), factor(FactorRain, rain, ""), factor(FactorHaze, haze, ""), factor(FactorWind, wind, "")}
+	}
+	tests := []struct {
+		name    string
+		factors []Factor
+		want    int
+	}{
+		{"perfect", all(100, 100, 100, 100, 100), 100},
+		{"weighted", all(100, 50, 100, 100, 100), 90},
+		{"rain caps a golden hour", all(100, 100, 10, 100, 100), 30},
+	}
+	for _, tt := range tests {
+		if got := total(tt.factors); got != tt.want {
+			t.Errorf("%s: total = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRank(t *testing.T) {
+	// 北京 2024-06-21：日出 04:46，日落 19:46，傍晚黄金时刻约 19:06-20:06，蓝调时刻约 20:06-20:19
+	loc := time.FixedZone("CST", 8*3600)
+	lat, lng := 39.9042, 116.4074
+	w := &mcp.WeatherResult{}
+	for h := 0; h < 24; h++ {
+		fh := mcp.WeatherForecastHour{Text: "晴", Clouds: 10, Rh: 50, WindClass: "2级", DataTime: fmt.Sprintf("2024-06-21 %02d:00:00", h)}
+		switch h {
+		case 12:
+			fh.Clouds = 40
+		case 15:
+			fh.Text, fh.Pop, fh.Prec1h = "中雨", 90, 3
+		case 19:
+			fh.Text, fh.Clouds = "多云", 50
+		}
+		w.Result.ForecastHours = append(w.Result.ForecastHours, fh)
+	}
+	w.Result.ForecastHours = append(w.Result.ForecastHours,
+		mcp.WeatherForecastHour{Text: "晴", Clouds: 50, DataTime: "2024-06-22 05:00:00"},
+		mcp.WeatherForecastHour{DataTime: "invalid"},
+	)
+
+	now := time.Date(2024, 6, 21, 8, 30, 0, 0, loc)
+	slots := Rank(w, lat, lng, loc, now, 14*time.Hour)
+
+	byHour := map[int]Slot{}
+	for _, s := range slots {
+		if s.Start.Day() != 21 {
+			t.Errorf("slot %s is beyond the horizon", s.Start.Format(forecastLayout))
+		}
+		byHour[s.Start.Hour()] = s
+		if len(s.Factors) != 5 {
+			t.Errorf("%s: %d factors, want 5", s.Start.Format("15:04"), len(s.Factors))
+		}
+	}
+	// 08:00 尚未结束因此保留；07:00 已结束，21:00 之后在夜间
+	for h := 8; h <= 20; h++ {
+		if _, ok := byHour[h]; !ok {
+			t.Errorf("missing slot %02d:00", h)
+		}
+	}
+	for _, h := range []int{7, 21, 22} {
+		if _, ok := byHour[h]; ok {
+			t.Errorf("unexpected slot %02d:00", h)
+		}
+	}
+
+	if s := byHour[19]; s.Phase != solar.PhaseGoldenHour || s.GoldenMinutes < 50 || s.Clouds != 50 {
+		t.Errorf("19:00 = %+v, want a golden-hour slot", s)
+	}
+	if s := byHour[20]; s.Phase != solar.PhaseGoldenHour || s.BlueMinutes == 0 {
+		t.Errorf("20:00 = phase %s, golden %d, blue %d; want golden with some blue", s.Phase, s.GoldenMinutes, s.BlueMinutes)
+	}
+	if s := byHour[12]; s.Phase != solar.PhaseDaylight || s.GoldenMinutes != 0 {
+		t.Errorf("12:00 = %+v, want daylight", s)
+	}
+
+	// 中等云量的傍晚黄金时刻排第一，下雨的时段排最后
+	if slots[0].Start.Hour() != 19 {
+		t.Errorf("best slot = %s, want 19:00", slots[0].Start.Format("15:04"))
+	}
+	if last := slots[len(slots)-1]; last.Start.Hour() != 15 || last.Score > byHour[15].Factors[2].Score+rainCap {
+		t.Errorf("worst slot = %s (score %d), want the rainy 15:00", last.Start.Format("15:04"), last.Score)
+	}
+	for i := 1; i < len(slots); i++ {
+		a, b := slots[i-1], slots[i]
+		if a.Score < b.Score || (a.Score == b.Score && !a.Start.Before(b.Start)) {
+			t.Errorf("slots %s (%d) and %s (%d) out of order", a.Start.Format("15:04"), a.Score, b.Start.Format("15:04"), b.Score)
+		}
+	}
+}
@@ -3,21 +3,107 @@ package config
 import (
 	"os"
 	"strconv"
+	"time"
 )
 
 type Config struct {
 	Port    int
 	GinMode string
 
+	// DBDriver mysql 或 sqlite；DBDSN 非空时直接使用，不再拼接 DB_HOST 等字段
+	DBDriver   string
+	DBDSN      string
 	DBHost     string
 	DBPort     int
 	DBUser     string
 	DBPassword string
 	DBName     string
-	DBSSLMode  string
+	// DBSSLMode MySQL TLS 模式：disabled/preferred/required/verify-ca/verify-identity
+	DBSSLMode string
+	DBSSLCA   string
+	DBSSLCert string
+	DBSSLKey  string
+
+	// 连接池
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+	DBConnMaxIdleTime time.Duration
+
+	// DBConfigured 是否设置了 DB_DSN 或 DB_HOST；未设置时服务启动只尝试一次默认地址
+	DBConfigured bool
+	// DBConnectRetries 启动时连接失败的重试次数，退避从 DBConnectBackoff 翻倍到 DBConnectMaxBackoff
+	DBConnectRetries    int
+	DBConnectBackoff    time.Duration
+	DBConnectMaxBackoff time.Duration
+
+	// DBAutoMigrate 启动时执行未执行的迁移
+	DBAutoMigrate bool
 
 	MCPXHSEndpoint  string
 	MCPMapsEndpoint string
+	MCPConfigPath   string
+	// CatalogPath 搜索短语目录（query.txt）路径
+	CatalogPath string
+
+	// XHSEnabled 为 false 时不挂载小红书模块；XHSServer 为 mcp.json 中的服务 key 或名称
+	XHSEnabled bool
+	XHSServer  string
+	// XHSSessionTTL 登录状态有效期；XHSSessionCheckInterval 后台校验间隔
+	XHSSessionTTL           time.Duration
+	XHSSessionCheckInterval time.Duration
+
+	// XHSCacheTTL 数据库中搜索结果的有效期
+	XHSCacheTTL time.Duration
+
+	// XHSRate* 小红书工具调用限流：全局与单关键词的每分钟次数与突发量、最小调用间隔、最长排队时间
+	XHSRateGlobalPerMinute  float64
+	XHSRateGlobalBurst      int
+	XHSRateKeywordPerMinute float64
+	XHSRateKeywordBurst     int
+	XHSRateMinSpacing       time.Duration
+	XHSRateMaxWait          time.Duration
+
+	// XHSCommentPosting 是否允许通过管理接口发表评论；XHSCommentMinInterval 两次发表之间的最小间隔
+	XHSCommentPosting     bool
+	XHSCommentMinInterval time.Duration
+
+	// Harvest* 定时采集：开关、间隔、每条短语结果数、两次搜索之间的间隔
+	HarvestEnabled    bool
+	HarvestInterval   time.Duration
+	HarvestPerQuery   int
+	HarvestQueryDelay time.Duration
+
+	// StorageBackend local 或 s3；UploadDir 为本地存储的根目录，UploadMaxBytes 单张照片的大小上限
+	StorageBackend string
+	UploadDir      string
+	UploadMaxBytes int64
+
+	// S3 兼容存储
+	S3Endpoint     string
+	S3Region       string
+	S3Bucket       string
+	S3AccessKey    string
+	S3SecretKey    string
+	S3UseSSL       bool
+	S3Prefix       string
+	S3CreateBucket bool
+	// PhotoMaxPixels 上传图片的像素数（宽×高）上限，0 表示不限制
+	PhotoMaxPixels int64
+	// PhotoTimezone EXIF 拍摄时间没有时区信息时使用的时区
+	PhotoTimezone string
+	// PhotoVariants 派生图规格 name:max_edge[:format[:quality]]，逗号分隔，为空时不生成
+	PhotoVariants string
+	// PhotoVariantWorkers 派生图 worker 数，也是同时解码图片（含计算感知哈希）的上限
+	PhotoVariantWorkers int
+	PhotoVariantQueue   int
+	// PhotoStripGPS 下载原图时清除 JPEG 中的 GPS 信息
+	PhotoStripGPS bool
+	// PhotoDupThreshold 近似重复判定的默认汉明距离（0-64）
+	PhotoDupThreshold int
+
+	// AdminToken 管理接口令牌（X-Admin-Token），为空时管理接口不可用
+	AdminToken string
 }
 
 func getEnv(key, def string) string {
@@ -36,16 +122,109 @@ func getEnvInt(key string, def int) int {
 	return def
 }
 
+func getEnvFloat(key string, def float64) float64 {
+	if v := os.Getenv(key); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return def
+}
+
+func getEnvBool(key string, def bool) bool {
+	if v := os.Getenv(key); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return def
+}
+
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return def
+}
+
 func Load() Config {
 	return Config{
 		Port:    getEnvInt("PORT", 8080),
 		GinMode: getEnv("GIN_MODE", "release"),
 
+		DBDriver:   getEnv("DB_DRIVER", "mysql"),
+		DBDSN:      getEnv("DB_DSN", ""),
 		DBHost:     getEnv("DB_HOST", "localhost"),
 		DBPort:     getEnvInt("DB_PORT", 3306),
 		DBUser:     getEnv("DB_USER", "root"),
 		DBPassword: getEnv("DB_PASSWORD", "root"),
 		DBName:     getEnv("DB_NAME", "photodb"),
 		DBSSLMode:  getEnv("DB_SSLMODE", ""),
+		DBSSLCA:    getEnv("DB_SSL_CA", ""),
+		DBSSLCert:  getEnv("DB_SSL_CERT", ""),
+		DBSSLKey:   getEnv("DB_SSL_KEY", ""),
+
+		DBMaxOpenConns:    getEnvInt("DB_MAX_OPEN_CONNS", 20),
+		DBMaxIdleConns:    getEnvInt("DB_MAX_IDLE_CONNS", 10),
+		DBConnMaxLifetime: getEnvDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+		DBConnMaxIdleTime: getEnvDuration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
+
+		DBConfigured:        os.Getenv("DB_DSN") != "" || os.Getenv("DB_HOST") != "",
+		DBConnectRetries:    getEnvInt("DB_CONNECT_RETRIES", 5),
+		DBConnectBackoff:    getEnvDuration("DB_CONNECT_BACKOFF", time.Second),
+		DBConnectMaxBackoff: getEnvDuration("DB_CONNECT_MAX_BACKOFF", 15*time.Second),
+
+		DBAutoMigrate: getEnvBool("DB_AUTO_MIGRATE", true),
+
+		MCPConfigPath: getEnv("MCP_CONFIG_PATH", "mcp.json"),
+		CatalogPath:   getEnv("QUERY_CATALOG_PATH", "query.txt"),
+
+		XHSEnabled: getEnvBool("XHS_ENABLED", true),
+		XHSServer:  getEnv("XHS_MCP_SERVER", ""),
+
+		XHSSessionTTL:           getEnvDuration("XHS_SESSION_TTL", 30*time.Minute),
+		XHSSessionCheckInterval: getEnvDuration("XHS_SESSION_CHECK_INTERVAL", 10*time.Minute),
+
+		XHSCacheTTL: getEnvDuration("XHS_CACHE_TTL", time.Hour),
+
+		XHSRateGlobalPerMinute:  getEnvFloat("XHS_RATE_GLOBAL_PER_MINUTE", 6),
+		XHSRateGlobalBurst:      getEnvInt("XHS_RATE_GLOBAL_BURST", 3),
+		XHSRateKeywordPerMinute: getEnvFloat("XHS_RATE_KEYWORD_PER_MINUTE", 1),
+		XHSRateKeywordBurst:     getEnvInt("XHS_RATE_KEYWORD_BURST", 2),
+		XHSRateMinSpacing:       getEnvDuration("XHS_RATE_MIN_SPACING", 3*time.Second),
+		XHSRateMaxWait:          getEnvDuration("XHS_RATE_MAX_WAIT", 20*time.Second),
+
+		XHSCommentPosting:     getEnvBool("XHS_COMMENT_POSTING", false),
+		XHSCommentMinInterval: getEnvDuration("XHS_COMMENT_MIN_INTERVAL", 2*time.Minute),
+
+		HarvestEnabled:    getEnvBool("HARVEST_ENABLED", false),
+		HarvestInterval:   getEnvDuration("HARVEST_INTERVAL", 6*time.Hour),
+		HarvestPerQuery:   getEnvInt("HARVEST_PER_QUERY", 10),
+		HarvestQueryDelay: getEnvDuration("HARVEST_QUERY_DELAY", 30*time.Second),
+
+		StorageBackend: getEnv("STORAGE_BACKEND", "local"),
+		UploadDir:      getEnv("UPLOAD_DIR", "uploads"),
+		UploadMaxBytes: int64(getEnvInt("UPLOAD_MAX_BYTES", 32<<20)),
+
+		S3Endpoint:     getEnv("S3_ENDPOINT", ""),
+		S3Region:       getEnv("S3_REGION", ""),
+		S3Bucket:       getEnv("S3_BUCKET", ""),
+		S3AccessKey:    getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:    getEnv("S3_SECRET_KEY", ""),
+		S3UseSSL:       getEnvBool("S3_USE_SSL", true),
+		S3Prefix:       getEnv("S3_PREFIX", ""),
+		S3CreateBucket: getEnvBool("S3_CREATE_BUCKET", false),
+		PhotoTimezone:  getEnv("PHOTO_TIMEZONE", "Asia/Shanghai"),
+		PhotoMaxPixels: int64(getEnvInt("PHOTO_MAX_PIXELS", 50_000_000)),
+
+		PhotoVariants:       getEnv("PHOTO_VARIANTS", "thumb:320:jpeg,medium:1280:jpeg"),
+		PhotoVariantWorkers: getEnvInt("PHOTO_VARIANT_WORKERS", 2),
+		PhotoVariantQueue:   getEnvInt("PHOTO_VARIANT_QUEUE", 64),
+		PhotoStripGPS:       getEnvBool("PHOTO_STRIP_GPS", true),
+		PhotoDupThreshold:   getEnvInt("PHOTO_DUP_THRESHOLD", 10),
+
+		AdminToken: getEnv("ADMIN_TOKEN", ""),
 	}
 }
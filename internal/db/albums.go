@@ -0,0 +1,281 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrAlbumNotFound 相册不存在
+var ErrAlbumNotFound = errors.New("album not found")
+
+// Album 相册，通常对应一次旅行。StartDate/EndDate 为 YYYY-MM-DD，可为空
+type Album struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	Name        string `gorm:"size:128;not null" json:"name"`
+	Description string `gorm:"type:text" json:"description,omitempty"`
+	Destination string `gorm:"size:128" json:"destination,omitempty"`
+	StartDate   string `gorm:"size:10" json:"start_date,omitempty"`
+	EndDate     string `gorm:"size:10" json:"end_date,omitempty"`
+
+	// PhotoCount 相册内照片数，由仓库查询时填充
+	PhotoCount int64 `gorm:"-" json:"photo_count"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AlbumPhoto 相册中的一张照片及其关联的地点：机位（SpotID）或百度地点（PlaceUID）。
+// PlaceBDLat/PlaceBDLng 为地点的 BD-09 坐标，照片没有 GPS 时用于定位
+type AlbumPhoto struct {
+	AlbumID    uint      `gorm:"primaryKey" json:"album_id"`
+	PhotoID    uint      `gorm:"primaryKey;index" json:"photo_id"`
+	SpotID     *uint     `gorm:"index" json:"spot_id,omitempty"`
+	PlaceUID   string    `gorm:"size:64" json:"place_uid,omitempty"`
+	PlaceName  string    `gorm:"size:128" json:"place_name,omitempty"`
+	PlaceBDLat float64   `gorm:"column:place_bd_lat" json:"place_bd_lat,omitempty"`
+	PlaceBDLng float64   `gorm:"column:place_bd_lng" json:"place_bd_lng,omitempty"`
+	AddedAt    time.Time `json:"added_at"`
+}
+
+// AlbumEntry 相册中的照片连同照片记录与关联机位，Spot 在机位已删除时为 nil
+type AlbumEntry struct {
+	AlbumPhoto
+	Photo Photo      `json:"photo"`
+	Spot  *PhotoSpot `json:"spot,omitempty"`
+}
+
+// AlbumRepository 相册的持久化
+type AlbumRepository interface {
+	List(ctx context.Context, limit, offset int) ([]Album, int64, error)
+	// Get 不存在时返回 ErrAlbumNotFound
+	Get(ctx context.Context, id uint) (*Album, error)
+	Create(ctx context.Context, album *Album) error
+	Update(ctx context.Context, album *Album) error
+	// Delete 同时删除相册与照片的关联，照片本身保留
+	Delete(ctx context.Context, id uint) error
+	// AddPhotos 把照片加入相册，已在相册中的照片更新其关联地点
+	AddPhotos(ctx context.Context, albumID uint, items []AlbumPhoto) error
+	// RemovePhoto 照片不在相册中时返回 ErrPhotoNotFound
+	RemovePhoto(ctx context.Context, albumID, photoID uint) error
+	// Entries 相册中的照片，按拍摄时间排序，没有拍摄时间的排在最后
+	Entries(ctx context.Context, albumID uint) ([]AlbumEntry, error)
+}
+
+type gormAlbumRepository struct {
+	db *gorm.DB
+}
+
+func NewAlbumRepository(db *gorm.DB) AlbumRepository {
+	return &gormAlbumRepository{db: db}
+}
+
+func (r *gormAlbumRepository) List(ctx context.Context, limit, offset int) ([]Album, int64, error) {
+	q := r.db.WithContext(ctx).Model(&Album{})
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	if offset > 0 {
+		q = q.Offset(offset)
+	}
+	var albums []Album
+	if err := q.Order("id DESC").Find(&albums).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := r.fillCounts(ctx, albums); err != nil {
+		return nil, 0, err
+	}
+	return albums, total, nil
+}
+
+func (r *gormAlbumRepository) Get(ctx context.Context, id uint) (*Album, error) {
+	var album Album
+	err := r.db.WithContext(ctx).First(&album, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAlbumNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	albums := []Album{album}
+	if err := r.fillCounts(ctx, albums); err != nil {
+		return nil, err
+	}
+	return &albums[0], nil
+}
+
+func (r *gormAlbumRepository) Create(ctx context.Context, album *Album) error {
+	album.ID = 0
+	return r.db.WithContext(ctx).Create(album).Error
+}
+
+func (r *gormAlbumRepository) Update(ctx context.Context, album *Album) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing Album
+		err := tx.Select("id", "created_at").First(&existing, album.ID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAlbumNotFound
+		}
+		if err != nil {
+			return err
+		}
+		album.CreatedAt = existing.CreatedAt
+		if err := tx.Save(album).Error; err != nil {
+			return err
+		}
+		return tx.Model(&AlbumPhoto{}).Where("album_id = ?", album.ID).Count(&album.PhotoCount).Error
+	})
+}
+
+func (r *gormAlbumRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&Album{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrAlbumNotFound
+		}
+		return tx.Where("album_id = ?", id).Delete(&AlbumPhoto{}).Error
+	})
+}
+
+func (r *gormAlbumRepository) AddPhotos(ctx context.Context, albumID uint, items []AlbumPhoto) error {
+	if len(items) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id").First(&Album{}, albumID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAlbumNotFound
+		} else if err != nil {
+			return err
+		}
+		now := time.Now()
+		for i := range items {
+			items[i].AlbumID = albumID
+			items[i].AddedAt = now
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "album_id"}, {Name: "photo_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"spot_id", "place_uid", "place_name", "place_bd_lat", "place_bd_lng"}),
+		}).Create(&items).Error
+	})
+}
+
+func (r *gormAlbumRepository) RemovePhoto(ctx context.Context, albumID, photoID uint) error {
+	res := r.db.WithContext(ctx).Where("album_id = ? AND photo_id = ?", albumID, photoID).Delete(&AlbumPhoto{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrPhotoNotFound
+	}
+	return nil
+}
+
+func (r *gormAlbumRepository) Entries(ctx context.Context, albumID uint) ([]AlbumEntry, error) {
+	db := r.db.WithContext(ctx)
+	var links []AlbumPhoto
+	if err := db.Where("album_id = ?", albumID).Find(&links).Error; err != nil {
+		return nil, err
+	}
+	if len(links) == 0 {
+		return nil, nil
+	}
+
+	photoIDs := make([]uint, 0, len(links))
+	var spotIDs []uint
+	for _, l := range links {
+		photoIDs = append(photoIDs, l.PhotoID)
+		if l.SpotID != nil {
+			spotIDs = append(spotIDs, *l.SpotID)
+		}
+	}
+	var photos []Photo
+	if err := db.Where("id IN ?", photoIDs).Find(&photos).Error; err != nil {
+		return nil, err
+	}
+	photoByID := make(map[uint]Photo, len(photos))
+	for _, p := range photos {
+		photoByID[p.ID] = p
+	}
+	spotByID := make(map[uint]*PhotoSpot)
+	if len(spotIDs) > 0 {
+		var spots []PhotoSpot
+		if err := db.Preload("Notes").Where("id IN ?", spotIDs).Find(&spots).Error; err != nil {
+			return nil, err
+		}
+		for i := range spots {
+			spotByID[spots[i].ID] = &spots[i]
+		}
+	}
+
+	entries := make([]AlbumEntry, 0, len(links))
+	for _, l := range links {
+		p, ok := photoByID[l.PhotoID]
+		if !ok {
+			continue
+		}
+		e := AlbumEntry{AlbumPhoto: l, Photo: p}
+		if l.SpotID != nil {
+			e.Spot = spotByID[*l.SpotID]
+		}
+		entries = append(entries, e)
+	}
+	sortEntries(entries)
+	return entries, nil
+}
+
+// fillCounts 填充各相册的照片数
+func (r *gormAlbumRepository) fillCounts(ctx context.Context, albums []Album) error {
+	if len(albums) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(albums))
+	for i, a := range albums {
+		ids[i] = a.ID
+	}
+	var rows []struct {
+		AlbumID uint
+		Count   int64
+	}
+	if err := r.db.WithContext(ctx).Model(&AlbumPhoto{}).
+		Select("album_id, COUNT(*) AS count").
+		Where("album_id IN ?", ids).
+		Group("album_id").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.AlbumID] = row.Count
+	}
+	for i := range albums {
+		albums[i].PhotoCount = counts[albums[i].ID]
+	}
+	return nil
+}
+
+// sortEntries 按拍摄时间排序，没有拍摄时间的排在最后，再按照片 ID
+func sortEntries(entries []AlbumEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i].Photo, entries[j].Photo
+		switch {
+		case a.TakenAt != nil && b.TakenAt != nil && !a.TakenAt.Equal(*b.TakenAt):
+			return a.TakenAt.Before(*b.TakenAt)
+		case a.TakenAt != nil && b.TakenAt == nil:
+			return true
+		case a.TakenAt == nil && b.TakenAt != nil:
+			return false
+		}
+		return a.ID < b.ID
+	})
+}
@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAlbumRepository(t *testing.T) {
+	ctx := context.Background()
+	database := openTestDB(t)
+	repo := NewAlbumRepository(database)
+	photos := NewPhotoRepository(database)
+	spots := NewSpotRepository(database)
+
+	album := &Album{Name: "北京三日", Destination: "北京", StartDate: "2024-05-01", EndDate: "2024-05-03"}
+	if err := repo.Create(ctx, album); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Create(ctx, &Album{Name: "上海"}); err != nil {
+		t.Fatal(err)
+	}
+
+	spot := &PhotoSpot{Name: "故宫角楼", City: "北京"}
+	if err := spots.Create(ctx, spot); err != nil {
+		t.Fatal(err)
+	}
+	early := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	late := early.Add(2 * time.Hour)
+	ps := []*Photo{
+		{StorageKey: "late", SHA256: "late", TakenAt: &late},
+		{StorageKey: "undated", SHA256: "undated"},
+		{StorageKey: "early", SHA256: "early", TakenAt: &early},
+	}
+	for _, p := range ps {
+		if err := photos.Create(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := repo.AddPhotos(ctx, album.ID, []AlbumPhoto{
+		{PhotoID: ps[0].ID, PlaceUID: "uid-1", PlaceName: "景山公园"},
+		{PhotoID: ps[1].ID},
+		{PhotoID: ps[2].ID, SpotID: &spot.ID},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// 再次加入时更新关联地点，不重复
+	if err := repo.AddPhotos(ctx, album.ID, []AlbumPhoto{{PhotoID: ps[0].ID, PlaceUID: "uid-2", PlaceName: "北海公园"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.AddPhotos(ctx, 999, []AlbumPhoto{{PhotoID: ps[0].ID}}); !errors.Is(err, ErrAlbumNotFound) {
+		t.Errorf("AddPhotos to missing album: err = %v, want ErrAlbumNotFound", err)
+	}
+
+	got, err := repo.Get(ctx, album.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "北京三日" || got.PhotoCount != 3 {
+		t.Errorf("Get = %+v, want 3 photos", got)
+	}
+	if _, err := repo.Get(ctx, 999); !errors.Is(err, ErrAlbumNotFound) {
+		t.Errorf("Get(999): err = %v, want ErrAlbumNotFound", err)
+	}
+
+	list, total, err := repo.List(ctx, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(list) != 2 || list[0].Name != "上海" || list[0].PhotoCount != 0 || list[1].PhotoCount != 3 {
+		t.Errorf("List = %+v (total %d), want newest first with photo counts", list, total)
+	}
+	if list, total, _ := repo.List(ctx, 1, 1); total != 2 || len(list) != 1 || list[0].ID != album.ID {
+		t.Errorf("paged List = %+v (total %d)", list, total)
+	}
+
+	entries, err := repo.Entries(ctx, album.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Entries = %d, want 3", len(entries))
+	}
+	if entries[0].Photo.ID != ps[2].ID || entries[1].Photo.ID != ps[0].ID || entries[2].Photo.ID != ps[1].ID {
+		t.Errorf("Entries order = %d, %d, %d; want early, late, undated", entries[0].Photo.ID, entries[1].Photo.ID, entries[2].Photo.ID)
+	}
+	if entries[0].Spot == nil || entries[0].Spot.Name != "故宫角楼" {
+		t.Errorf("first entry spot = %+v", entries[0].Spot)
+	}
+	if entries[1].PlaceUID != "uid-2" || entries[1].PlaceName != "北海公园" || entries[1].Spot != nil {
+		t.Errorf("second entry = %+v, want the updated place", entries[1].AlbumPhoto)
+	}
+
+	// 机位删除后照片仍在相册中，Spot 为 nil
+	if err := spots.Delete(ctx, spot.ID); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := repo.Entries(ctx, album.ID); len(entries) != 3 || entries[0].Spot != nil {
+		t.Errorf("entries after spot delete = %d, spot %+v", len(entries), entries[0].Spot)
+	}
+
+	got.Description = "五一"
+	got.CreatedAt = time.Time{}
+	if err := repo.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	if got.CreatedAt.IsZero() || got.PhotoCount != 3 {
+		t.Errorf("Update = %+v, want CreatedAt kept and photo count filled", got)
+	}
+	if err := repo.Update(ctx, &Album{ID: 999, Name: "x"}); !errors.Is(err, ErrAlbumNotFound) {
+		t.Errorf("Update of missing album: err = %v, want ErrAlbumNotFound", err)
+	}
+
+	if err := repo.RemovePhoto(ctx, album.ID, ps[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.RemovePhoto(ctx, album.ID, ps[1].ID); !errors.Is(err, ErrPhotoNotFound) {
+		t.Errorf("second RemovePhoto: err = %v, want ErrPhotoNotFound", err)
+	}
+
+	if err := repo.Delete(ctx, album.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Get(ctx, album.ID); !errors.Is(err, ErrAlbumNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrAlbumNotFound", err)
+	}
+	if entries, err := repo.Entries(ctx, album.ID); err != nil || len(entries) != 0 {
+		t.Errorf("Entries after Delete = %d, %v", len(entries), err)
+	}
+	if _, err := photos.Get(ctx, ps[0].ID); err != nil {
+		t.Errorf("photo deleted with its album: %v", err)
+	}
+	if err := repo.Delete(ctx, album.ID); !errors.Is(err, ErrAlbumNotFound) {
+		t.Errorf("second Delete: err = %v, want ErrAlbumNotFound", err)
+	}
+}
@@ -1,17 +1,181 @@
 package db
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/glebarez/sqlite"
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"github.com/huangqi/photo-backend/internal/config"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
 
+// 支持的数据库驱动
+const (
+	DriverMySQL  = "mysql"
+	DriverSQLite = "sqlite"
+)
+
+// pingTimeout 单次连通性检查的超时
+const pingTimeout = 5 * time.Second
+
+// Connect 按 cfg.DBDriver 连接数据库并设置连接池。DBDSN 为空时 MySQL 由 DB_HOST 等字段拼接，SQLite 使用 photo.db
 func Connect(cfg config.Config) (*gorm.DB, error) {
-	dsn := fmt.Sprintf(
-		"%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		cfg.DBUser, cfg.DBPassword, cfg.DBHost, cfg.DBPort, cfg.DBName,
-	)
-	return gorm.Open(mysql.Open(dsn), &gorm.Config{})
-} 
\ No newline at end of file
+	dialector, err := dialectorFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.DBConnMaxIdleTime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	if err := sqlDB.PingContext(ctx); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// ConnectWithRetry 连接失败时按指数退避重试，用于等待 compose 中的 MySQL 就绪。
+// 共尝试 cfg.DBConnectRetries+1 次，等待时间从 DBConnectBackoff 开始翻倍，不超过 DBConnectMaxBackoff
+func ConnectWithRetry(ctx context.Context, cfg config.Config) (*gorm.DB, error) {
+	backoff := cfg.DBConnectBackoff
+	for attempt := 0; ; attempt++ {
+		db, err := Connect(cfg)
+		if err == nil {
+			return db, nil
+		}
+		if attempt >= cfg.DBConnectRetries {
+			return nil, err
+		}
+		log.Printf("database not ready (attempt %d/%d): %v; retrying in %s", attempt+1, cfg.DBConnectRetries+1, err, backoff)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if cfg.DBConnectMaxBackoff > 0 && backoff > cfg.DBConnectMaxBackoff {
+			backoff = cfg.DBConnectMaxBackoff
+		}
+	}
+}
+
+// Ping 检查数据库连通性
+func Ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	return sqlDB.PingContext(ctx)
+}
+
+// PoolStats 连接池统计
+type PoolStats struct {
+	Driver             string  `json:"driver"`
+	MaxOpenConnections int     `json:"max_open_connections"`
+	OpenConnections    int     `json:"open_connections"`
+	InUse              int     `json:"in_use"`
+	Idle               int     `json:"idle"`
+	WaitCount          int64   `json:"wait_count"`
+	WaitDurationMs     float64 `json:"wait_duration_ms"`
+	MaxIdleClosed      int64   `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64   `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64   `json:"max_lifetime_closed"`
+}
+
+// Stats 返回连接池统计
+func Stats(db *gorm.DB) (PoolStats, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return PoolStats{}, err
+	}
+	s := sqlDB.Stats()
+	return PoolStats{
+		Driver:             db.Dialector.Name(),
+		MaxOpenConnections: s.MaxOpenConnections,
+		OpenConnections:    s.OpenConnections,
+		InUse:              s.InUse,
+		Idle:               s.Idle,
+		WaitCount:          s.WaitCount,
+		WaitDurationMs:     float64(s.WaitDuration) / float64(time.Millisecond),
+		MaxIdleClosed:      s.MaxIdleClosed,
+		MaxIdleTimeClosed:  s.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  s.MaxLifetimeClosed,
+	}, nil
+}
+
+func dialectorFor(cfg config.Config) (gorm.Dialector, error) {
+	switch cfg.DBDriver {
+	case "", DriverMySQL:
+		dsn, err := mysqlDSN(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return mysql.Open(dsn), nil
+	case DriverSQLite:
+		dsn := cfg.DBDSN
+		if dsn == "" {
+			dsn = "photo.db"
+		}
+		return sqlite.Open(sqliteDSN(dsn)), nil
+	default:
+		return nil, fmt.Errorf("unsupported DB_DRIVER %q (want %s or %s)", cfg.DBDriver, DriverMySQL, DriverSQLite)
+	}
+}
+
+// mysqlDSN 拼接或解析 DSN，并按 DBSSLMode 设置 TLS
+func mysqlDSN(cfg config.Config) (string, error) {
+	var mc *mysqldriver.Config
+	if cfg.DBDSN != "" {
+		var err error
+		if mc, err = mysqldriver.ParseDSN(cfg.DBDSN); err != nil {
+			return "", fmt.Errorf("invalid DB_DSN: %w", err)
+		}
+	} else {
+		mc = mysqldriver.NewConfig()
+		mc.User = cfg.DBUser
+		mc.Passwd = cfg.DBPassword
+		mc.Net = "tcp"
+		mc.Addr = net.JoinHostPort(cfg.DBHost, strconv.Itoa(cfg.DBPort))
+		mc.DBName = cfg.DBName
+		mc.ParseTime = true
+		mc.Loc = time.Local
+		mc.Params = map[string]string{"charset": "utf8mb4"}
+	}
+	if err := applyMySQLTLS(mc, cfg); err != nil {
+		return "", err
+	}
+	return mc.FormatDSN(), nil
+}
+
+// sqliteDSN 未显式指定 pragma 时开启外键与 busy_timeout，避免并发写入时立即返回 SQLITE_BUSY
+func sqliteDSN(dsn string) string {
+	if dsn == ":memory:" || strings.Contains(dsn, "_pragma=") {
+		return dsn
+	}
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+}
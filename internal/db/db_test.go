@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/huangqi/photo-backend/internal/config"
+	"gorm.io/gorm"
+)
+
+// openTestDB 在临时目录中打开 SQLite 并执行全部迁移
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := Connect(config.Config{
+		DBDriver: DriverSQLite,
+		DBDSN:    filepath.Join(t.TempDir(), "test.db"),
+	})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if _, err := NewMigrator(db).Up(context.Background()); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	return db
+}
+
+func TestMigratorUpDown(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	m := NewMigrator(db)
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("second Up applied %d migrations, want 0", len(applied))
+	}
+	status, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != len(migrations) {
+		t.Fatalf("status has %d entries, want %d", len(status), len(migrations))
+	}
+	for _, st := range status {
+		if !st.Applied {
+			t.Errorf("migration %d %s not applied", st.Version, st.Name)
+		}
+	}
+
+	reverted, err := m.Down(ctx, len(migrations))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reverted) != len(migrations) {
+		t.Errorf("Down reverted %d migrations, want %d", len(reverted), len(migrations))
+	}
+	for _, table := range []string{"xhs_links", "xhs_posts", "photo_spots", "photos", "albums"} {
+		if db.Migrator().HasTable(table) {
+			t.Errorf("table %s still exists after Down", table)
+		}
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up after Down: %v", err)
+	}
+}
+
+func TestConnectUnsupportedDriver(t *testing.T) {
+	if _, err := Connect(config.Config{DBDriver: "postgres"}); err == nil {
+		t.Fatal("expected error for unsupported driver")
+	}
+}
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// XHSLink 采集到的小红书笔记链接，按笔记 ID 去重
+type XHSLink struct {
+	ID          uint      `gorm:"primaryKey" json:"-"`
+	NoteID      string    `gorm:"size:32;uniqueIndex;not null" json:"note_id"`
+	URL         string    `gorm:"size:1024" json:"url"`
+	XsecToken   string    `gorm:"size:128" json:"xsec_token,omitempty"`
+	Title       string    `gorm:"size:512" json:"title"`
+	Category    string    `gorm:"size:64;index" json:"category"`
+	Query       string    `gorm:"size:255" json:"query"`
+	Rank        int       `json:"rank"`
+	BestRank    int       `json:"best_rank"`
+	SeenCount   int       `json:"seen_count"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `gorm:"index" json:"last_seen_at"`
+}
+
+// LinkStore XHSLink 的持久化
+type LinkStore struct {
+	db *gorm.DB
+}
+
+func NewLinkStore(db *gorm.DB) *LinkStore {
+	return &LinkStore{db: db}
+}
+
+// Record 记录一批采集结果：新链接写入首次出现时间，已有链接更新最近出现时间、排名与出现次数
+func (s *LinkStore) Record(ctx context.Context, links []XHSLink) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, l := range links {
+			var existing XHSLink
+			err := tx.Where("note_id = ?", l.NoteID).First(&existing).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				l.ID = 0
+				l.BestRank = l.Rank
+				l.SeenCount = 1
+				if l.FirstSeenAt.IsZero() {
+					l.FirstSeenAt = l.LastSeenAt
+				}
+				if err := tx.Create(&l).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			existing.Rank = l.Rank
+			if l.Rank < existing.BestRank || existing.BestRank == 0 {
+				existing.BestRank = l.Rank
+				existing.Category = l.Category
+				existing.Query = l.Query
+			}
+			if l.Title != "" {
+				existing.Title = l.Title
+			}
+			if l.XsecToken != "" {
+				existing.URL = l.URL
+				existing.XsecToken = l.XsecToken
+			}
+			existing.SeenCount++
+			existing.LastSeenAt = l.LastSeenAt
+			if err := tx.Save(&existing).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// List 按最近出现时间倒序列出链接，category 为空时返回全部
+func (s *LinkStore) List(ctx context.Context, category string) ([]XHSLink, error) {
+	q := s.db.WithContext(ctx).Order("last_seen_at DESC, best_rank ASC")
+	if category != "" {
+		q = q.Where("category = ?", category)
+	}
+	var links []XHSLink
+	if err := q.Find(&links).Error; err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// LinksCSVHeader xhs_links.csv 的列
+var LinksCSVHeader = []string{"note_id", "title", "url", "category", "query", "rank", "best_rank", "seen_count", "first_seen_at", "last_seen_at"}
+
+// ExportCSV 以 xhs_links.csv 格式导出
+func (s *LinkStore) ExportCSV(ctx context.Context, w io.Writer, category string) error {
+	links, err := s.List(ctx, category)
+	if err != nil {
+		return err
+	}
+	return WriteLinksCSV(w, links)
+}
+
+// WriteLinksCSV 以 xhs_links.csv 格式写出已查询的链接
+func WriteLinksCSV(w io.Writer, links []XHSLink) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(LinksCSVHeader); err != nil {
+		return err
+	}
+	for _, l := range links {
+		if err := cw.Write([]string{
+			l.NoteID,
+			l.Title,
+			l.URL,
+			l.Category,
+			l.Query,
+			strconv.Itoa(l.Rank),
+			strconv.Itoa(l.BestRank),
+			strconv.Itoa(l.SeenCount),
+			l.FirstSeenAt.Format(time.RFC3339),
+			l.LastSeenAt.Format(time.RFC3339),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLinkStoreRecord(t *testing.T) {
+	ctx := context.Background()
+	store := NewLinkStore(openTestDB(t))
+
+	first := time.Now().Add(-time.Hour).Truncate(time.Second)
+	second := first.Add(30 * time.Minute)
+	if err := store.Record(ctx, []XHSLink{
+		{NoteID: "a", URL: "https://x/a", Title: "A", Category: "北京", Query: "q1", Rank: 3, LastSeenAt: first},
+		{NoteID: "b", URL: "https://x/b", Title: "B", Category: "日本", Query: "q2", Rank: 1, LastSeenAt: first},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// 再次出现时更新排名与出现次数；更好的排名改写分类与短语
+	if err := store.Record(ctx, []XHSLink{
+		{NoteID: "a", URL: "https://x/a?xsec_token=t", XsecToken: "t", Category: "婚纱旅拍", Query: "q3", Rank: 1, LastSeenAt: second},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	links, err := store.List(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 2 || links[0].NoteID != "a" {
+		t.Fatalf("List = %+v, want a (most recent) then b", links)
+	}
+	a := links[0]
+	if a.SeenCount != 2 || a.Rank != 1 || a.BestRank != 1 || a.Category != "婚纱旅拍" || a.Query != "q3" {
+		t.Errorf("a = %+v", a)
+	}
+	if a.Title != "A" || a.XsecToken != "t" || a.URL != "https://x/a?xsec_token=t" {
+		t.Errorf("a title/url = %q %q %q", a.Title, a.URL, a.XsecToken)
+	}
+	if !a.FirstSeenAt.Equal(first) || !a.LastSeenAt.Equal(second) {
+		t.Errorf("a seen at %v..%v, want %v..%v", a.FirstSeenAt, a.LastSeenAt, first, second)
+	}
+
+	filtered, err := store.List(ctx, "日本")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered) != 1 || filtered[0].NoteID != "b" {
+		t.Errorf("List(日本) = %+v", filtered)
+	}
+}
+
+func TestLinkStoreExportCSV(t *testing.T) {
+	ctx := context.Background()
+	store := NewLinkStore(openTestDB(t))
+	if err := store.Record(ctx, []XHSLink{
+		{NoteID: "a", URL: "https://x/a", Title: "标题, 带逗号", Category: "北京", Rank: 2, LastSeenAt: time.Now()},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := store.ExportCSV(ctx, &buf, ""); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d csv records, want header and 1 row", len(records))
+	}
+	if strings.Join(records[0], ",") != strings.Join(LinksCSVHeader, ",") {
+		t.Errorf("header = %v", records[0])
+	}
+	if records[1][0] != "a" || records[1][1] != "标题, 带逗号" || records[1][6] != "2" {
+		t.Errorf("row = %v", records[1])
+	}
+}
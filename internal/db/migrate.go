@@ -0,0 +1,187 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration 一个版本化的表结构变更。Version 单调递增且不可复用
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SQL 由若干条 SQL 语句构造迁移函数
+func SQL(statements ...string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// SchemaMigration schema_migrations 表中的一条记录
+type SchemaMigration struct {
+	Version   int64  `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"size:255"`
+	AppliedAt time.Time
+}
+
+// MigrationStatus 迁移状态
+type MigrationStatus struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// migrationLockName 多副本同时启动时用于互斥的锁名
+const migrationLockName = "photo_backend_schema_migrations"
+
+// migrationLockTimeout 等待其他副本完成迁移的最长时间（秒）
+const migrationLockTimeout = 120
+
+// Migrator 执行版本化迁移
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewMigrator 使用 migrations.go 中登记的迁移
+func NewMigrator(db *gorm.DB) *Migrator {
+	return NewMigratorWith(db, migrations)
+}
+
+// NewMigratorWith 使用指定的迁移列表
+func NewMigratorWith(db *gorm.DB, list []Migration) *Migrator {
+	sorted := append([]Migration(nil), list...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return &Migrator{db: db, migrations: sorted}
+}
+
+// Up 按版本顺序执行所有未执行的迁移，返回本次执行的迁移
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func(conn *gorm.DB) error {
+		done, err := m.appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := done[mig.Version]; ok {
+				continue
+			}
+			if err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := mig.Up(tx); err != nil {
+					return err
+				}
+				return tx.Create(&SchemaMigration{Version: mig.Version, Name: mig.Name, AppliedAt: time.Now()}).Error
+			}); err != nil {
+				return fmt.Errorf("migration %d %s up: %w", mig.Version, mig.Name, err)
+			}
+			applied = append(applied, mig)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down 按版本倒序回滚最近 steps 个已执行的迁移
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withLock(ctx, func(conn *gorm.DB) error {
+		done, err := m.appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := done[mig.Version]; !ok {
+				continue
+			}
+			if mig.Down == nil {
+				return fmt.Errorf("migration %d %s is irreversible", mig.Version, mig.Name)
+			}
+			if err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := mig.Down(tx); err != nil {
+					return err
+				}
+				return tx.Delete(&SchemaMigration{Version: mig.Version}).Error
+			}); err != nil {
+				return fmt.Errorf("migration %d %s down: %w", mig.Version, mig.Name, err)
+			}
+			reverted = append(reverted, mig)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status 列出所有迁移及其执行状态；schema_migrations 中存在但代码中没有的版本也会列出
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	if err := m.db.WithContext(ctx).AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, err
+	}
+	done, err := m.appliedVersions(m.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	var out []MigrationStatus
+	for _, mig := range m.migrations {
+		st := MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if rec, ok := done[mig.Version]; ok {
+			st.Applied = true
+			st.AppliedAt = &rec.AppliedAt
+			delete(done, mig.Version)
+		}
+		out = append(out, st)
+	}
+	for _, rec := range done {
+		out = append(out, MigrationStatus{Version: rec.Version, Name: rec.Name + " (unknown)", Applied: true, AppliedAt: &rec.AppliedAt})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+func (m *Migrator) appliedVersions(conn *gorm.DB) (map[int64]SchemaMigration, error) {
+	var records []SchemaMigration
+	if err := conn.Order("version").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	done := make(map[int64]SchemaMigration, len(records))
+	for _, r := range records {
+		done[r.Version] = r
+	}
+	return done, nil
+}
+
+// withLock 在单个连接上持有迁移锁执行 fn，避免多个副本同时迁移。
+// MySQL 使用 GET_LOCK 会话级咨询锁，其他数据库不加锁。
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if conn.Dialector.Name() == "mysql" {
+			var got *int
+			if err := conn.Raw("SELECT GET_LOCK(?, ?)", migrationLockName, migrationLockTimeout).Scan(&got).Error; err != nil {
+				return fmt.Errorf("acquire migration lock: %w", err)
+			}
+			if got == nil || *got != 1 {
+				return fmt.Errorf("acquire migration lock: timed out after %ds", migrationLockTimeout)
+			}
+			defer conn.Exec("SELECT RELEASE_LOCK(?)", migrationLockName)
+		}
+		if err := conn.AutoMigrate(&SchemaMigration{}); err != nil {
+			return err
+		}
+		// 新会话仍使用同一连接，但不带上面语句残留的表名
+		return fn(conn.Session(&gorm.Session{NewDB: true}))
+	})
+}
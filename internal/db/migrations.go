@@ -0,0 +1,310 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// migrations 已登记的迁移，按版本追加，已发布的迁移不要修改。
+// 迁移中使用各自冻结的表结构，而不是会继续演进的模型。
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_xhs_links",
+		Up:      createTables(&xhsLinkV1{}),
+		Down:    dropTables(&xhsLinkV1{}),
+	},
+	{
+		Version: 2,
+		Name:    "create_xhs_search_cache",
+		Up:      createTables(&xhsPostV1{}, &xhsSearchQueryV1{}, &xhsSearchResultV1{}),
+		Down:    dropTables(&xhsSearchResultV1{}, &xhsSearchQueryV1{}, &xhsPostV1{}),
+	},
+	{
+		Version: 3,
+		Name:    "create_photo_spots",
+		Up:      createTables(&photoSpotV3{}, &photoSpotNoteV3{}, &photoSpotTagV3{}),
+		Down:    dropTables(&photoSpotTagV3{}, &photoSpotNoteV3{}, &photoSpotV3{}),
+	},
+	{
+		Version: 4,
+		Name:    "create_photos",
+		Up:      createTables(&photoV4{}),
+		Down:    dropTables(&photoV4{}),
+	},
+	{
+		Version: 5,
+		Name:    "add_photo_hashes",
+		Up:      steps(addColumns(&photoHashV5{}, "DHash", "PHash"), createIndex(&photoHashV5{}, "idx_photos_dhash")),
+		Down:    steps(dropIndex(&photoHashV5{}, "idx_photos_dhash"), dropColumns(&photoHashV5{}, "DHash", "PHash")),
+	},
+	{
+		Version: 6,
+		Name:    "create_albums",
+		Up:      createTables(&albumV6{}, &albumPhotoV6{}),
+		Down:    dropTables(&albumPhotoV6{}, &albumV6{}),
+	},
+	{
+		Version: 7,
+		Name:    "drop_photo_dhash_index",
+		// 近似查找用内存中的哈希索引，按 dhash 等值查询的索引用不上
+		Up:   dropIndex(&photoHashV5{}, "idx_photos_dhash"),
+		Down: createIndex(&photoHashV5{}, "idx_photos_dhash"),
+	},
+}
+
+// createTables 表不存在时创建，兼容此前用 AutoMigrate 建过表的库
+func createTables(models ...any) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, model := range models {
+			if tx.Migrator().HasTable(model) {
+				continue
+			}
+			if err := tx.Migrator().CreateTable(model); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func dropTables(models ...any) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(models...)
+	}
+}
+
+// steps 依次执行多个迁移函数
+func steps(fns ...func(tx *gorm.DB) error) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, fn := range fns {
+			if err := fn(tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// addColumns 列不存在时添加
+func addColumns(model any, fields ...string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		for _, field := range fields {
+			if m.HasColumn(model, field) {
+				continue
+			}
+			if err := m.AddColumn(model, field); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// dropColumns 列存在时删除。不用 Migrator().DropColumn：SQLite 驱动会重建整张表并丢掉其他索引
+func dropColumns(model any, fields ...string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		for _, name := range fields {
+			field := stmt.Schema.LookUpField(name)
+			if field == nil {
+				return fmt.Errorf("%s has no field %s", stmt.Table, name)
+			}
+			if !tx.Migrator().HasColumn(model, name) {
+				continue
+			}
+			if err := tx.Exec("ALTER TABLE ? DROP COLUMN ?", clause.Table{Name: stmt.Table}, clause.Column{Name: field.DBName}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func dropIndex(model any, name string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		if !tx.Migrator().HasIndex(model, name) {
+			return nil
+		}
+		return tx.Migrator().DropIndex(model, name)
+	}
+}
+
+// createIndex 索引不存在时按模型上的声明创建
+func createIndex(model any, name string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		if tx.Migrator().HasIndex(model, name) {
+			return nil
+		}
+		return tx.Migrator().CreateIndex(model, name)
+	}
+}
+
+type xhsLinkV1 struct {
+	ID          uint   `gorm:"primaryKey"`
+	NoteID      string `gorm:"size:32;uniqueIndex;not null"`
+	URL         string `gorm:"size:1024"`
+	XsecToken   string `gorm:"size:128"`
+	Title       string `gorm:"size:512"`
+	Category    string `gorm:"size:64;index"`
+	Query       string `gorm:"size:255"`
+	Rank        int
+	BestRank    int
+	SeenCount   int
+	FirstSeenAt time.Time
+	LastSeenAt  time.Time `gorm:"index"`
+}
+
+func (xhsLinkV1) TableName() string { return "xhs_links" }
+
+type xhsPostV1 struct {
+	ID        uint   `gorm:"primaryKey"`
+	NoteID    string `gorm:"size:32;uniqueIndex;not null"`
+	Title     string `gorm:"size:512"`
+	Author    string `gorm:"size:128"`
+	Likes     int
+	Excerpt   string `gorm:"type:text"`
+	PostURL   string `gorm:"size:1024"`
+	XsecToken string `gorm:"size:128"`
+	CoverURL  string `gorm:"size:1024"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (xhsPostV1) TableName() string { return "xhs_posts" }
+
+type xhsSearchQueryV1 struct {
+	ID            uint   `gorm:"primaryKey"`
+	Keyword       string `gorm:"size:191;uniqueIndex;not null"`
+	FetchLimit    int
+	ResultCount   int
+	LastFetchedAt time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (xhsSearchQueryV1) TableName() string { return "xhs_search_queries" }
+
+type xhsSearchResultV1 struct {
+	QueryID   uint `gorm:"primaryKey"`
+	PostID    uint `gorm:"primaryKey;index"`
+	Position  int  `gorm:"index"`
+	FetchedAt time.Time
+}
+
+func (xhsSearchResultV1) TableName() string { return "xhs_search_results" }
+
+type photoSpotV3 struct {
+	ID             uint   `gorm:"primaryKey"`
+	Name           string `gorm:"size:128;not null"`
+	City           string `gorm:"size:64;index"`
+	Address        string `gorm:"size:255"`
+	Description    string `gorm:"type:text"`
+	Lat            float64
+	Lng            float64
+	BDLat          float64 `gorm:"column:bd_lat"`
+	BDLng          float64 `gorm:"column:bd_lng"`
+	BaiduUID       string  `gorm:"column:baidu_uid;size:64;index"`
+	Tags           string  `gorm:"type:text"`
+	BestSeasons    string  `gorm:"type:text"`
+	BestTimeOfDay  string  `gorm:"type:text"`
+	PoseCategories string  `gorm:"type:text"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+func (photoSpotV3) TableName() string { return "photo_spots" }
+
+type photoSpotNoteV3 struct {
+	ID     uint   `gorm:"primaryKey"`
+	SpotID uint   `gorm:"uniqueIndex:idx_photo_spot_notes_spot_note;not null"`
+	NoteID string `gorm:"size:32;uniqueIndex:idx_photo_spot_notes_spot_note;not null"`
+	URL    string `gorm:"size:1024"`
+	Title  string `gorm:"size:512"`
+}
+
+func (photoSpotNoteV3) TableName() string { return "photo_spot_notes" }
+
+type photoSpotTagV3 struct {
+	SpotID uint   `gorm:"primaryKey"`
+	Tag    string `gorm:"primaryKey;size:64;index"`
+}
+
+func (photoSpotTagV3) TableName() string { return "photo_spot_tags" }
+
+type photoV4 struct {
+	ID              uint   `gorm:"primaryKey"`
+	StorageKey      string `gorm:"size:128;index;not null"`
+	SHA256          string `gorm:"column:sha256;size:64;index"`
+	OriginalName    string `gorm:"size:255"`
+	ContentType     string `gorm:"size:64"`
+	Size            int64
+	Width           int
+	Height          int
+	TakenAt         *time.Time `gorm:"index"`
+	CameraMake      string     `gorm:"size:64"`
+	CameraModel     string     `gorm:"size:128"`
+	LensModel       string     `gorm:"size:128"`
+	FocalLength     float64
+	FocalLength35mm int    `gorm:"column:focal_length_35mm"`
+	ExposureTime    string `gorm:"size:16"`
+	FNumber         float64
+	ISO             int
+	Orientation     int
+	HasGPS          bool
+	Lat             float64
+	Lng             float64
+	BDLat           float64 `gorm:"column:bd_lat"`
+	BDLng           float64 `gorm:"column:bd_lng"`
+	Altitude        float64
+	Address         string `gorm:"size:255"`
+	Province        string `gorm:"size:64"`
+	City            string `gorm:"size:64;index"`
+	District        string `gorm:"size:64"`
+	POIName         string `gorm:"column:poi_name;size:128"`
+	POIUID          string `gorm:"column:poi_uid;size:64"`
+	CreatedAt       time.Time
+}
+
+func (photoV4) TableName() string { return "photos" }
+
+// photoHashV5 v5 给 photos 增加的感知哈希列
+type photoHashV5 struct {
+	DHash string `gorm:"column:dhash;size:16;index:idx_photos_dhash"`
+	PHash string `gorm:"column:phash;size:16"`
+}
+
+func (photoHashV5) TableName() string { return "photos" }
+
+type albumV6 struct {
+	ID          uint   `gorm:"primaryKey"`
+	Name        string `gorm:"size:128;not null"`
+	Description string `gorm:"type:text"`
+	Destination string `gorm:"size:128"`
+	StartDate   string `gorm:"size:10"`
+	EndDate     string `gorm:"size:10"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (albumV6) TableName() string { return "albums" }
+
+type albumPhotoV6 struct {
+	AlbumID    uint    `gorm:"primaryKey"`
+	PhotoID    uint    `gorm:"primaryKey;index"`
+	SpotID     *uint   `gorm:"index"`
+	PlaceUID   string  `gorm:"size:64"`
+	PlaceName  string  `gorm:"size:128"`
+	PlaceBDLat float64 `gorm:"column:place_bd_lat"`
+	PlaceBDLng float64 `gorm:"column:place_bd_lng"`
+	AddedAt    time.Time
+}
+
+func (albumPhotoV6) TableName() string { return "album_photos" }
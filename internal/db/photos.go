@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrPhotoNotFound 照片不存在
+var ErrPhotoNotFound = errors.New("photo not found")
+
+// Photo 上传的照片及其 EXIF、拍摄地点。Lat/Lng 为 WGS-84，BDLat/BDLng 为 BD-09
+type Photo struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+	// StorageKey 原图的存储键，由内容 SHA-256 决定
+	StorageKey   string `gorm:"size:128;index;not null" json:"-"`
+	SHA256       string `gorm:"column:sha256;size:64;index" json:"sha256"`
+	OriginalName string `gorm:"size:255" json:"original_name,omitempty"`
+	ContentType  string `gorm:"size:64" json:"content_type"`
+	Size         int64  `json:"size"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+
+	TakenAt         *time.Time `gorm:"index" json:"taken_at,omitempty"`
+	CameraMake      string     `gorm:"size:64" json:"camera_make,omitempty"`
+	CameraModel     string     `gorm:"size:128" json:"camera_model,omitempty"`
+	LensModel       string     `gorm:"size:128" json:"lens_model,omitempty"`
+	FocalLength     float64    `json:"focal_length,omitempty"`
+	FocalLength35mm int        `gorm:"column:focal_length_35mm" json:"focal_length_35mm,omitempty"`
+	ExposureTime    string     `gorm:"size:16" json:"exposure_time,omitempty"`
+	FNumber         float64    `json:"f_number,omitempty"`
+	ISO             int        `json:"iso,omitempty"`
+	Orientation     int        `json:"orientation,omitempty"`
+
+	HasGPS   bool    `json:"has_gps"`
+	Lat      float64 `json:"lat,omitempty"`
+	Lng      float64 `json:"lng,omitempty"`
+	BDLat    float64 `gorm:"column:bd_lat" json:"bd_lat,omitempty"`
+	BDLng    float64 `gorm:"column:bd_lng" json:"bd_lng,omitempty"`
+	Altitude float64 `json:"altitude,omitempty"`
+
+	Address  string `gorm:"size:255" json:"address,omitempty"`
+	Province string `gorm:"size:64" json:"province,omitempty"`
+	City     string `gorm:"size:64;index" json:"city,omitempty"`
+	District string `gorm:"size:64" json:"district,omitempty"`
+	// POIName / POIUID 最近的景点
+	POIName string `gorm:"column:poi_name;size:128" json:"poi_name,omitempty"`
+	POIUID  string `gorm:"column:poi_uid;size:64" json:"poi_uid,omitempty"`
+
+	// DHash / PHash 感知哈希（16 位十六进制），用于查找近似重复的照片
+	DHash string `gorm:"column:dhash;size:16" json:"dhash,omitempty"`
+	PHash string `gorm:"column:phash;size:16" json:"phash,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+
+	// Variants 派生图名称到地址，由接口层填充
+	Variants map[string]string `gorm:"-" json:"variants,omitempty"`
+}
+
+// PhotoHash 照片的感知哈希，近似重复查找只需要这几列
+type PhotoHash struct {
+	ID    uint
+	DHash string `gorm:"column:dhash"`
+	PHash string `gorm:"column:phash"`
+}
+
+// PhotoRepository 照片的持久化
+type PhotoRepository interface {
+	Create(ctx context.Context, photo *Photo) error
+	// Get 不存在时返回 ErrPhotoNotFound
+	Get(ctx context.Context, id uint) (*Photo, error)
+	// GetMany 按 ID 批量查询，不存在的 ID 被忽略，结果按 ID 排序
+	GetMany(ctx context.Context, ids []uint) ([]Photo, error)
+	// ListHashes ID 大于 afterID、已计算感知哈希的照片，按 ID 排序
+	ListHashes(ctx context.Context, afterID uint) ([]PhotoHash, error)
+	// CountHashes 已计算感知哈希的照片数
+	CountHashes(ctx context.Context) (int64, error)
+	// ListUnhashed ID 大于 afterID、尚未计算感知哈希的照片，按 ID 排序，最多 limit 张
+	ListUnhashed(ctx context.Context, afterID uint, limit int) ([]Photo, error)
+	// SetHashes 保存感知哈希
+	SetHashes(ctx context.Context, id uint, dhash, phash string) error
+}
+
+type gormPhotoRepository struct {
+	db *gorm.DB
+}
+
+func NewPhotoRepository(db *gorm.DB) PhotoRepository {
+	return &gormPhotoRepository{db: db}
+}
+
+func (r *gormPhotoRepository) Create(ctx context.Context, photo *Photo) error {
+	photo.ID = 0
+	return r.db.WithContext(ctx).Create(photo).Error
+}
+
+func (r *gormPhotoRepository) Get(ctx context.Context, id uint) (*Photo, error) {
+	var photo Photo
+	err := r.db.WithContext(ctx).First(&photo, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPhotoNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &photo, nil
+}
+
+func (r *gormPhotoRepository) GetMany(ctx context.Context, ids []uint) ([]Photo, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var photos []Photo
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Order("id").Find(&photos).Error; err != nil {
+		return nil, err
+	}
+	return photos, nil
+}
+
+func (r *gormPhotoRepository) ListHashes(ctx context.Context, afterID uint) ([]PhotoHash, error) {
+	var hashes []PhotoHash
+	if err := r.db.WithContext(ctx).Model(&Photo{}).
+		Select("id", "dhash", "phash").
+		Where("id > ? AND dhash <> '' AND phash <> ''", afterID).
+		Order("id").
+		Find(&hashes).Error; err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+func (r *gormPhotoRepository) CountHashes(ctx context.Context) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&Photo{}).Where("dhash <> '' AND phash <> ''").Count(&n).Error
+	return n, err
+}
+
+func (r *gormPhotoRepository) ListUnhashed(ctx context.Context, afterID uint, limit int) ([]Photo, error) {
+	var photos []Photo
+	if err := r.db.WithContext(ctx).
+		Where("id > ? AND (dhash IS NULL OR dhash = '' OR phash IS NULL OR phash = '')", afterID).
+		Order("id").
+		Limit(limit).
+		Find(&photos).Error; err != nil {
+		return nil, err
+	}
+	return photos, nil
+}
+
+func (r *gormPhotoRepository) SetHashes(ctx context.Context, id uint, dhash, phash string) error {
+	return r.db.WithContext(ctx).Model(&Photo{}).Where("id = ?", id).
+		Updates(map[string]any{"dhash": dhash, "phash": phash}).Error
+}
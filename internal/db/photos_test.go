@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPhotoRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewPhotoRepository(openTestDB(t))
+
+	taken := time.Date(2024, 6, 21, 19, 30, 0, 0, time.UTC)
+	photos := []*Photo{
+		{StorageKey: "a", SHA256: "a", Width: 4000, Height: 3000, TakenAt: &taken, HasGPS: true, Lat: 39.9, Lng: 116.4, DHash: "00ff00ff00ff00ff", PHash: "0f0f0f0f0f0f0f0f"},
+		{StorageKey: "b", SHA256: "b"},
+		{StorageKey: "c", SHA256: "c", DHash: "ffff0000ffff0000", PHash: "f0f0f0f0f0f0f0f0"},
+	}
+	for _, p := range photos {
+		if err := repo.Create(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := repo.Get(ctx, photos[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Width != 4000 || !got.HasGPS || got.TakenAt == nil || !got.TakenAt.Equal(taken) {
+		t.Errorf("Get = %+v", got)
+	}
+	if _, err := repo.Get(ctx, 999); !errors.Is(err, ErrPhotoNotFound) {
+		t.Errorf("Get(999): err = %v, want ErrPhotoNotFound", err)
+	}
+
+	many, err := repo.GetMany(ctx, []uint{photos[2].ID, 999, photos[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(many) != 2 || many[0].ID != photos[0].ID || many[1].ID != photos[2].ID {
+		t.Errorf("GetMany = %d photos, want photos a and c in ID order", len(many))
+	}
+
+	hashes, err := repo.ListHashes(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hashes) != 2 || hashes[0].DHash != "00ff00ff00ff00ff" || hashes[1].PHash != "f0f0f0f0f0f0f0f0" {
+		t.Errorf("ListHashes = %+v, want only hashed photos", hashes)
+	}
+	if n, err := repo.CountHashes(ctx); err != nil || n != 2 {
+		t.Errorf("CountHashes = %d, %v; want 2", n, err)
+	}
+	if hashes, err := repo.ListHashes(ctx, photos[0].ID); err != nil || len(hashes) != 1 || hashes[0].ID != photos[2].ID {
+		t.Errorf("ListHashes after %d = %+v, %v; want photo c", photos[0].ID, hashes, err)
+	}
+
+	unhashed, err := repo.ListUnhashed(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unhashed) != 1 || unhashed[0].ID != photos[1].ID {
+		t.Fatalf("ListUnhashed = %d photos, want photo b", len(unhashed))
+	}
+	if err := repo.SetHashes(ctx, photos[1].ID, "0123456789abcdef", "fedcba9876543210"); err != nil {
+		t.Fatal(err)
+	}
+	if unhashed, err := repo.ListUnhashed(ctx, 0, 10); err != nil || len(unhashed) != 0 {
+		t.Errorf("ListUnhashed after SetHashes = %d photos, %v", len(unhashed), err)
+	}
+	if hashes, _ := repo.ListHashes(ctx, 0); len(hashes) != 3 {
+		t.Errorf("ListHashes after SetHashes = %d rows, want 3", len(hashes))
+	}
+	if n, err := repo.CountHashes(ctx); err != nil || n != 3 {
+		t.Errorf("CountHashes = %d, %v; want 3", n, err)
+	}
+}
@@ -0,0 +1,215 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrSpotNotFound 机位不存在
+var ErrSpotNotFound = errors.New("photo spot not found")
+
+// PhotoSpot 拍照机位。Lat/Lng 为 WGS-84，BDLat/BDLng 为 BD-09（百度地图使用）
+type PhotoSpot struct {
+	ID          uint    `gorm:"primaryKey" json:"id"`
+	Name        string  `gorm:"size:128;not null" json:"name"`
+	City        string  `gorm:"size:64;index" json:"city"`
+	Address     string  `gorm:"size:255" json:"address,omitempty"`
+	Description string  `gorm:"type:text" json:"description,omitempty"`
+	Lat         float64 `json:"lat"`
+	Lng         float64 `json:"lng"`
+	BDLat       float64 `gorm:"column:bd_lat" json:"bd_lat"`
+	BDLng       float64 `gorm:"column:bd_lng" json:"bd_lng"`
+	BaiduUID    string  `gorm:"column:baidu_uid;size:64;index" json:"baidu_uid,omitempty"`
+
+	Tags           StringList `gorm:"type:text" json:"tags"`
+	BestSeasons    StringList `gorm:"type:text" json:"best_seasons"`
+	BestTimeOfDay  StringList `gorm:"type:text" json:"best_time_of_day"`
+	PoseCategories StringList `gorm:"type:text" json:"pose_categories"`
+
+	Notes []PhotoSpotNote `gorm:"foreignKey:SpotID" json:"xhs_notes"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PhotoSpotNote 机位关联的小红书笔记
+type PhotoSpotNote struct {
+	ID     uint   `gorm:"primaryKey" json:"-"`
+	SpotID uint   `gorm:"uniqueIndex:idx_photo_spot_notes_spot_note;not null" json:"-"`
+	NoteID string `gorm:"size:32;uniqueIndex:idx_photo_spot_notes_spot_note;not null" json:"note_id"`
+	URL    string `gorm:"size:1024" json:"url,omitempty"`
+	Title  string `gorm:"size:512" json:"title,omitempty"`
+}
+
+// PhotoSpotTag 标签索引，用于按标签过滤；与 PhotoSpot.Tags 同步维护
+type PhotoSpotTag struct {
+	SpotID uint   `gorm:"primaryKey"`
+	Tag    string `gorm:"primaryKey;size:64;index"`
+}
+
+// SpotFilter 机位列表过滤条件，零值表示不过滤
+type SpotFilter struct {
+	City   string
+	Tag    string
+	Limit  int
+	Offset int
+}
+
+// SpotRepository 机位的持久化
+type SpotRepository interface {
+	List(ctx context.Context, filter SpotFilter) ([]PhotoSpot, int64, error)
+	// Get 不存在时返回 ErrSpotNotFound
+	Get(ctx context.Context, id uint) (*PhotoSpot, error)
+	Create(ctx context.Context, spot *PhotoSpot) error
+	// Update 整体替换机位字段、标签与关联笔记
+	Update(ctx context.Context, spot *PhotoSpot) error
+	Delete(ctx context.Context, id uint) error
+}
+
+type gormSpotRepository struct {
+	db *gorm.DB
+}
+
+func NewSpotRepository(db *gorm.DB) SpotRepository {
+	return &gormSpotRepository{db: db}
+}
+
+func (r *gormSpotRepository) List(ctx context.Context, filter SpotFilter) ([]PhotoSpot, int64, error) {
+	q := r.db.WithContext(ctx).Model(&PhotoSpot{})
+	if filter.City != "" {
+		q = q.Where("city = ?", filter.City)
+	}
+	if filter.Tag != "" {
+		q = q.Where("id IN (?)", r.db.Model(&PhotoSpotTag{}).Select("spot_id").Where("tag = ?", normalizeTag(filter.Tag)))
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if filter.Limit > 0 {
+		q = q.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		q = q.Offset(filter.Offset)
+	}
+	var spots []PhotoSpot
+	if err := q.Preload("Notes").Order("id").Find(&spots).Error; err != nil {
+		return nil, 0, err
+	}
+	return spots, total, nil
+}
+
+func (r *gormSpotRepository) Get(ctx context.Context, id uint) (*PhotoSpot, error) {
+	var spot PhotoSpot
+	err := r.db.WithContext(ctx).Preload("Notes").First(&spot, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSpotNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &spot, nil
+}
+
+func (r *gormSpotRepository) Create(ctx context.Context, spot *PhotoSpot) error {
+	spot.ID = 0
+	spot.Tags = normalizeTags(spot.Tags)
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		notes := spot.Notes
+		if err := tx.Omit(clause.Associations).Create(spot).Error; err != nil {
+			return err
+		}
+		return replaceSpotChildren(tx, spot, notes)
+	})
+}
+
+func (r *gormSpotRepository) Update(ctx context.Context, spot *PhotoSpot) error {
+	spot.Tags = normalizeTags(spot.Tags)
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing PhotoSpot
+		err := tx.Select("id", "created_at").First(&existing, spot.ID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSpotNotFound
+		}
+		if err != nil {
+			return err
+		}
+		spot.CreatedAt = existing.CreatedAt
+		notes := spot.Notes
+		if err := tx.Omit(clause.Associations).Save(spot).Error; err != nil {
+			return err
+		}
+		return replaceSpotChildren(tx, spot, notes)
+	})
+}
+
+func (r *gormSpotRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&PhotoSpot{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrSpotNotFound
+		}
+		if err := tx.Where("spot_id = ?", id).Delete(&PhotoSpotTag{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("spot_id = ?", id).Delete(&PhotoSpotNote{}).Error
+	})
+}
+
+// replaceSpotChildren 重建标签索引与关联笔记，同一笔记只保留一条
+func replaceSpotChildren(tx *gorm.DB, spot *PhotoSpot, notes []PhotoSpotNote) error {
+	if err := tx.Where("spot_id = ?", spot.ID).Delete(&PhotoSpotTag{}).Error; err != nil {
+		return err
+	}
+	for _, tag := range spot.Tags {
+		if err := tx.Create(&PhotoSpotTag{SpotID: spot.ID, Tag: tag}).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Where("spot_id = ?", spot.ID).Delete(&PhotoSpotNote{}).Error; err != nil {
+		return err
+	}
+	spot.Notes = make([]PhotoSpotNote, 0, len(notes))
+	seen := make(map[string]bool, len(notes))
+	for _, n := range notes {
+		if n.NoteID == "" || seen[n.NoteID] {
+			continue
+		}
+		seen[n.NoteID] = true
+		n.ID = 0
+		n.SpotID = spot.ID
+		if err := tx.Create(&n).Error; err != nil {
+			return err
+		}
+		spot.Notes = append(spot.Notes, n)
+	}
+	return nil
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// normalizeTags 去空白、转小写并去重，保持原有顺序
+func normalizeTags(tags StringList) StringList {
+	out := make(StringList, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		t = normalizeTag(t)
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+	return out
+}
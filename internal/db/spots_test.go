@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSpotRepositoryCRUD(t *testing.T) {
+	ctx := context.Background()
+	repo := NewSpotRepository(openTestDB(t))
+
+	spot := &PhotoSpot{
+		Name:          "故宫角楼",
+		City:          "北京",
+		Lat:           39.9234,
+		Lng:           116.3908,
+		Tags:          StringList{" 夜景 ", "古建", "夜景"},
+		BestTimeOfDay: StringList{"sunset"},
+		Notes: []PhotoSpotNote{
+			{NoteID: "6639eb29000000001e03aa87", Title: "机位"},
+			{NoteID: "6639eb29000000001e03aa87"},
+			{NoteID: ""},
+		},
+	}
+	if err := repo.Create(ctx, spot); err != nil {
+		t.Fatal(err)
+	}
+	if spot.ID == 0 {
+		t.Fatal("Create did not assign an ID")
+	}
+	if err := repo.Create(ctx, &PhotoSpot{Name: "外滩", City: "上海", Tags: StringList{"夜景"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repo.Get(ctx, spot.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Tags) != 2 || got.Tags[0] != "夜景" || got.Tags[1] != "古建" {
+		t.Errorf("tags = %q, want normalized [夜景 古建]", got.Tags)
+	}
+	if len(got.BestTimeOfDay) != 1 || got.BestTimeOfDay[0] != "sunset" {
+		t.Errorf("best_time_of_day = %q", got.BestTimeOfDay)
+	}
+	if len(got.Notes) != 1 || got.Notes[0].Title != "机位" {
+		t.Errorf("notes = %+v, want one deduplicated note", got.Notes)
+	}
+
+	tests := []struct {
+		filter SpotFilter
+		want   int64
+	}{
+		{SpotFilter{}, 2},
+		{SpotFilter{City: "北京"}, 1},
+		{SpotFilter{Tag: "夜景"}, 2},
+		{SpotFilter{Tag: " 古建 "}, 1},
+		{SpotFilter{City: "上海", Tag: "古建"}, 0},
+	}
+	for _, tt := range tests {
+		spots, total, err := repo.List(ctx, tt.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != tt.want || int64(len(spots)) != tt.want {
+			t.Errorf("List(%+v) = %d spots, total %d; want %d", tt.filter, len(spots), total, tt.want)
+		}
+	}
+	spots, total, err := repo.List(ctx, SpotFilter{Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(spots) != 1 || spots[0].Name != "外滩" {
+		t.Errorf("paged List = %d spots (total %d)", len(spots), total)
+	}
+
+	// Update 整体替换标签与笔记
+	got.Tags = StringList{"日出"}
+	got.Notes = []PhotoSpotNote{{NoteID: "68a85a11000000001d0233d9"}}
+	if err := repo.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := repo.Get(ctx, spot.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updated.Notes) != 1 || updated.Notes[0].NoteID != "68a85a11000000001d0233d9" {
+		t.Errorf("notes after update = %+v", updated.Notes)
+	}
+	if !updated.CreatedAt.Equal(got.CreatedAt) {
+		t.Errorf("CreatedAt changed on update: %v -> %v", got.CreatedAt, updated.CreatedAt)
+	}
+	if _, total, _ := repo.List(ctx, SpotFilter{Tag: "夜景"}); total != 1 {
+		t.Errorf("tag index not replaced: %d spots tagged 夜景, want 1", total)
+	}
+
+	if err := repo.Delete(ctx, spot.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Get(ctx, spot.ID); !errors.Is(err, ErrSpotNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrSpotNotFound", err)
+	}
+	if err := repo.Delete(ctx, spot.ID); !errors.Is(err, ErrSpotNotFound) {
+		t.Errorf("second Delete: err = %v, want ErrSpotNotFound", err)
+	}
+	if err := repo.Update(ctx, &PhotoSpot{ID: spot.ID, Name: "x"}); !errors.Is(err, ErrSpotNotFound) {
+		t.Errorf("Update of deleted spot: err = %v, want ErrSpotNotFound", err)
+	}
+}
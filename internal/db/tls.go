@@ -0,0 +1,127 @@
+package db
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/huangqi/photo-backend/internal/config"
+)
+
+// mysqlTLSConfigName 注册到驱动的自定义 TLS 配置名
+const mysqlTLSConfigName = "photo-backend"
+
+// applyMySQLTLS 按 DBSSLMode 设置 TLS，取值与 mysql 客户端的 --ssl-mode 一致：
+//
+//	disabled         不加密
+//	preferred        服务器支持时加密，不校验证书
+//	required         必须加密，不校验证书
+//	verify-ca        必须加密，用 DB_SSL_CA 校验证书链
+//	verify-identity  在 verify-ca 的基础上校验主机名
+//
+// 为空时保持 DSN 原样（拼接的 DSN 即不加密）。
+func applyMySQLTLS(mc *mysqldriver.Config, cfg config.Config) error {
+	mode := strings.ToLower(strings.ReplaceAll(cfg.DBSSLMode, "_", "-"))
+	switch mode {
+	case "":
+		return nil
+	case "disabled", "disable", "false":
+		mc.TLSConfig = "false"
+		return nil
+	case "preferred", "prefer":
+		mc.TLSConfig = "preferred"
+		return nil
+	case "required", "require", "true":
+		if cfg.DBSSLCA == "" && cfg.DBSSLCert == "" {
+			mc.TLSConfig = "skip-verify"
+			return nil
+		}
+	case "verify-ca", "verify-identity", "verify-full":
+		if cfg.DBSSLCA == "" {
+			return fmt.Errorf("DB_SSLMODE=%s requires DB_SSL_CA", cfg.DBSSLMode)
+		}
+	default:
+		return fmt.Errorf("unsupported DB_SSLMODE %q", cfg.DBSSLMode)
+	}
+
+	tlsCfg, err := buildMySQLTLS(mode, mc.Addr, cfg)
+	if err != nil {
+		return err
+	}
+	if err := mysqldriver.RegisterTLSConfig(mysqlTLSConfigName, tlsCfg); err != nil {
+		return err
+	}
+	mc.TLSConfig = mysqlTLSConfigName
+	return nil
+}
+
+func buildMySQLTLS(mode, addr string, cfg config.Config) (*tls.Config, error) {
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	var roots *x509.CertPool
+	if cfg.DBSSLCA != "" {
+		pem, err := os.ReadFile(cfg.DBSSLCA)
+		if err != nil {
+			return nil, fmt.Errorf("read DB_SSL_CA: %w", err)
+		}
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("DB_SSL_CA %s contains no certificates", cfg.DBSSLCA)
+		}
+		tlsCfg.RootCAs = roots
+	}
+	if cfg.DBSSLCert != "" || cfg.DBSSLKey != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.DBSSLCert, cfg.DBSSLKey)
+		if err != nil {
+			return nil, fmt.Errorf("load DB_SSL_CERT/DB_SSL_KEY: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	switch mode {
+	case "verify-identity", "verify-full":
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		tlsCfg.ServerName = host
+	case "verify-ca":
+		// 只校验证书链，不校验主机名
+		tlsCfg.InsecureSkipVerify = true
+		tlsCfg.VerifyPeerCertificate = verifyChain(roots)
+	default:
+		// required 且提供了 CA 时按 verify-ca 处理，只有客户端证书时不校验服务器
+		tlsCfg.InsecureSkipVerify = true
+		if roots != nil {
+			tlsCfg.VerifyPeerCertificate = verifyChain(roots)
+		}
+	}
+	return tlsCfg, nil
+}
+
+func verifyChain(roots *x509.CertPool) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("mysql server sent no certificate")
+		}
+		certs := make([]*x509.Certificate, len(rawCerts))
+		for i, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return err
+			}
+			certs[i] = cert
+		}
+		intermediates := x509.NewCertPool()
+		for _, c := range certs[1:] {
+			intermediates.AddCert(c)
+		}
+		_, err := certs[0].Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates})
+		return err
+	}
+}
@@ -0,0 +1,189 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/huangqi/photo-backend/internal/mcp"
+	"gorm.io/gorm"
+)
+
+// ErrNotCached 关键词尚未缓存
+var ErrNotCached = errors.New("search results not cached")
+
+// XHSPost 笔记，按笔记 ID 去重
+type XHSPost struct {
+	ID        uint   `gorm:"primaryKey"`
+	NoteID    string `gorm:"size:32;uniqueIndex;not null"`
+	Title     string `gorm:"size:512"`
+	Author    string `gorm:"size:128"`
+	Likes     int
+	Excerpt   string `gorm:"type:text"`
+	PostURL   string `gorm:"size:1024"`
+	XsecToken string `gorm:"size:128"`
+	CoverURL  string `gorm:"size:1024"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// XHSSearchQuery 搜索关键词及最近一次从 MCP 拉取的信息
+type XHSSearchQuery struct {
+	ID      uint   `gorm:"primaryKey"`
+	Keyword string `gorm:"size:191;uniqueIndex;not null"`
+	// FetchLimit 最近一次拉取时请求的数量；有结果因缺少笔记 ID 未入库时记为入库数量
+	FetchLimit int
+	// ResultCount 入库的结果数量
+	ResultCount   int
+	LastFetchedAt time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// XHSSearchResult 关键词到笔记的结果排名，Position 从 1 开始
+type XHSSearchResult struct {
+	QueryID   uint `gorm:"primaryKey"`
+	PostID    uint `gorm:"primaryKey;index"`
+	Position  int  `gorm:"index"`
+	FetchedAt time.Time
+}
+
+// CachedSearch 缓存的搜索结果
+type CachedSearch struct {
+	Posts       []mcp.XHSPost
+	FetchLimit  int
+	ResultCount int
+	FetchedAt   time.Time
+}
+
+// Covers 缓存能否满足 limit 条的请求：入库数量不少于 limit，
+// 或上游返回的结果少于请求数量（关键词已没有更多结果）且当时请求的数量不少于 limit
+func (c *CachedSearch) Covers(limit int) bool {
+	if c.ResultCount >= limit {
+		return true
+	}
+	return c.ResultCount < c.FetchLimit && c.FetchLimit >= limit
+}
+
+// XHSRepository 小红书搜索结果的持久化
+type XHSRepository interface {
+	// SaveSearchResults 用一次拉取的结果替换关键词的排名，并更新笔记信息。
+	// 没有可入库的结果时不写入，避免解析失败或临时的空结果覆盖已有缓存
+	SaveSearchResults(ctx context.Context, keyword string, limit int, posts []mcp.XHSPost, fetchedAt time.Time) error
+	// GetSearchResults 按排名返回缓存结果，未缓存时返回 ErrNotCached
+	GetSearchResults(ctx context.Context, keyword string) (*CachedSearch, error)
+}
+
+type gormXHSRepository struct {
+	db *gorm.DB
+}
+
+func NewXHSRepository(db *gorm.DB) XHSRepository {
+	return &gormXHSRepository{db: db}
+}
+
+func (r *gormXHSRepository) SaveSearchResults(ctx context.Context, keyword string, limit int, posts []mcp.XHSPost, fetchedAt time.Time) error {
+	// 没有笔记 ID 的结果无法去重，不入库
+	stored := make([]mcp.XHSPost, 0, len(posts))
+	seen := make(map[string]bool, len(posts))
+	for _, p := range posts {
+		if p.ID == "" || seen[p.ID] {
+			continue
+		}
+		seen[p.ID] = true
+		stored = append(stored, p)
+	}
+	if len(stored) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := XHSSearchQuery{Keyword: keyword}
+		if err := tx.Where("keyword = ?", keyword).FirstOrCreate(&query).Error; err != nil {
+			return err
+		}
+		query.FetchLimit = limit
+		if len(stored) < len(posts) {
+			// 有结果未入库时，无法据此判断关键词已没有更多结果
+			query.FetchLimit = len(stored)
+		}
+		query.ResultCount = len(stored)
+		query.LastFetchedAt = fetchedAt
+		if err := tx.Save(&query).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("query_id = ?", query.ID).Delete(&XHSSearchResult{}).Error; err != nil {
+			return err
+		}
+
+		for i, p := range stored {
+			var post XHSPost
+			err := tx.Where("note_id = ?", p.ID).First(&post).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			post.NoteID = p.ID
+			post.Title = p.Title
+			post.Author = p.Author
+			post.Likes = p.Likes
+			post.Excerpt = p.Excerpt
+			post.PostURL = p.PostURL
+			post.XsecToken = p.XsecToken
+			post.CoverURL = p.CoverURL
+			if err := tx.Save(&post).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&XHSSearchResult{
+				QueryID:   query.ID,
+				PostID:    post.ID,
+				Position:  i + 1,
+				FetchedAt: fetchedAt,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *gormXHSRepository) GetSearchResults(ctx context.Context, keyword string) (*CachedSearch, error) {
+	db := r.db.WithContext(ctx)
+	var query XHSSearchQuery
+	err := db.Where("keyword = ?", keyword).First(&query).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotCached
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []XHSPost
+	if err := db.Table("xhs_posts").
+		Select("xhs_posts.*").
+		Joins("JOIN xhs_search_results ON xhs_search_results.post_id = xhs_posts.id").
+		Where("xhs_search_results.query_id = ?", query.ID).
+		Order("xhs_search_results.position ASC").
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	posts := make([]mcp.XHSPost, len(rows))
+	for i, p := range rows {
+		posts[i] = mcp.XHSPost{
+			ID:        p.NoteID,
+			Title:     p.Title,
+			Author:    p.Author,
+			Likes:     p.Likes,
+			Excerpt:   p.Excerpt,
+			PostURL:   p.PostURL,
+			XsecToken: p.XsecToken,
+			CoverURL:  p.CoverURL,
+		}
+	}
+	return &CachedSearch{
+		Posts:       posts,
+		FetchLimit:  query.FetchLimit,
+		ResultCount: query.ResultCount,
+		FetchedAt:   query.LastFetchedAt,
+	}, nil
+}
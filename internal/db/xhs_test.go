@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/huangqi/photo-backend/internal/mcp"
+)
+
+func testPost(i int) mcp.XHSPost {
+	id := fmt.Sprintf("%024x", i)
+	return mcp.XHSPost{
+		ID:      id,
+		Title:   fmt.Sprintf("post %d", i),
+		PostURL: "https://www.xiaohongshu.com/explore/" + id,
+	}
+}
+
+func TestXHSRepositorySaveAndGet(t *testing.T) {
+	ctx := context.Background()
+	repo := NewXHSRepository(openTestDB(t))
+
+	if _, err := repo.GetSearchResults(ctx, "旅行"); !errors.Is(err, ErrNotCached) {
+		t.Fatalf("GetSearchResults on empty db: err = %v, want ErrNotCached", err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	posts := []mcp.XHSPost{testPost(3), testPost(1), testPost(3), testPost(2)}
+	if err := repo.SaveSearchResults(ctx, "旅行", 5, posts, now); err != nil {
+		t.Fatal(err)
+	}
+	cached, err := repo.GetSearchResults(ctx, "旅行")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, p := range cached.Posts {
+		ids = append(ids, p.ID)
+	}
+	want := []string{testPost(3).ID, testPost(1).ID, testPost(2).ID}
+	if fmt.Sprint(ids) != fmt.Sprint(want) {
+		t.Errorf("cached ids = %v, want %v (deduplicated, in rank order)", ids, want)
+	}
+	if cached.ResultCount != 3 || !cached.FetchedAt.Equal(now) {
+		t.Errorf("ResultCount = %d, FetchedAt = %v; want 3, %v", cached.ResultCount, cached.FetchedAt, now)
+	}
+
+	// 再次拉取替换排名，笔记信息更新
+	updated := testPost(2)
+	updated.Title = "updated"
+	if err := repo.SaveSearchResults(ctx, "旅行", 5, []mcp.XHSPost{updated}, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	cached, err = repo.GetSearchResults(ctx, "旅行")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cached.Posts) != 1 || cached.Posts[0].Title != "updated" {
+		t.Errorf("after refresh posts = %+v, want only the updated post", cached.Posts)
+	}
+}
+
+func TestXHSRepositorySkipsEmptyResults(t *testing.T) {
+	ctx := context.Background()
+	repo := NewXHSRepository(openTestDB(t))
+
+	if err := repo.SaveSearchResults(ctx, "旅行", 5, []mcp.XHSPost{testPost(1), testPost(2)}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	// 空结果与全部缺少笔记 ID 的结果不覆盖已有缓存
+	for _, posts := range [][]mcp.XHSPost{nil, {{Title: "no id"}}} {
+		if err := repo.SaveSearchResults(ctx, "旅行", 5, posts, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cached, err := repo.GetSearchResults(ctx, "旅行")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cached.Posts) != 2 {
+		t.Errorf("cached %d posts, want 2", len(cached.Posts))
+	}
+
+	if err := repo.SaveSearchResults(ctx, "空", 5, nil, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetSearchResults(ctx, "空"); !errors.Is(err, ErrNotCached) {
+		t.Errorf("empty results were cached: err = %v", err)
+	}
+}
+
+func TestCachedSearchCovers(t *testing.T) {
+	ctx := context.Background()
+	repo := NewXHSRepository(openTestDB(t))
+
+	save := func(keyword string, limit int, posts []mcp.XHSPost) *CachedSearch {
+		t.Helper()
+		if err := repo.SaveSearchResults(ctx, keyword, limit, posts, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		cached, err := repo.GetSearchResults(ctx, keyword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cached
+	}
+
+	full := save("full", 3, []mcp.XHSPost{testPost(1), testPost(2), testPost(3)})
+	// 上游返回的结果少于请求数量：关键词没有更多结果
+	exhausted := save("exhausted", 10, []mcp.XHSPost{testPost(1), testPost(2)})
+	// 有结果因缺少笔记 ID 未入库：只覆盖入库的数量
+	dropped := save("dropped", 10, []mcp.XHSPost{testPost(1), {Title: "no id"}})
+
+	tests := []struct {
+		name   string
+		cached *CachedSearch
+		limit  int
+		want   bool
+	}{
+		{"full within fetched", full, 3, true},
+		{"full beyond fetched", full, 4, false},
+		{"exhausted within fetch limit", exhausted, 10, true},
+		{"exhausted beyond fetch limit", exhausted, 11, false},
+		{"dropped within stored", dropped, 1, true},
+		{"dropped beyond stored", dropped, 2, false},
+	}
+	for _, tt := range tests {
+		if got := tt.cached.Covers(tt.limit); got != tt.want {
+			t.Errorf("%s: Covers(%d) = %v, want %v", tt.name, tt.limit, got, tt.want)
+		}
+	}
+}
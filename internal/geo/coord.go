@@ -0,0 +1,140 @@
+// Package geo 坐标系转换与距离计算。
+//
+// 国内地图使用的坐标系：
+//
+//	WGS-84  GPS 与 EXIF 中的原始坐标
+//	GCJ-02  国测局坐标，高德、腾讯等使用
+//	BD-09   百度坐标，在 GCJ-02 基础上再次偏移，百度地图 MCP 的输入输出均为 BD-09
+//
+// 境外坐标不做偏移。
+package geo
+
+import "math"
+
+const (
+	earthRadiusKm = 6371.0088
+
+	// 克拉索夫斯基椭球参数，GCJ-02 偏移算法使用
+	krasovskyA  = 6378245.0
+	krasovskyEE = 0.00669342162296594323
+
+	bdX = math.Pi * 3000.0 / 180.0
+)
+
+// Point 经纬度坐标（度）
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// Valid 纬度在 [-90,90]、经度在 [-180,180] 内
+func (p Point) Valid() bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lng >= -180 && p.Lng <= 180 &&
+		!math.IsNaN(p.Lat) && !math.IsNaN(p.Lng)
+}
+
+// IsZero 未设置坐标
+func (p Point) IsZero() bool {
+	return p.Lat == 0 && p.Lng == 0
+}
+
+// OutOfChina 粗略判断是否在中国境外，境外坐标不做偏移
+func OutOfChina(p Point) bool {
+	return p.Lng < 72.004 || p.Lng > 137.8347 || p.Lat < 0.8293 || p.Lat > 55.8271
+}
+
+// WGS84ToGCJ02 WGS-84 转 GCJ-02
+func WGS84ToGCJ02(p Point) Point {
+	if OutOfChina(p) {
+		return p
+	}
+	dLat, dLng := gcjOffset(p)
+	return Point{Lat: p.Lat + dLat, Lng: p.Lng + dLng}
+}
+
+// GCJ02ToWGS84 GCJ-02 转 WGS-84，迭代求逆，误差小于 1e-7 度
+func GCJ02ToWGS84(p Point) Point {
+	if OutOfChina(p) {
+		return p
+	}
+	w := p
+	for i := 0; i < 10; i++ {
+		g := WGS84ToGCJ02(w)
+		dLat, dLng := g.Lat-p.Lat, g.Lng-p.Lng
+		w.Lat -= dLat
+		w.Lng -= dLng
+		if math.Abs(dLat) < 1e-7 && math.Abs(dLng) < 1e-7 {
+			break
+		}
+	}
+	return w
+}
+
+// GCJ02ToBD09 GCJ-02 转 BD-09
+func GCJ02ToBD09(p Point) Point {
+	z := math.Sqrt(p.Lng*p.Lng+p.Lat*p.Lat) + 0.00002*math.Sin(p.Lat*bdX)
+	theta := math.Atan2(p.Lat, p.Lng) + 0.000003*math.Cos(p.Lng*bdX)
+	return Point{Lat: z*math.Sin(theta) + 0.006, Lng: z*math.Cos(theta) + 0.0065}
+}
+
+// BD09ToGCJ02 BD-09 转 GCJ-02
+func BD09ToGCJ02(p Point) Point {
+	x, y := p.Lng-0.0065, p.Lat-0.006
+	z := math.Sqrt(x*x+y*y) - 0.00002*math.Sin(y*bdX)
+	theta := math.Atan2(y, x) - 0.000003*math.Cos(x*bdX)
+	return Point{Lat: z * math.Sin(theta), Lng: z * math.Cos(theta)}
+}
+
+// WGS84ToBD09 WGS-84 转 BD-09
+func WGS84ToBD09(p Point) Point {
+	if OutOfChina(p) {
+		return p
+	}
+	return GCJ02ToBD09(WGS84ToGCJ02(p))
+}
+
+// BD09ToWGS84 BD-09 转 WGS-84
+func BD09ToWGS84(p Point) Point {
+	if OutOfChina(p) {
+		return p
+	}
+	return GCJ02ToWGS84(BD09ToGCJ02(p))
+}
+
+// DistanceKm 两点间的大圆距离（公里）
+func DistanceKm(a, b Point) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLng := (b.Lng - a.Lng) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func gcjOffset(p Point) (dLat, dLng float64) {
+	x, y := p.Lng-105.0, p.Lat-35.0
+	dLat = transformLat(x, y)
+	dLng = transformLng(x, y)
+	radLat := p.Lat / 180.0 * math.Pi
+	magic := math.Sin(radLat)
+	magic = 1 - krasovskyEE*magic*magic
+	sqrtMagic := math.Sqrt(magic)
+	dLat = (dLat * 180.0) / ((krasovskyA * (1 - krasovskyEE)) / (magic * sqrtMagic) * math.Pi)
+	dLng = (dLng * 180.0) / (krasovskyA / sqrtMagic * math.Cos(radLat) * math.Pi)
+	return dLat, dLng
+}
+
+func transformLat(x, y float64) float64 {
+	ret := -100.0 + 2.0*x + 3.0*y + 0.2*y*y + 0.1*x*y + 0.2*math.Sqrt(math.Abs(x))
+	ret += (20.0*math.Sin(6.0*x*math.Pi) + 20.0*math.Sin(2.0*x*math.Pi)) * 2.0 / 3.0
+	ret += (20.0*math.Sin(y*math.Pi) + 40.0*math.Sin(y/3.0*math.Pi)) * 2.0 / 3.0
+	ret += (160.0*math.Sin(y/12.0*math.Pi) + 320*math.Sin(y*math.Pi/30.0)) * 2.0 / 3.0
+	return ret
+}
+
+func transformLng(x, y float64) float64 {
+	ret := 300.0 + x + 2.0*y + 0.1*x*x + 0.1*x*y + 0.1*math.Sqrt(math.Abs(x))
+	ret += (20.0*math.Sin(6.0*x*math.Pi) + 20.0*math.Sin(2.0*x*math.Pi)) * 2.0 / 3.0
+	ret += (20.0*math.Sin(x*math.Pi) + 40.0*math.Sin(x/3.0*math.Pi)) * 2.0 / 3.0
+	ret += (150.0*math.Sin(x/12.0*math.Pi) + 300.0*math.Sin(x/30.0*math.Pi)) * 2.0 / 3.0
+	return ret
+}
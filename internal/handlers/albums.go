@@ -0,0 +1,370 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangqi/photo-backend/internal/album"
+	"github.com/huangqi/photo-backend/internal/db"
+	"github.com/huangqi/photo-backend/internal/geo"
+	"github.com/huangqi/photo-backend/internal/mcp"
+)
+
+const (
+	defaultAlbumLimit = 20
+	maxAlbumLimit     = 100
+	// maxAlbumPhotosPerRequest 一次最多加入的照片数
+	maxAlbumPhotosPerRequest = 200
+	// timelineTimeout 生成时间线时调用百度地图的总超时
+	timelineTimeout = 30 * time.Second
+)
+
+type AlbumHandler struct {
+	Repo   db.AlbumRepository
+	Photos db.PhotoRepository
+	Spots  db.SpotRepository
+	// Maps 为 nil 时时间线不做逆地理编码与路线规划
+	Maps mcp.BaiduMapsClient
+	// Location 按天分组使用的时区
+	Location *time.Location
+	// Photo 非 nil 时为照片填充派生图地址
+	Photo *PhotoHandler
+}
+
+func NewAlbumHandler(repo db.AlbumRepository, photos db.PhotoRepository, spots db.SpotRepository, maps mcp.BaiduMapsClient, loc *time.Location) *AlbumHandler {
+	return &AlbumHandler{Repo: repo, Photos: photos, Spots: spots, Maps: maps, Location: loc}
+}
+
+// albumRequest 创建与更新相册的请求体，日期格式 YYYY-MM-DD
+type albumRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Destination string `json:"destination"`
+	StartDate   string `json:"start_date"`
+	EndDate     string `json:"end_date"`
+}
+
+// albumPhotoRequest 加入相册的照片。spot_id 与 place_uid 至多给出一个；都不给时按照片自身的 GPS 定位
+type albumPhotoRequest struct {
+	PhotoID   uint     `json:"photo_id"`
+	SpotID    *uint    `json:"spot_id"`
+	PlaceUID  string   `json:"place_uid"`
+	PlaceName string   `json:"place_name"`
+	BDLat     *float64 `json:"bd_lat"`
+	BDLng     *float64 `json:"bd_lng"`
+}
+
+// List 相册列表，limit/offset 分页，新建的在前
+func (h *AlbumHandler) List(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultAlbumLimit)))
+	if err != nil || limit <= 0 {
+		limit = defaultAlbumLimit
+	}
+	limit = min(limit, maxAlbumLimit)
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	albums, total, err := h.Repo.List(c.Request.Context(), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": albums, "total": total, "limit": limit, "offset": offset})
+}
+
+// Get 相册信息及按拍摄时间排序的照片
+func (h *AlbumHandler) Get(c *gin.Context) {
+	id, ok := albumID(c)
+	if !ok {
+		return
+	}
+	a, err := h.Repo.Get(c.Request.Context(), id)
+	if err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+	entries, err := h.Repo.Entries(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if entries == nil {
+		entries = []db.AlbumEntry{}
+	}
+	for i := range entries {
+		h.fillVariants(&entries[i].Photo)
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"album": a, "photos": entries}})
+}
+
+// Create 创建相册
+func (h *AlbumHandler) Create(c *gin.Context) {
+	a, ok := bindAlbum(c)
+	if !ok {
+		return
+	}
+	if err := h.Repo.Create(c.Request.Context(), a); err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": a})
+}
+
+// Update 整体更新相册信息，不影响相册中的照片
+func (h *AlbumHandler) Update(c *gin.Context) {
+	id, ok := albumID(c)
+	if !ok {
+		return
+	}
+	a, ok := bindAlbum(c)
+	if !ok {
+		return
+	}
+	a.ID = id
+	if err := h.Repo.Update(c.Request.Context(), a); err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": a})
+}
+
+// Delete 删除相册，照片本身保留
+func (h *AlbumHandler) Delete(c *gin.Context) {
+	id, ok := albumID(c)
+	if !ok {
+		return
+	}
+	if err := h.Repo.Delete(c.Request.Context(), id); err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// AddPhotos 把照片加入相册并关联机位或百度地点；照片已在相册中时更新关联
+func (h *AlbumHandler) AddPhotos(c *gin.Context) {
+	id, ok := albumID(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		Photos []albumPhotoRequest `json:"photos"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+	if len(req.Photos) == 0 || len(req.Photos) > maxAlbumPhotosPerRequest {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("photos must contain 1 to %d items", maxAlbumPhotosPerRequest)})
+		return
+	}
+	items, err := h.albumPhotos(c.Request.Context(), req.Photos)
+	if err != nil {
+		var notFound notFoundError
+		if errors.As(err, &notFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.Repo.AddPhotos(c.Request.Context(), id, items); err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": items})
+}
+
+// RemovePhoto 把照片移出相册
+func (h *AlbumHandler) RemovePhoto(c *gin.Context) {
+	id, ok := albumID(c)
+	if !ok {
+		return
+	}
+	photoID, err := strconv.ParseUint(c.Param("photo_id"), 10, 64)
+	if err != nil || photoID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid photo id"})
+		return
+	}
+	if err := h.Repo.RemovePhoto(c.Request.Context(), id, uint(photoID)); err != nil {
+		if errors.Is(err, db.ErrPhotoNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "photo not in album"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Timeline 按天的行程：停留点（逆地理编码地名）与相邻停留点之间的路线
+func (h *AlbumHandler) Timeline(c *gin.Context) {
+	id, ok := albumID(c)
+	if !ok {
+		return
+	}
+	mode := c.DefaultQuery("mode", album.ModeWalking)
+	if !slices.Contains(album.Modes, mode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid mode (want one of " + strings.Join(album.Modes, ", ") + ")"})
+		return
+	}
+	a, err := h.Repo.Get(c.Request.Context(), id)
+	if err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+	entries, err := h.Repo.Entries(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timelineTimeout)
+	defer cancel()
+	timeline := album.Build(ctx, h.Maps, entries, h.Location, mode)
+	for i := range timeline.Days {
+		for j := range timeline.Days[i].Stops {
+			for k := range timeline.Days[i].Stops[j].Photos {
+				h.fillVariants(&timeline.Days[i].Stops[j].Photos[k])
+			}
+		}
+	}
+	for i := range timeline.Undated {
+		h.fillVariants(&timeline.Undated[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"album": a, "timeline": timeline}})
+}
+
+// notFoundError 请求中引用的照片或机位不存在
+type notFoundError struct{ msg string }
+
+func (e notFoundError) Error() string { return e.msg }
+
+// albumPhotos 校验照片与机位存在，并整理关联地点
+func (h *AlbumHandler) albumPhotos(ctx context.Context, reqs []albumPhotoRequest) ([]db.AlbumPhoto, error) {
+	ids := make([]uint, 0, len(reqs))
+	for _, r := range reqs {
+		if r.PhotoID == 0 {
+			return nil, errors.New("photo_id is required")
+		}
+		ids = append(ids, r.PhotoID)
+	}
+	photos, err := h.Photos.GetMany(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[uint]bool, len(photos))
+	for _, p := range photos {
+		found[p.ID] = true
+	}
+
+	items := make([]db.AlbumPhoto, 0, len(reqs))
+	seen := make(map[uint]bool, len(reqs))
+	for _, r := range reqs {
+		if !found[r.PhotoID] {
+			return nil, notFoundError{fmt.Sprintf("photo %d not found", r.PhotoID)}
+		}
+		if seen[r.PhotoID] {
+			continue
+		}
+		seen[r.PhotoID] = true
+
+		item := db.AlbumPhoto{PhotoID: r.PhotoID}
+		placeUID := strings.TrimSpace(r.PlaceUID)
+		switch {
+		case r.SpotID != nil && placeUID != "":
+			return nil, fmt.Errorf("photo %d: spot_id and place_uid are mutually exclusive", r.PhotoID)
+		case r.SpotID != nil:
+			if _, err := h.Spots.Get(ctx, *r.SpotID); errors.Is(err, db.ErrSpotNotFound) {
+				return nil, notFoundError{fmt.Sprintf("spot %d not found", *r.SpotID)}
+			} else if err != nil {
+				return nil, err
+			}
+			item.SpotID = r.SpotID
+		case placeUID != "":
+			item.PlaceUID = placeUID
+			item.PlaceName = strings.TrimSpace(r.PlaceName)
+			if r.BDLat != nil && r.BDLng != nil {
+				bd := geo.Point{Lat: *r.BDLat, Lng: *r.BDLng}
+				if !bd.Valid() {
+					return nil, fmt.Errorf("photo %d: invalid bd_lat/bd_lng", r.PhotoID)
+				}
+				item.PlaceBDLat, item.PlaceBDLng = bd.Lat, bd.Lng
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (h *AlbumHandler) fillVariants(p *db.Photo) {
+	if h.Photo != nil {
+		h.Photo.fillVariants(p)
+	}
+}
+
+func albumID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid album id"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondAlbumError(c *gin.Context, err error) {
+	if errors.Is(err, db.ErrAlbumNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// bindAlbum 解析并校验请求体，失败时已写入 400 响应
+func bindAlbum(c *gin.Context) (*db.Album, bool) {
+	var req albumRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return nil, false
+	}
+	a, err := albumFromRequest(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return a, true
+}
+
+func albumFromRequest(req albumRequest) (*db.Album, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+	start, end := strings.TrimSpace(req.StartDate), strings.TrimSpace(req.EndDate)
+	for field, v := range map[string]string{"start_date": start, "end_date": end} {
+		if v == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", v); err != nil {
+			return nil, fmt.Errorf("invalid %s %q (want YYYY-MM-DD)", field, v)
+		}
+	}
+	if start != "" && end != "" && end < start {
+		return nil, errors.New("end_date is before start_date")
+	}
+	return &db.Album{
+		Name:        name,
+		Description: req.Description,
+		Destination: strings.TrimSpace(req.Destination),
+		StartDate:   start,
+		EndDate:     end,
+	}, nil
+}
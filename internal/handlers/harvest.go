@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangqi/photo-backend/internal/db"
+	"github.com/huangqi/photo-backend/internal/harvest"
+)
+
+type HarvestHandler struct {
+	Harvester *harvest.Harvester
+	Links     *db.LinkStore
+}
+
+func NewHarvestHandler(harvester *harvest.Harvester, links *db.LinkStore) *HarvestHandler {
+	return &HarvestHandler{Harvester: harvester, Links: links}
+}
+
+// Status 管理接口：采集器状态
+func (h *HarvestHandler) Status(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": h.Harvester.Status()})
+}
+
+// Run 管理接口：立即在后台触发一次采集
+func (h *HarvestHandler) Run(c *gin.Context) {
+	if h.Harvester.Status().Running {
+		c.JSON(http.StatusConflict, gin.H{"error": harvest.ErrRunning.Error()})
+		return
+	}
+	// 采集耗时较长，不绑定请求的 context
+	go func() {
+		_, _ = h.Harvester.RunOnce(context.Background())
+	}()
+	c.JSON(http.StatusAccepted, gin.H{"data": gin.H{"status": "started"}})
+}
+
+// ListLinks 管理接口：已采集的链接，可按 category 过滤
+func (h *HarvestHandler) ListLinks(c *gin.Context) {
+	links, err := h.Links.List(c.Request.Context(), c.Query("category"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": links})
+}
+
+// ExportLinksCSV 管理接口：以 xhs_links.csv 格式导出
+func (h *HarvestHandler) ExportLinksCSV(c *gin.Context) {
+	// 先查询再写响应头，查询失败时还能返回 JSON 错误
+	links, err := h.Links.List(c.Request.Context(), c.Query("category"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="xhs_links.csv"`)
+	if err := db.WriteLinksCSV(c.Writer, links); err != nil {
+		// CSV 已开始写出，只能中断响应
+		log.Printf("warn: export xhs links csv: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangqi/photo-backend/internal/catalog"
+	"github.com/huangqi/photo-backend/internal/mcp"
+)
+
+// maxCatalogQueries 单次分类搜索最多执行的搜索短语数量
+const maxCatalogQueries = 10
+
+type InspirationHandler struct {
+	Catalog *catalog.Catalog
+	// XHS 为 nil 时只能浏览分类，不能执行搜索
+	XHS *XHSHandler
+}
+
+func NewInspirationHandler(cat *catalog.Catalog, xhs *XHSHandler) *InspirationHandler {
+	return &InspirationHandler{Catalog: cat, XHS: xhs}
+}
+
+// ListCategories 返回全部分类、搜索短语与置顶链接
+func (h *InspirationHandler) ListCategories(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": h.Catalog.Categories})
+}
+
+// GetCategory 返回单个分类
+func (h *InspirationHandler) GetCategory(c *gin.Context) {
+	category, ok := h.Catalog.Category(c.Param("name"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": category})
+}
+
+// SearchCategory 依次执行分类下的搜索短语，返回合并去重后的帖子
+func (h *InspirationHandler) SearchCategory(c *gin.Context) {
+	if h.XHS == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "xhs module unavailable"})
+		return
+	}
+	category, ok := h.Catalog.Category(c.Param("name"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+		return
+	}
+	h.XHS.ensureInit(c)
+
+	perQuery := parseLimitParam(c, "limit_per_query", 5)
+	maxQueries, err := strconv.Atoi(c.DefaultQuery("max_queries", strconv.Itoa(maxCatalogQueries)))
+	if err != nil || maxQueries <= 0 || maxQueries > maxCatalogQueries {
+		maxQueries = maxCatalogQueries
+	}
+	skipLogin := c.DefaultQuery("skip_login", "false") == "true"
+
+	var lastErr error
+	result := category.Search(c.Request.Context(), func(ctx context.Context, keyword string, limit int) ([]mcp.XHSPost, error) {
+		posts, err := h.XHS.searchPosts(ctx, keyword, limit, skipLogin)
+		if err != nil {
+			lastErr = err
+		}
+		return posts, err
+	}, perQuery, maxQueries)
+
+	if len(result.Posts) == 0 && lastErr != nil {
+		respondXHSError(c, lastErr)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
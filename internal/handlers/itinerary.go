@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangqi/photo-backend/internal/album"
+	"github.com/huangqi/photo-backend/internal/db"
+	"github.com/huangqi/photo-backend/internal/geo"
+	"github.com/huangqi/photo-backend/internal/itinerary"
+)
+
+const (
+	// itineraryTimeout 解析地点与规划路线的总超时
+	itineraryTimeout = 45 * time.Second
+	maxStayMinutes   = 8 * 60
+)
+
+// itineraryPlaceRequest 行程中的地点，按 spot_id、bd_lat/bd_lng、uid（需同时给出 name）、address、name 的顺序定位
+type itineraryPlaceRequest struct {
+	SpotID      *uint    `json:"spot_id"`
+	UID         string   `json:"uid"`
+	Name        string   `json:"name"`
+	Address     string   `json:"address"`
+	Region      string   `json:"region"`
+	BDLat       *float64 `json:"bd_lat"`
+	BDLng       *float64 `json:"bd_lng"`
+	Light       []string `json:"light"`
+	StayMinutes int      `json:"stay_minutes"`
+}
+
+// itineraryRequest 行程规划请求体。date 为 YYYY-MM-DD，start_time 为当天的 HH:MM
+type itineraryRequest struct {
+	Date        string                  `json:"date"`
+	TZ          string                  `json:"tz"`
+	Mode        string                  `json:"mode"`
+	Start       *itineraryPlaceRequest  `json:"start"`
+	StartTime   string                  `json:"start_time"`
+	StayMinutes int                     `json:"stay_minutes"`
+	Places      []itineraryPlaceRequest `json:"places"`
+}
+
+// Itinerary 规划一天的拍摄行程：解析地点坐标，规划两两之间的路线，按各地点的最佳光线时段排出顺序与时间
+func (h *TravelHandler) Itinerary(c *gin.Context) {
+	var req itineraryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+	if len(req.Places) == 0 || len(req.Places) > itinerary.MaxPlaces {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("places must contain 1 to %d items", itinerary.MaxPlaces)})
+		return
+	}
+	mode := req.Mode
+	if mode == "" {
+		mode = album.ModeWalking
+	}
+	if !slices.Contains(album.Modes, mode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid mode (want one of " + strings.Join(album.Modes, ", ") + ")"})
+		return
+	}
+	loc := h.Location
+	if req.TZ != "" {
+		l, err := time.LoadLocation(req.TZ)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tz: " + req.TZ})
+			return
+		}
+		loc = l
+	}
+	date, err := time.ParseInLocation("2006-01-02", req.Date, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date must be YYYY-MM-DD"})
+		return
+	}
+	if req.StayMinutes < 0 || req.StayMinutes > maxStayMinutes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("stay_minutes must be between 0 and %d", maxStayMinutes)})
+		return
+	}
+	plan := itinerary.Request{Date: date, Mode: mode, Stay: time.Duration(req.StayMinutes) * time.Minute}
+	if req.StartTime != "" {
+		t, err := time.ParseInLocation("2006-01-02 15:04", req.Date+" "+req.StartTime, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "start_time must be HH:MM"})
+			return
+		}
+		plan.StartAt = &t
+	}
+
+	ctx := c.Request.Context()
+	for i, r := range req.Places {
+		p, err := h.itineraryPlace(ctx, r)
+		if err != nil {
+			writeItineraryPlaceError(c, fmt.Sprintf("places[%d]", i), err)
+			return
+		}
+		plan.Places = append(plan.Places, p)
+	}
+	if req.Start != nil {
+		p, err := h.itineraryPlace(ctx, *req.Start)
+		if err != nil {
+			writeItineraryPlaceError(c, "start", err)
+			return
+		}
+		plan.Start = &p
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, itineraryTimeout)
+	defer cancel()
+	result, err := itinerary.Build(ctx, h.BaiduMaps, plan)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "unresolved": result.Unresolved})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// itineraryPlace 校验请求中的地点；给出 spot_id 时取机位的坐标与最佳时段
+func (h *TravelHandler) itineraryPlace(ctx context.Context, r itineraryPlaceRequest) (itinerary.Place, error) {
+	light, err := oneOf("light", r.Light, spotTimesOfDay)
+	if err != nil {
+		return itinerary.Place{}, err
+	}
+	if r.StayMinutes < 0 || r.StayMinutes > maxStayMinutes {
+		return itinerary.Place{}, fmt.Errorf("stay_minutes must be between 0 and %d", maxStayMinutes)
+	}
+	p := itinerary.Place{
+		UID:     strings.TrimSpace(r.UID),
+		Name:    strings.TrimSpace(r.Name),
+		Address: strings.TrimSpace(r.Address),
+		Region:  strings.TrimSpace(r.Region),
+		Light:   light,
+		Stay:    time.Duration(r.StayMinutes) * time.Minute,
+	}
+	if (r.BDLat == nil) != (r.BDLng == nil) {
+		return itinerary.Place{}, errors.New("bd_lat and bd_lng must be given together")
+	}
+	if r.BDLat != nil {
+		bd := geo.Point{Lat: *r.BDLat, Lng: *r.BDLng}
+		if !bd.Valid() {
+			return itinerary.Place{}, errors.New("invalid bd_lat/bd_lng")
+		}
+		p.BD09 = &bd
+	}
+	if r.SpotID == nil {
+		return p, nil
+	}
+
+	if h.Spots == nil {
+		return itinerary.Place{}, errors.New("spots module unavailable")
+	}
+	spot, err := h.Spots.Get(ctx, *r.SpotID)
+	if errors.Is(err, db.ErrSpotNotFound) {
+		return itinerary.Place{}, notFoundError{fmt.Sprintf("spot %d not found", *r.SpotID)}
+	}
+	if err != nil {
+		return itinerary.Place{}, err
+	}
+	p.SpotID = &spot.ID
+	p.Name = firstNonEmpty(p.Name, spot.Name)
+	p.Address = firstNonEmpty(p.Address, spot.Address)
+	p.UID = firstNonEmpty(p.UID, spot.BaiduUID)
+	p.BD09 = &geo.Point{Lat: spot.BDLat, Lng: spot.BDLng}
+	if len(p.Light) == 0 {
+		p.Light = spot.BestTimeOfDay
+	}
+	return p, nil
+}
+
+func writeItineraryPlaceError(c *gin.Context, field string, err error) {
+	var notFound notFoundError
+	if errors.As(err, &notFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": field + ": " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": field + ": " + err.Error()})
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangqi/photo-backend/internal/conditions"
+	"github.com/huangqi/photo-backend/internal/db"
+	"github.com/huangqi/photo-backend/internal/geo"
+	"github.com/huangqi/photo-backend/internal/mcp"
+	"github.com/huangqi/photo-backend/internal/solar"
+)
+
+const (
+	defaultTrackInterval = 30
+	minTrackInterval     = 5
+	maxTrackInterval     = 180
+
+	defaultConditionHours = 48
+	maxConditionHours     = 72
+	defaultConditionLimit = 10
+	conditionsTimeout     = 15 * time.Second
+)
+
+// LightHandler 拍摄光线：日出日落、黄金时刻、蓝调时刻与太阳轨迹
+type LightHandler struct {
+	// Location 未指定 tz 时使用的时区，也是百度天气预报时间所在的时区
+	Location *time.Location
+	// Maps 为 nil 时拍摄条件评分与按地点名称查询太阳方位不可用
+	Maps mcp.BaiduMapsClient
+	// Spots 为 nil 时不能按机位查询太阳方位
+	Spots db.SpotRepository
+}
+
+func NewLightHandler(loc *time.Location, maps mcp.BaiduMapsClient) *LightHandler {
+	if loc == nil {
+		loc = time.Local
+	}
+	return &LightHandler{Location: loc, Maps: maps}
+}
+
+// lightResponse 日照信息及当天太阳高度不低于 -6° 时段的轨迹
+type lightResponse struct {
+	*solar.Light
+	Timezone string        `json:"timezone"`
+	Track    []solar.Event `json:"track"`
+}
+
+// Get 某地某天的日照信息，lat/lng 为 WGS-84，date 缺省为当地今天
+func (h *LightHandler) Get(c *gin.Context) {
+	point, ok := parsePoint(c)
+	if !ok {
+		return
+	}
+	date, ok := parseLightDate(c, h.Location)
+	if !ok {
+		return
+	}
+	interval, err := strconv.Atoi(c.DefaultQuery("interval", strconv.Itoa(defaultTrackInterval)))
+	if err != nil || interval < minTrackInterval || interval > maxTrackInterval {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "interval must be an integer between " + strconv.Itoa(minTrackInterval) + " and " + strconv.Itoa(maxTrackInterval)})
+		return
+	}
+
+	resp := lightResponse{
+		Light:    solar.Compute(date, point.Lat, point.Lng),
+		Timezone: date.Location().String(),
+		Track:    []solar.Event{},
+	}
+	for _, e := range solar.Track(date, date.AddDate(0, 0, 1), time.Duration(interval)*time.Minute, point.Lat, point.Lng) {
+		if e.Elevation >= solar.AltitudeBlueLow && e.Time.Before(date.AddDate(0, 0, 1)) {
+			resp.Track = append(resp.Track, e)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"data": resp})
+}
+
+// Conditions 结合百度天气逐小时预报与黄金/蓝调时刻，为接下来的拍摄时段打分，按得分从高到低返回
+func (h *LightHandler) Conditions(c *gin.Context) {
+	point, ok := parsePoint(c)
+	if !ok {
+		return
+	}
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", strconv.Itoa(defaultConditionHours)))
+	if err != nil || hours <= 0 || hours > maxConditionHours {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "hours must be an integer between 1 and " + strconv.Itoa(maxConditionHours)})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultConditionLimit)))
+	if err != nil || limit <= 0 {
+		limit = defaultConditionLimit
+	}
+	limit = min(limit, maxConditionHours)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), conditionsTimeout)
+	defer cancel()
+	isChina := "true"
+	if geo.OutOfChina(point) {
+		isChina = "false"
+	}
+	bd := geo.WGS84ToBD09(point)
+	weather, err := h.Maps.GetWeather(ctx, strconv.FormatFloat(bd.Lng, 'f', 6, 64)+","+strconv.FormatFloat(bd.Lat, 'f', 6, 64), "", isChina)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	slots := conditions.Rank(weather, point.Lat, point.Lng, h.Location, time.Now(), time.Duration(hours)*time.Hour)
+	total := len(slots)
+	if len(slots) > limit {
+		slots = slots[:limit]
+	}
+	c.JSON(http.StatusOK, gin.H{"data": slots, "total": total, "location": weather.Result.Location})
+}
+
+// parsePoint 读取必填的 lat/lng 参数；非法时已写入 400 响应
+func parsePoint(c *gin.Context) (geo.Point, bool) {
+	if c.Query("lat") == "" || c.Query("lng") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "lat and lng are required"})
+		return geo.Point{}, false
+	}
+	lat, errLat := strconv.ParseFloat(c.Query("lat"), 64)
+	lng, errLng := strconv.ParseFloat(c.Query("lng"), 64)
+	p := geo.Point{Lat: lat, Lng: lng}
+	if errLat != nil || errLng != nil || !p.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid lat/lng"})
+		return geo.Point{}, false
+	}
+	return p, true
+}
+
+// parseLightDate 读取 date（YYYY-MM-DD）与 tz（IANA 时区名）参数，返回该日零点；
+// date 缺省为 tz 下的今天，tz 缺省为 def。非法时已写入 400 响应
+func parseLightDate(c *gin.Context, def *time.Location) (time.Time, bool) {
+	loc := def
+	if name := c.Query("tz"); name != "" {
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tz: " + name})
+			return time.Time{}, false
+		}
+		loc = l
+	}
+	raw := c.Query("date")
+	if raw == "" {
+		y, m, d := time.Now().In(loc).Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, loc), true
+	}
+	date, err := time.ParseInLocation("2006-01-02", raw, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date must be YYYY-MM-DD"})
+		return time.Time{}, false
+	}
+	return date, true
+}
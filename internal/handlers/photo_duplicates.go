@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangqi/photo-backend/internal/db"
+	"github.com/huangqi/photo-backend/internal/photo"
+)
+
+const (
+	defaultDuplicateLimit = 20
+	maxDuplicateLimit     = 100
+	// maxDuplicateThreshold 超过 64 位哈希的一半后结果已没有意义
+	maxDuplicateThreshold = 32
+)
+
+// similarPhoto 近似重复的照片及两种哈希的汉明距离
+type similarPhoto struct {
+	Photo         db.Photo `json:"photo"`
+	DHashDistance int      `json:"dhash_distance"`
+	PHashDistance int      `json:"phash_distance"`
+}
+
+// photoGroup 一组互相近似的照片（连拍或重复上传），按拍摄时间排序
+type photoGroup struct {
+	Photos []db.Photo `json:"photos"`
+}
+
+// CheckDuplicates 上传前查重：计算上传图片（multipart 字段 file）的感知哈希并查找近似照片，不保存
+func (h *PhotoHandler) CheckDuplicates(c *gin.Context) {
+	threshold, ok := h.threshold(c)
+	if !ok {
+		return
+	}
+	img, ok := h.readUpload(c)
+	if !ok {
+		return
+	}
+	defer img.Close()
+	orientation := 0
+	if meta, err := photo.ParseEXIF(img.reader(), h.Location); err == nil {
+		orientation = meta.Orientation
+	}
+	hashes, err := h.hashImage(c.Request.Context(), img.reader(), orientation)
+	if err != nil {
+		status := http.StatusUnprocessableEntity
+		if c.Request.Context().Err() != nil {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	matches, err := h.findSimilar(c.Request.Context(), hashes, threshold, 0, duplicateLimit(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": matches, "dhash": photo.FormatHash(hashes.DHash), "phash": photo.FormatHash(hashes.PHash), "threshold": threshold})
+}
+
+// Duplicates 与已有照片近似重复的其他照片，按距离从近到远
+func (h *PhotoHandler) Duplicates(c *gin.Context) {
+	threshold, ok := h.threshold(c)
+	if !ok {
+		return
+	}
+	p, ok := h.photo(c)
+	if !ok {
+		return
+	}
+	hashes, err := photo.ParseHashes(p.DHash, p.PHash)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "photo has no perceptual hash"})
+		return
+	}
+	matches, err := h.findSimilar(c.Request.Context(), hashes, threshold, p.ID, duplicateLimit(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": matches, "threshold": threshold})
+}
+
+// Groups 把所有照片按感知哈希分组，返回成员不少于 2 张的组，用于合并连拍和重复上传
+func (h *PhotoHandler) Groups(c *gin.Context) {
+	threshold, ok := h.threshold(c)
+	if !ok {
+		return
+	}
+	limit := duplicateLimit(c)
+	index, err := h.hashes.load(c.Request.Context(), h.Repo)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	groups := index.Groups(threshold)
+	total := len(groups)
+	if len(groups) > limit {
+		groups = groups[:limit]
+	}
+
+	var wanted []uint
+	for _, g := range groups {
+		wanted = append(wanted, g...)
+	}
+	photos, err := h.Repo.GetMany(c.Request.Context(), wanted)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	byID := make(map[uint]db.Photo, len(photos))
+	for _, p := range photos {
+		byID[p.ID] = p
+	}
+
+	out := make([]photoGroup, 0, len(groups))
+	for _, g := range groups {
+		var group photoGroup
+		for _, id := range g {
+			if p, ok := byID[id]; ok {
+				h.fillVariants(&p)
+				group.Photos = append(group.Photos, p)
+			}
+		}
+		sort.SliceStable(group.Photos, func(i, j int) bool { return takenBefore(group.Photos[i], group.Photos[j]) })
+		if len(group.Photos) > 1 {
+			out = append(out, group)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"data": out, "total": total, "threshold": threshold})
+}
+
+const (
+	defaultRehashLimit = 50
+	maxRehashLimit     = 500
+)
+
+// rehashResult 一批回填的结果；done 为 false 时以 next_after_id 作为 after_id 继续
+type rehashResult struct {
+	Hashed      int           `json:"hashed"`
+	Failed      []rehashError `json:"failed,omitempty"`
+	NextAfterID uint          `json:"next_after_id"`
+	Done        bool          `json:"done"`
+}
+
+type rehashError struct {
+	ID    uint   `json:"id"`
+	Error string `json:"error"`
+}
+
+// Rehash 管理接口：为没有感知哈希的照片（如哈希功能上线前上传的）补算哈希。
+// 每次处理 ID 大于 after_id 的至多 limit 张
+func (h *PhotoHandler) Rehash(c *gin.Context) {
+	afterID, err := strconv.ParseUint(c.DefaultQuery("after_id", "0"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid after_id"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultRehashLimit)))
+	if err != nil || limit <= 0 {
+		limit = defaultRehashLimit
+	}
+	limit = min(limit, maxRehashLimit)
+
+	ctx := c.Request.Context()
+	photos, err := h.Repo.ListUnhashed(ctx, uint(afterID), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	result := rehashResult{NextAfterID: uint(afterID), Done: len(photos) < limit}
+	for _, p := range photos {
+		result.NextAfterID = p.ID
+		hashes, err := h.hashStored(ctx, &p)
+		if err == nil {
+			err = h.Repo.SetHashes(ctx, p.ID, photo.FormatHash(hashes.DHash), photo.FormatHash(hashes.PHash))
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": ctx.Err().Error()})
+				return
+			}
+			log.Printf("photo: rehash %d: %v", p.ID, err)
+			result.Failed = append(result.Failed, rehashError{ID: p.ID, Error: err.Error()})
+			continue
+		}
+		h.hashes.add(p.ID, hashes)
+		result.Hashed++
+	}
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// hashStored 读取存储中的原图计算感知哈希，像素数超过 MaxPixels 的不解码
+func (h *PhotoHandler) hashStored(ctx context.Context, p *db.Photo) (photo.Hash, error) {
+	if h.MaxPixels > 0 && int64(p.Width)*int64(p.Height) > h.MaxPixels {
+		return photo.Hash{}, fmt.Errorf("photo is %dx%d, exceeds %d pixels", p.Width, p.Height, h.MaxPixels)
+	}
+	rc, _, err := h.Store.Get(ctx, p.StorageKey)
+	if err != nil {
+		return photo.Hash{}, err
+	}
+	defer rc.Close()
+	return h.hashImage(ctx, rc, p.Orientation)
+}
+
+// hashImage 在解码并发上限内计算感知哈希，排队期间请求结束返回 ctx.Err()
+func (h *PhotoHandler) hashImage(ctx context.Context, r io.Reader, orientation int) (photo.Hash, error) {
+	var hashes photo.Hash
+	err := h.Decodes.Do(ctx, func() error {
+		var err error
+		hashes, err = photo.HashImage(r, orientation)
+		return err
+	})
+	return hashes, err
+}
+
+// findSimilar 查找与 target 两种哈希距离都不超过 threshold 的照片，exclude 为要排除的照片 ID
+func (h *PhotoHandler) findSimilar(ctx context.Context, target photo.Hash, threshold int, exclude uint, limit int) ([]similarPhoto, error) {
+	index, err := h.hashes.load(ctx, h.Repo)
+	if err != nil {
+		return nil, err
+	}
+	var matches []similarPhoto
+	for _, m := range index.Search(target, threshold) {
+		if m.ID != exclude {
+			matches = append(matches, similarPhoto{Photo: db.Photo{ID: m.ID}, DHashDistance: m.DHashDistance, PHashDistance: m.PHashDistance})
+		}
+	}
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	wanted := make([]uint, len(matches))
+	for i, m := range matches {
+		wanted[i] = m.Photo.ID
+	}
+	photos, err := h.Repo.GetMany(ctx, wanted)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]db.Photo, len(photos))
+	for _, p := range photos {
+		byID[p.ID] = p
+	}
+	out := matches[:0]
+	for _, m := range matches {
+		p, ok := byID[m.Photo.ID]
+		if !ok {
+			continue
+		}
+		h.fillVariants(&p)
+		m.Photo = p
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// photoHashes 照片感知哈希的内存索引。首次查询时从数据库加载，之后每次查询前只加载 ID 更大的照片；
+// 本实例上传或回填的照片直接加入索引。其他实例为旧照片回填的哈希 ID 不大于 lastID，
+// 增量加载读不到，数据库中的哈希数与索引不一致时整体重新加载
+type photoHashes struct {
+	mu     sync.Mutex
+	index  *photo.HashIndex
+	lastID uint
+	// invalid 数据库中无法解析的哈希数，不进入索引
+	invalid int
+}
+
+func (x *photoHashes) load(ctx context.Context, repo db.PhotoRepository) (*photo.HashIndex, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.index == nil {
+		x.index = photo.NewHashIndex()
+	}
+	if err := x.readAfter(ctx, repo, x.lastID); err != nil {
+		return nil, err
+	}
+	count, err := repo.CountHashes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if count != int64(x.index.Len()+x.invalid) {
+		x.index, x.lastID, x.invalid = photo.NewHashIndex(), 0, 0
+		if err := x.readAfter(ctx, repo, 0); err != nil {
+			x.index = nil
+			return nil, err
+		}
+	}
+	return x.index, nil
+}
+
+func (x *photoHashes) readAfter(ctx context.Context, repo db.PhotoRepository, afterID uint) error {
+	rows, err := repo.ListHashes(ctx, afterID)
+	if err != nil {
+		return err
+	}
+	for _, r := range rows {
+		x.lastID = max(x.lastID, r.ID)
+		hash, err := photo.ParseHashes(r.DHash, r.PHash)
+		if err != nil {
+			log.Printf("photo: %d: %v", r.ID, err)
+			x.invalid++
+			continue
+		}
+		x.index.Add(r.ID, hash)
+	}
+	return nil
+}
+
+// add 索引尚未加载时忽略，加载时会从数据库读到
+func (x *photoHashes) add(id uint, hash photo.Hash) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.index != nil {
+		x.index.Add(id, hash)
+	}
+}
+
+// threshold 读取 threshold 参数，缺省为 DupThreshold；非法时已写入 400 响应
+func (h *PhotoHandler) threshold(c *gin.Context) (int, bool) {
+	raw := c.Query("threshold")
+	if raw == "" {
+		return min(max(h.DupThreshold, 0), maxDuplicateThreshold), true
+	}
+	t, err := strconv.Atoi(raw)
+	if err != nil || t < 0 || t > maxDuplicateThreshold {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "threshold must be an integer between 0 and " + strconv.Itoa(maxDuplicateThreshold)})
+		return 0, false
+	}
+	return t, true
+}
+
+func duplicateLimit(c *gin.Context) int {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultDuplicateLimit)))
+	if err != nil || limit <= 0 {
+		limit = defaultDuplicateLimit
+	}
+	return min(limit, maxDuplicateLimit)
+}
+
+// takenBefore 按拍摄时间排序，没有拍摄时间的排在后面，再按 ID
+func takenBefore(a, b db.Photo) bool {
+	switch {
+	case a.TakenAt != nil && b.TakenAt != nil && !a.TakenAt.Equal(*b.TakenAt):
+		return a.TakenAt.Before(*b.TakenAt)
+	case a.TakenAt != nil && b.TakenAt == nil:
+		return true
+	case a.TakenAt == nil && b.TakenAt != nil:
+		return false
+	}
+	return a.ID < b.ID
+}
@@ -0,0 +1,410 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangqi/photo-backend/internal/db"
+	"github.com/huangqi/photo-backend/internal/geo"
+	"github.com/huangqi/photo-backend/internal/mcp"
+	"github.com/huangqi/photo-backend/internal/photo"
+	"github.com/huangqi/photo-backend/internal/storage"
+	_ "golang.org/x/image/webp"
+)
+
+// locateTimeout 上传时逆地理编码与 POI 检索的总超时
+const locateTimeout = 15 * time.Second
+
+// photoExtensions 支持的图片类型及保存时的扩展名
+var photoExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+}
+
+type PhotoHandler struct {
+	Repo db.PhotoRepository
+	// Maps 为 nil 时不做逆地理编码
+	Maps     mcp.BaiduMapsClient
+	Store    storage.BlobStore
+	MaxBytes int64
+	// MaxPixels 图片像素数（宽×高）上限，0 表示不限制；解码与生成派生图的内存与之成正比
+	MaxPixels int64
+	// Location EXIF 拍摄时间没有时区时使用
+	Location *time.Location
+	// Variants 为 nil 时不生成派生图
+	Variants *photo.Pipeline
+	// Decodes 限制计算感知哈希时同时解码的图片数，应与 Variants 共用；为 nil 时不限制
+	Decodes *photo.DecodeLimiter
+	// StripGPS 下载原图时清除 JPEG 中的 GPS
+	StripGPS bool
+	// DupThreshold 近似重复查找的默认汉明距离
+	DupThreshold int
+
+	hashes photoHashes
+}
+
+func NewPhotoHandler(repo db.PhotoRepository, maps mcp.BaiduMapsClient, store storage.BlobStore, maxBytes int64, loc *time.Location) *PhotoHandler {
+	return &PhotoHandler{Repo: repo, Maps: maps, Store: store, MaxBytes: maxBytes, Location: loc}
+}
+
+// uploadResponse 上传结果：照片记录、完整 EXIF 与拍摄地点
+type uploadResponse struct {
+	Photo    *db.Photo       `json:"photo"`
+	EXIF     *photo.EXIF     `json:"exif,omitempty"`
+	Location *photo.Location `json:"location,omitempty"`
+	// GeocodeError 逆地理编码失败或未启用的原因，照片仍会保存
+	GeocodeError string `json:"geocode_error,omitempty"`
+	// Duplicates 上传前已存在的近似重复照片
+	Duplicates []similarPhoto `json:"duplicates,omitempty"`
+}
+
+// uploadedImage 校验过的上传图片。内容留在 multipart 文件中（较大的文件已落盘），
+// 按需通过 reader 重新读取，不整体读入内存
+type uploadedImage struct {
+	file        multipart.File
+	size        int64
+	sum         [sha256.Size]byte
+	contentType string
+	ext         string
+	config      image.Config
+	filename    string
+}
+
+// reader 从头读取上传内容，每次返回独立的 reader
+func (u *uploadedImage) reader() *io.SectionReader {
+	return io.NewSectionReader(u.file, 0, u.size)
+}
+
+func (u *uploadedImage) Close() error {
+	return u.file.Close()
+}
+
+// readUpload 打开 multipart 字段 file，校验大小与图片类型并计算 SHA-256；失败时已写入响应。
+// 成功时由调用方关闭返回的 uploadedImage
+func (h *PhotoHandler) readUpload(c *gin.Context) (*uploadedImage, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.MaxBytes+1<<20)
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "photo exceeds " + strconv.FormatInt(h.MaxBytes, 10) + " bytes"})
+			return nil, false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "multipart field file is required"})
+		return nil, false
+	}
+	if header.Size > h.MaxBytes {
+		file.Close()
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "photo exceeds " + strconv.FormatInt(h.MaxBytes, 10) + " bytes"})
+		return nil, false
+	}
+	img := &uploadedImage{file: file, size: header.Size, filename: filepath.Base(header.Filename)}
+	if ok := h.checkUpload(c, img); !ok {
+		file.Close()
+		return nil, false
+	}
+	return img, true
+}
+
+// checkUpload 流式计算 SHA-256，按文件头判断类型并读取图片尺寸
+func (h *PhotoHandler) checkUpload(c *gin.Context, img *uploadedImage) bool {
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, img.reader()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read upload: " + err.Error()})
+		return false
+	}
+	copy(img.sum[:], hasher.Sum(nil))
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(img.reader(), head)
+	img.contentType = http.DetectContentType(head[:n])
+	ext, ok := photoExtensions[img.contentType]
+	if !ok {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "unsupported image type " + img.contentType})
+		return false
+	}
+	img.ext = ext
+	cfg, _, err := image.DecodeConfig(img.reader())
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid image: " + err.Error()})
+		return false
+	}
+	if h.MaxPixels > 0 && int64(cfg.Width)*int64(cfg.Height) > h.MaxPixels {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("photo is %dx%d, exceeds %d pixels", cfg.Width, cfg.Height, h.MaxPixels)})
+		return false
+	}
+	img.config = cfg
+	return true
+}
+
+// Upload 上传照片（multipart 字段 file），解析 EXIF 并对 GPS 坐标逆地理编码
+func (h *PhotoHandler) Upload(c *gin.Context) {
+	img, ok := h.readUpload(c)
+	if !ok {
+		return
+	}
+	defer img.Close()
+
+	hash := hex.EncodeToString(img.sum[:])
+	p := &db.Photo{
+		StorageKey:   storage.ContentKey("originals", img.sum, img.ext),
+		SHA256:       hash,
+		OriginalName: img.filename,
+		ContentType:  img.contentType,
+		Size:         img.size,
+		Width:        img.config.Width,
+		Height:       img.config.Height,
+	}
+	resp := uploadResponse{Photo: p}
+
+	meta, err := photo.ParseEXIF(img.reader(), h.Location)
+	if err != nil && !errors.Is(err, photo.ErrNoEXIF) {
+		log.Printf("photo: exif of %s: %v", hash, err)
+	}
+	if meta != nil {
+		resp.EXIF = meta
+		applyEXIF(p, meta)
+		if meta.HasGPS {
+			resp.Location, resp.GeocodeError = h.locate(c.Request.Context(), geo.Point{Lat: meta.Lat, Lng: meta.Lng})
+			applyLocation(p, meta, resp.Location)
+		}
+	}
+
+	if hashes, err := h.hashImage(c.Request.Context(), img.reader(), p.Orientation); err != nil {
+		log.Printf("photo: perceptual hash of %s: %v", hash, err)
+	} else {
+		p.DHash, p.PHash = photo.FormatHash(hashes.DHash), photo.FormatHash(hashes.PHash)
+		// 先查重再入库，结果里不会包含这张照片本身
+		resp.Duplicates, err = h.findSimilar(c.Request.Context(), hashes, h.DupThreshold, 0, defaultDuplicateLimit)
+		if err != nil {
+			log.Printf("photo: duplicate lookup of %s: %v", hash, err)
+		}
+	}
+
+	if err := h.save(c.Request.Context(), p.StorageKey, img); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store photo: " + err.Error()})
+		return
+	}
+	if err := h.Repo.Create(c.Request.Context(), p); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if hashes, err := photo.ParseHashes(p.DHash, p.PHash); err == nil {
+		h.hashes.add(p.ID, hashes)
+	}
+	if h.Variants != nil {
+		if err := h.Variants.Enqueue(photoSource(p)); err != nil {
+			log.Printf("photo: %d: %v; variants will be rendered on first request", p.ID, err)
+		}
+	}
+	h.fillVariants(p)
+	c.JSON(http.StatusCreated, gin.H{"data": resp})
+}
+
+// Get 照片元数据
+func (h *PhotoHandler) Get(c *gin.Context) {
+	p, ok := h.photo(c)
+	if !ok {
+		return
+	}
+	h.fillVariants(p)
+	c.Header("Cache-Control", "no-cache")
+	c.JSON(http.StatusOK, gin.H{"data": p})
+}
+
+// File 返回原图；StripGPS 时 JPEG 的 GPS 信息被清除
+func (h *PhotoHandler) File(c *gin.Context) {
+	p, ok := h.photo(c)
+	if !ok {
+		return
+	}
+	if !h.StripGPS || p.ContentType != "image/jpeg" || !p.HasGPS {
+		h.serveBlob(c, p.StorageKey, p.ContentType, photo.ImmutableCacheControl)
+		return
+	}
+
+	rc, info, err := h.Store.Get(c.Request.Context(), p.StorageKey)
+	if err != nil {
+		respondBlobError(c, err)
+		return
+	}
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	data, _ = photo.StripJPEGGPS(data)
+	c.Header("Content-Type", p.ContentType)
+	c.Header("Cache-Control", photo.ImmutableCacheControl)
+	if info.ETag != "" {
+		c.Header("ETag", strings.TrimSuffix(info.ETag, `"`)+`-nogps"`)
+	}
+	http.ServeContent(c.Writer, c.Request, "", info.ModTime, bytes.NewReader(data))
+}
+
+// Variant 返回派生图，尚未生成时同步生成
+func (h *PhotoHandler) Variant(c *gin.Context) {
+	if h.Variants == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "photo variants are disabled"})
+		return
+	}
+	p, ok := h.photo(c)
+	if !ok {
+		return
+	}
+	spec, err := h.Variants.Ensure(c.Request.Context(), photoSource(p), c.Param("variant"))
+	if errors.Is(err, photo.ErrUnknownVariant) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// 地址中的版本与当前规格一致时内容不会再变；缺失或过期时（规格已变化）只允许协商缓存
+	cacheControl := "no-cache"
+	if c.Query("v") == spec.Version() {
+		cacheControl = photo.ImmutableCacheControl
+	}
+	h.serveBlob(c, spec.Key(p.SHA256), spec.ContentType(), cacheControl)
+}
+
+func photoSource(p *db.Photo) photo.Source {
+	return photo.Source{Key: p.StorageKey, SHA256: p.SHA256, Orientation: p.Orientation}
+}
+
+func (h *PhotoHandler) fillVariants(p *db.Photo) {
+	if h.Variants == nil {
+		return
+	}
+	p.Variants = make(map[string]string, len(h.Variants.Specs()))
+	for _, spec := range h.Variants.Specs() {
+		p.Variants[spec.Name] = fmt.Sprintf("/api/photos/%d/variants/%s?v=%s", p.ID, spec.Name, spec.Version())
+	}
+}
+
+func respondBlobError(c *gin.Context, err error) {
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "photo file not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// serveBlob 返回存储中的对象，带 ETag；支持 Range 与条件请求（存储返回可 Seek 的对象时）
+func (h *PhotoHandler) serveBlob(c *gin.Context, key, contentType, cacheControl string) {
+	rc, info, err := h.Store.Get(c.Request.Context(), key)
+	if err != nil {
+		respondBlobError(c, err)
+		return
+	}
+	defer rc.Close()
+
+	if contentType == "" {
+		contentType = info.ContentType
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Cache-Control", cacheControl)
+	if info.ETag != "" {
+		c.Header("ETag", info.ETag)
+	}
+	if rs, ok := rc.(io.ReadSeeker); ok {
+		http.ServeContent(c.Writer, c.Request, "", info.ModTime, rs)
+		return
+	}
+	c.DataFromReader(http.StatusOK, info.Size, contentType, rc, nil)
+}
+
+func (h *PhotoHandler) photo(c *gin.Context) (*db.Photo, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid photo id"})
+		return nil, false
+	}
+	p, err := h.Repo.Get(c.Request.Context(), uint(id))
+	if errors.Is(err, db.ErrPhotoNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return p, true
+}
+
+// locate 逆地理编码，失败时返回原因而不是中断上传
+func (h *PhotoHandler) locate(ctx context.Context, wgs geo.Point) (*photo.Location, string) {
+	if h.Maps == nil {
+		return nil, "baidu maps not configured"
+	}
+	ctx, cancel := context.WithTimeout(ctx, locateTimeout)
+	defer cancel()
+	loc, err := photo.Locate(ctx, h.Maps, wgs)
+	if err != nil {
+		return nil, err.Error()
+	}
+	return loc, ""
+}
+
+// save 内容寻址，同一内容已存在时不重复写入
+func (h *PhotoHandler) save(ctx context.Context, key string, img *uploadedImage) error {
+	if _, err := h.Store.Stat(ctx, key); err == nil {
+		return nil
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+	_, err := h.Store.Put(ctx, key, img.reader(), storage.PutOptions{ContentType: img.contentType, Size: img.size})
+	return err
+}
+
+func applyEXIF(p *db.Photo, meta *photo.EXIF) {
+	p.TakenAt = meta.TakenAt
+	p.CameraMake = meta.CameraMake
+	p.CameraModel = meta.CameraModel
+	p.LensModel = meta.LensModel
+	p.FocalLength = meta.FocalLength
+	p.FocalLength35mm = meta.FocalLength35mm
+	p.ExposureTime = meta.ExposureTime
+	p.FNumber = meta.FNumber
+	p.ISO = meta.ISO
+	p.Orientation = meta.Orientation
+}
+
+func applyLocation(p *db.Photo, meta *photo.EXIF, loc *photo.Location) {
+	p.HasGPS = true
+	p.Lat, p.Lng, p.Altitude = meta.Lat, meta.Lng, meta.Altitude
+	bd := geo.WGS84ToBD09(geo.Point{Lat: meta.Lat, Lng: meta.Lng})
+	p.BDLat, p.BDLng = bd.Lat, bd.Lng
+	if loc == nil {
+		return
+	}
+	p.Address = loc.FormattedAddress
+	p.Province = loc.Province
+	p.City = loc.City
+	p.District = loc.District
+	if len(loc.POIs) > 0 {
+		p.POIName = loc.POIs[0].Name
+		p.POIUID = loc.POIs[0].UID
+	}
+}
@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangqi/photo-backend/internal/catalog"
+	"github.com/huangqi/photo-backend/internal/db"
+	"github.com/huangqi/photo-backend/internal/geo"
+	"github.com/huangqi/photo-backend/internal/mcp"
+)
+
+const (
+	defaultSpotLimit = 20
+	maxSpotLimit     = 100
+)
+
+// 机位的最佳季节与时段取值
+var (
+	spotSeasons    = []string{"spring", "summer", "autumn", "winter"}
+	spotTimesOfDay = []string{"sunrise", "morning", "noon", "afternoon", "golden_hour", "sunset", "blue_hour", "night"}
+)
+
+type SpotHandler struct {
+	Repo db.SpotRepository
+	// Catalog 非 nil 时校验 pose_categories 是否为已知分类
+	Catalog *catalog.Catalog
+}
+
+func NewSpotHandler(repo db.SpotRepository, cat *catalog.Catalog) *SpotHandler {
+	return &SpotHandler{Repo: repo, Catalog: cat}
+}
+
+// spotNoteRequest 关联笔记，note_id 与 url 至少给出一个
+type spotNoteRequest struct {
+	NoteID string `json:"note_id"`
+	URL    string `json:"url"`
+	Title  string `json:"title"`
+}
+
+// spotRequest 创建与更新机位的请求体。坐标给出 WGS-84（lat/lng）或 BD-09（bd_lat/bd_lng）之一即可，另一组自动换算
+type spotRequest struct {
+	Name           string            `json:"name"`
+	City           string            `json:"city"`
+	Address        string            `json:"address"`
+	Description    string            `json:"description"`
+	Lat            *float64          `json:"lat"`
+	Lng            *float64          `json:"lng"`
+	BDLat          *float64          `json:"bd_lat"`
+	BDLng          *float64          `json:"bd_lng"`
+	BaiduUID       string            `json:"baidu_uid"`
+	Tags           []string          `json:"tags"`
+	BestSeasons    []string          `json:"best_seasons"`
+	BestTimeOfDay  []string          `json:"best_time_of_day"`
+	PoseCategories []string          `json:"pose_categories"`
+	XHSNotes       []spotNoteRequest `json:"xhs_notes"`
+}
+
+// List 机位列表，可按 city、tag 过滤，limit/offset 分页
+func (h *SpotHandler) List(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultSpotLimit)))
+	if err != nil || limit <= 0 {
+		limit = defaultSpotLimit
+	}
+	limit = min(limit, maxSpotLimit)
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	spots, total, err := h.Repo.List(c.Request.Context(), db.SpotFilter{
+		City:   strings.TrimSpace(c.Query("city")),
+		Tag:    c.Query("tag"),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": spots, "total": total, "limit": limit, "offset": offset})
+}
+
+// Get 机位详情
+func (h *SpotHandler) Get(c *gin.Context) {
+	id, ok := spotID(c)
+	if !ok {
+		return
+	}
+	spot, err := h.Repo.Get(c.Request.Context(), id)
+	if err != nil {
+		respondSpotError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": spot})
+}
+
+// Create 创建机位
+func (h *SpotHandler) Create(c *gin.Context) {
+	spot, ok := h.bindSpot(c)
+	if !ok {
+		return
+	}
+	if err := h.Repo.Create(c.Request.Context(), spot); err != nil {
+		respondSpotError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": spot})
+}
+
+// Update 整体更新机位（标签与关联笔记同样整体替换）
+func (h *SpotHandler) Update(c *gin.Context) {
+	id, ok := spotID(c)
+	if !ok {
+		return
+	}
+	spot, ok := h.bindSpot(c)
+	if !ok {
+		return
+	}
+	spot.ID = id
+	if err := h.Repo.Update(c.Request.Context(), spot); err != nil {
+		respondSpotError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": spot})
+}
+
+// Delete 删除机位
+func (h *SpotHandler) Delete(c *gin.Context) {
+	id, ok := spotID(c)
+	if !ok {
+		return
+	}
+	if err := h.Repo.Delete(c.Request.Context(), id); err != nil {
+		respondSpotError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func spotID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid spot id"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondSpotError(c *gin.Context, err error) {
+	if errors.Is(err, db.ErrSpotNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// bindSpot 解析并校验请求体，失败时已写入 400 响应
+func (h *SpotHandler) bindSpot(c *gin.Context) (*db.PhotoSpot, bool) {
+	var req spotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return nil, false
+	}
+	spot, err := h.spotFromRequest(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return spot, true
+}
+
+func (h *SpotHandler) spotFromRequest(req spotRequest) (*db.PhotoSpot, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	wgs, bd, err := spotCoordinates(req)
+	if err != nil {
+		return nil, err
+	}
+	seasons, err := oneOf("best_seasons", req.BestSeasons, spotSeasons)
+	if err != nil {
+		return nil, err
+	}
+	times, err := oneOf("best_time_of_day", req.BestTimeOfDay, spotTimesOfDay)
+	if err != nil {
+		return nil, err
+	}
+	poses, err := h.poseCategories(req.PoseCategories)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := spotNotes(req.XHSNotes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &db.PhotoSpot{
+		Name:           name,
+		City:           strings.TrimSpace(req.City),
+		Address:        strings.TrimSpace(req.Address),
+		Description:    req.Description,
+		Lat:            wgs.Lat,
+		Lng:            wgs.Lng,
+		BDLat:          bd.Lat,
+		BDLng:          bd.Lng,
+		BaiduUID:       strings.TrimSpace(req.BaiduUID),
+		Tags:           req.Tags,
+		BestSeasons:    seasons,
+		BestTimeOfDay:  times,
+		PoseCategories: poses,
+		Notes:          notes,
+	}, nil
+}
+
+// spotCoordinates 返回 WGS-84 与 BD-09 坐标，缺失的一组由另一组换算
+func spotCoordinates(req spotRequest) (wgs, bd geo.Point, err error) {
+	hasWGS := req.Lat != nil && req.Lng != nil
+	hasBD := req.BDLat != nil && req.BDLng != nil
+	switch {
+	case hasWGS:
+		wgs = geo.Point{Lat: *req.Lat, Lng: *req.Lng}
+		if !wgs.Valid() {
+			return wgs, bd, errors.New("invalid lat/lng")
+		}
+		if hasBD {
+			bd = geo.Point{Lat: *req.BDLat, Lng: *req.BDLng}
+		} else {
+			bd = geo.WGS84ToBD09(wgs)
+		}
+	case hasBD:
+		bd = geo.Point{Lat: *req.BDLat, Lng: *req.BDLng}
+		wgs = geo.BD09ToWGS84(bd)
+	default:
+		return wgs, bd, errors.New("lat/lng (WGS-84) or bd_lat/bd_lng (BD-09) is required")
+	}
+	if !bd.Valid() {
+		return wgs, bd, errors.New("invalid bd_lat/bd_lng")
+	}
+	return wgs, bd, nil
+}
+
+// oneOf 校验取值并去重，统一为小写
+func oneOf(field string, values, allowed []string) (db.StringList, error) {
+	out := make(db.StringList, 0, len(values))
+	for _, v := range values {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v == "" || slices.Contains(out, v) {
+			continue
+		}
+		if !slices.Contains(allowed, v) {
+			return nil, fmt.Errorf("invalid %s %q (want one of %s)", field, v, strings.Join(allowed, ", "))
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func (h *SpotHandler) poseCategories(values []string) (db.StringList, error) {
+	out := make(db.StringList, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" || slices.Contains(out, v) {
+			continue
+		}
+		if h.Catalog != nil {
+			if _, ok := h.Catalog.Category(v); !ok {
+				return nil, fmt.Errorf("unknown pose category %q", v)
+			}
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func spotNotes(reqs []spotNoteRequest) ([]db.PhotoSpotNote, error) {
+	notes := make([]db.PhotoSpotNote, 0, len(reqs))
+	for _, n := range reqs {
+		id, url := strings.TrimSpace(n.NoteID), strings.TrimSpace(n.URL)
+		if id == "" && url != "" {
+			id, _ = mcp.ParseXHSNoteURL(url)
+		}
+		if !mcp.IsXHSNoteID(id) {
+			return nil, fmt.Errorf("invalid xhs note %q", firstNonEmpty(n.NoteID, n.URL))
+		}
+		if url == "" {
+			url = mcp.XHSNoteURL(id, "")
+		}
+		notes = append(notes, db.PhotoSpotNote{NoteID: id, URL: url, Title: strings.TrimSpace(n.Title)})
+	}
+	return notes, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
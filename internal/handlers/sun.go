@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangqi/photo-backend/internal/db"
+	"github.com/huangqi/photo-backend/internal/geo"
+	"github.com/huangqi/photo-backend/internal/solar"
+)
+
+const placeSearchTimeout = 10 * time.Second
+
+// sunTarget 被拍摄的地点。Source 为坐标来源：spot、place、bd09 或 wgs84
+type sunTarget struct {
+	Source  string  `json:"source"`
+	SpotID  uint    `json:"spot_id,omitempty"`
+	UID     string  `json:"uid,omitempty"`
+	Name    string  `json:"name,omitempty"`
+	Address string  `json:"address,omitempty"`
+	Lat     float64 `json:"lat"`
+	Lng     float64 `json:"lng"`
+	BDLat   float64 `json:"bd_lat"`
+	BDLng   float64 `json:"bd_lng"`
+}
+
+// sunResponse 某地某时刻的太阳位置、影子与构图建议，Shadow 在太阳落山后为空
+type sunResponse struct {
+	Target       sunTarget           `json:"target"`
+	Time         time.Time           `json:"time"`
+	Timezone     string              `json:"timezone"`
+	Sun          solar.Position      `json:"sun"`
+	Phase        string              `json:"phase"`
+	Shadow       *solar.Shadow       `json:"shadow"`
+	Compositions []solar.Composition `json:"compositions"`
+	Light        *solar.Light        `json:"light"`
+}
+
+// Sun 机位或地点在某时刻的太阳方位、影长与顺光/侧光/逆光的镜头朝向
+func (h *LightHandler) Sun(c *gin.Context) {
+	target, ok := h.sunTarget(c)
+	if !ok {
+		return
+	}
+	at, ok := parseSunTime(c, h.Location)
+	if !ok {
+		return
+	}
+	pos := solar.PositionAt(at, target.Lat, target.Lng)
+	y, m, d := at.Date()
+	c.JSON(http.StatusOK, gin.H{"data": sunResponse{
+		Target:       target,
+		Time:         at,
+		Timezone:     at.Location().String(),
+		Sun:          pos,
+		Phase:        solar.PhaseOf(pos.Elevation),
+		Shadow:       solar.ShadowOf(pos),
+		Compositions: solar.Compositions(pos),
+		Light:        solar.Compute(time.Date(y, m, d, 0, 0, 0, 0, at.Location()), target.Lat, target.Lng),
+	}})
+}
+
+// sunTarget 依次按 spot_id、place（百度地点检索）、bd_lat/bd_lng、lat/lng 确定地点；失败时已写入响应
+func (h *LightHandler) sunTarget(c *gin.Context) (sunTarget, bool) {
+	switch {
+	case c.Query("spot_id") != "":
+		id, err := strconv.ParseUint(c.Query("spot_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid spot_id"})
+			return sunTarget{}, false
+		}
+		if h.Spots == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "spots module unavailable"})
+			return sunTarget{}, false
+		}
+		spot, err := h.Spots.Get(c.Request.Context(), uint(id))
+		if errors.Is(err, db.ErrSpotNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return sunTarget{}, false
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return sunTarget{}, false
+		}
+		return sunTarget{Source: "spot", SpotID: spot.ID, UID: spot.BaiduUID, Name: spot.Name, Address: spot.Address,
+			Lat: spot.Lat, Lng: spot.Lng, BDLat: spot.BDLat, BDLng: spot.BDLng}, true
+
+	case c.Query("place") != "":
+		if h.Maps == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "baidu maps not configured"})
+			return sunTarget{}, false
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), placeSearchTimeout)
+		defer cancel()
+		places, err := h.Maps.SearchPlaces(ctx, c.Query("place"), "", c.DefaultQuery("region", "全国"), "", 0, "", "")
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return sunTarget{}, false
+		}
+		for _, p := range places {
+			bd := geo.Point{Lat: p.Location.Lat, Lng: p.Location.Lng}
+			if !bd.Valid() || bd.IsZero() {
+				continue
+			}
+			wgs := geo.BD09ToWGS84(bd)
+			return sunTarget{Source: "place", UID: p.UID, Name: p.Name, Address: p.Address,
+				Lat: wgs.Lat, Lng: wgs.Lng, BDLat: bd.Lat, BDLng: bd.Lng}, true
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "place not found"})
+		return sunTarget{}, false
+
+	case c.Query("bd_lat") != "" || c.Query("bd_lng") != "":
+		lat, errLat := strconv.ParseFloat(c.Query("bd_lat"), 64)
+		lng, errLng := strconv.ParseFloat(c.Query("bd_lng"), 64)
+		bd := geo.Point{Lat: lat, Lng: lng}
+		if errLat != nil || errLng != nil || !bd.Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid bd_lat/bd_lng"})
+			return sunTarget{}, false
+		}
+		wgs := geo.BD09ToWGS84(bd)
+		return sunTarget{Source: "bd09", Lat: wgs.Lat, Lng: wgs.Lng, BDLat: bd.Lat, BDLng: bd.Lng}, true
+	}
+
+	if c.Query("lat") == "" && c.Query("lng") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "one of spot_id, place, bd_lat/bd_lng or lat/lng is required"})
+		return sunTarget{}, false
+	}
+	wgs, ok := parsePoint(c)
+	if !ok {
+		return sunTarget{}, false
+	}
+	bd := geo.WGS84ToBD09(wgs)
+	return sunTarget{Source: "wgs84", Lat: wgs.Lat, Lng: wgs.Lng, BDLat: bd.Lat, BDLng: bd.Lng}, true
+}
+
+// parseSunTime 读取 time 参数：RFC 3339，或 tz 下的 "YYYY-MM-DD HH:MM"，缺省为当前时间；非法时已写入 400 响应
+func parseSunTime(c *gin.Context, def *time.Location) (time.Time, bool) {
+	loc := def
+	if name := c.Query("tz"); name != "" {
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tz: " + name})
+			return time.Time{}, false
+		}
+		loc = l
+	}
+	raw := c.Query("time")
+	if raw == "" {
+		return time.Now().In(loc).Truncate(time.Second), true
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t.In(loc), true
+	}
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, raw, loc); err == nil {
+			return t, true
+		}
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "time must be RFC 3339 or YYYY-MM-DD HH:MM"})
+	return time.Time{}, false
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangqi/photo-backend/internal/db"
+	"gorm.io/gorm"
+)
+
+// SystemHandler 健康检查与运行指标
+type SystemHandler struct {
+	// DB 为 nil 表示未连接数据库，DBUnavailableReason 说明原因
+	DB                  *gorm.DB
+	DBUnavailableReason string
+	StartedAt           time.Time
+}
+
+func NewSystemHandler(database *gorm.DB, dbReason string) *SystemHandler {
+	return &SystemHandler{DB: database, DBUnavailableReason: dbReason, StartedAt: time.Now()}
+}
+
+// Health 数据库已连接但 ping 失败时返回 503；未配置数据库不影响健康状态
+func (h *SystemHandler) Health(c *gin.Context) {
+	status := http.StatusOK
+	database := gin.H{"status": "ok"}
+	switch {
+	case h.DB == nil:
+		database = gin.H{"status": "unavailable", "reason": h.DBUnavailableReason}
+	default:
+		if err := db.Ping(c.Request.Context(), h.DB); err != nil {
+			status = http.StatusServiceUnavailable
+			database = gin.H{"status": "error", "error": err.Error()}
+		}
+	}
+	overall := "ok"
+	if status != http.StatusOK {
+		overall = "degraded"
+	}
+	c.JSON(status, gin.H{"data": gin.H{"status": overall, "database": database}})
+}
+
+// Metrics 运行时长与数据库连接池统计
+func (h *SystemHandler) Metrics(c *gin.Context) {
+	data := gin.H{"uptime_seconds": int64(time.Since(h.StartedAt).Seconds())}
+	if h.DB == nil {
+		data["database"] = gin.H{"available": false, "reason": h.DBUnavailableReason}
+	} else if stats, err := db.Stats(h.DB); err != nil {
+		data["database"] = gin.H{"available": false, "reason": err.Error()}
+	} else {
+		data["database"] = gin.H{"available": true, "pool": stats}
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
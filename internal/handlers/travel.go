@@ -3,17 +3,35 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/huangqi/photo-backend/internal/db"
 	"github.com/huangqi/photo-backend/internal/mcp"
+	"github.com/huangqi/photo-backend/internal/solar"
 )
 
 type TravelHandler struct {
 	Maps mcp.MapsClient
+	// Location 计算景点日照信息与行程时间时未指定 tz 使用的时区
+	Location *time.Location
+	// BaiduMaps 为 nil 时行程规划只接受带坐标的地点，路程按直线距离估算
+	BaiduMaps mcp.BaiduMapsClient
+	// Spots 为 nil 时行程规划不接受 spot_id
+	Spots db.SpotRepository
 }
 
-func NewTravelHandler(client mcp.MapsClient) *TravelHandler {
-	return &TravelHandler{Maps: client}
+func NewTravelHandler(client mcp.MapsClient, loc *time.Location) *TravelHandler {
+	if loc == nil {
+		loc = time.Local
+	}
+	return &TravelHandler{Maps: client, Location: loc}
+}
+
+// nearbyAttraction 附近景点及其当天的日照信息，景点没有坐标时 Light 为空
+type nearbyAttraction struct {
+	mcp.Attraction
+	Light *solar.Light `json:"light,omitempty"`
 }
 
 func (h *TravelHandler) GetNearby(c *gin.Context) {
@@ -27,10 +45,21 @@ func (h *TravelHandler) GetNearby(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "lat and lng are required"})
 		return
 	}
+	date, ok := parseLightDate(c, h.Location)
+	if !ok {
+		return
+	}
 	items, err := h.Maps.GetNearbyAttractions(c.Request.Context(), lat, lng, radiusKm)
 	if err != nil {
 		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": items})
+	out := make([]nearbyAttraction, len(items))
+	for i, item := range items {
+		out[i].Attraction = item
+		if item.Latitude != 0 || item.Longitude != 0 {
+			out[i].Light = solar.Compute(date, item.Latitude, item.Longitude)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"data": out})
 }
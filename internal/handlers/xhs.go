@@ -1,30 +1,156 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
+	"github.com/huangqi/photo-backend/internal/db"
 	"github.com/huangqi/photo-backend/internal/mcp"
 )
 
+// maxXHSLimit 单次请求允许的最大结果数，避免一次搜索过久
+const maxXHSLimit = 50
+
+// maxCommentRunes 评论内容最大长度
+const maxCommentRunes = 500
+
 type XHSHandler struct {
-	Client      mcp.XHSClient
-	initialized bool
+	Client   mcp.XHSClient
+	Session  *mcp.XHSSession
+	initOnce sync.Once
+
+	// Repo 为 nil 时不缓存搜索结果；CacheTTL 内的缓存直接返回
+	Repo     db.XHSRepository
+	CacheTTL time.Duration
+
+	// CommentPosting 为 false 时拒绝发表评论；CommentMinInterval 限制两次发表的最小间隔
+	CommentPosting     bool
+	CommentMinInterval time.Duration
+	commentMu          sync.Mutex
+	lastComment        time.Time
 }
 
-func NewXHSHandler(client mcp.XHSClient) *XHSHandler {
-	return &XHSHandler{Client: client}
+// NewXHSHandler session 为 nil 时使用不过期的默认会话
+func NewXHSHandler(client mcp.XHSClient, session *mcp.XHSSession) *XHSHandler {
+	if session == nil {
+		session = mcp.NewXHSSession(client, 0)
+	}
+	return &XHSHandler{Client: client, Session: session}
 }
 
 // ensureInit initializes MCP client once per process
 func (h *XHSHandler) ensureInit(c *gin.Context) {
-	if h.initialized {
+	h.initOnce.Do(func() {
+		_ = h.Client.Initialize(c.Request.Context(), "photo-backend-server", "1.0.0")
+	})
+}
+
+// parseLimit 解析 limit 参数，非法时回退默认值并限制上限
+func parseLimit(c *gin.Context, def int) int {
+	return parseLimitParam(c, "limit", def)
+}
+
+func parseLimitParam(c *gin.Context, key string, def int) int {
+	limit, err := strconv.Atoi(c.DefaultQuery(key, strconv.Itoa(def)))
+	if err != nil || limit <= 0 {
+		return def
+	}
+	if limit > maxXHSLimit {
+		return maxXHSLimit
+	}
+	return limit
+}
+
+// respondXHSError 限流错误返回 429 与 Retry-After，其他错误返回 502
+func respondXHSError(c *gin.Context, err error) {
+	var rateErr *mcp.RateLimitError
+	if errors.As(err, &rateErr) {
+		seconds := retryAfterSeconds(rateErr.RetryAfter)
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "retry_after_seconds": seconds})
 		return
 	}
-	// Initialize MCP client
-	_ = h.Client.Initialize(c.Request.Context(), "photo-backend-server", "1.0.0")
-	h.initialized = true
+	c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+}
+
+func retryAfterSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// withLogin 按需登录后执行 fn，skipLogin 时直接执行
+func (h *XHSHandler) withLogin(ctx context.Context, skipLogin bool, fn func() error) error {
+	if skipLogin {
+		return fn()
+	}
+	return h.Session.Do(ctx, fn)
+}
+
+// 缓存状态，通过 X-Cache 响应头返回
+const (
+	cacheHit   = "HIT"
+	cacheMiss  = "MISS"
+	cacheStale = "STALE"
+)
+
+func (h *XHSHandler) searchPosts(ctx context.Context, keyword string, limit int, skipLogin bool) ([]mcp.XHSPost, error) {
+	posts, _, err := h.cachedSearch(ctx, keyword, limit, skipLogin, false)
+	return posts, err
+}
+
+// cachedSearch 缓存在 CacheTTL 内且覆盖请求的 limit 时直接返回；否则从 MCP 拉取并写回数据库。
+// 拉取失败或返回空结果时退回过期缓存。Repo 为 nil 时不使用缓存。
+func (h *XHSHandler) cachedSearch(ctx context.Context, keyword string, limit int, skipLogin, refresh bool) ([]mcp.XHSPost, string, error) {
+	var cached *db.CachedSearch
+	if h.Repo != nil {
+		var err error
+		cached, err = h.Repo.GetSearchResults(ctx, keyword)
+		if err != nil && !errors.Is(err, db.ErrNotCached) {
+			log.Printf("xhs cache: read %q failed: %v", keyword, err)
+		}
+		if cached != nil && !refresh && cached.Covers(limit) && time.Since(cached.FetchedAt) < h.CacheTTL {
+			return truncatePosts(cached.Posts, limit), cacheHit, nil
+		}
+	}
+
+	var posts []mcp.XHSPost
+	err := h.withLogin(ctx, skipLogin, func() error {
+		var err error
+		posts, err = h.Client.GetPostsByKeyword(ctx, keyword, limit)
+		return err
+	})
+	if err == nil && len(posts) == 0 && cached != nil && len(cached.Posts) > 0 {
+		err = errors.New("empty search results")
+	}
+	if err != nil {
+		if cached != nil && len(cached.Posts) > 0 {
+			log.Printf("xhs cache: serving stale results for %q: %v", keyword, err)
+			return truncatePosts(cached.Posts, limit), cacheStale, nil
+		}
+		return nil, cacheMiss, err
+	}
+	if h.Repo != nil {
+		if err := h.Repo.SaveSearchResults(ctx, keyword, limit, posts, time.Now()); err != nil {
+			log.Printf("xhs cache: save %q failed: %v", keyword, err)
+		}
+	}
+	return posts, cacheMiss, nil
+}
+
+func truncatePosts(posts []mcp.XHSPost, limit int) []mcp.XHSPost {
+	if limit > 0 && len(posts) > limit {
+		return posts[:limit]
+	}
+	return posts
 }
 
 // GetHot retains backward compatibility. When query param q is present, it searches.
@@ -33,30 +159,16 @@ func (h *XHSHandler) GetHot(c *gin.Context) {
 	h.ensureInit(c)
 
 	skipLogin := c.DefaultQuery("skip_login", "false") == "true"
-	if !skipLogin {
-		// Best-effort login; ignore error but surface if needed
-		_, _ = h.Client.CallTool(c.Request.Context(), "login", map[string]any{})
-	}
+	limit := parseLimit(c, 10)
+	keyword := c.DefaultQuery("q", "热门")
+	refresh := c.DefaultQuery("refresh", "false") == "true"
 
-	limitStr := c.DefaultQuery("limit", "10")
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 {
-		limit = 10
-	}
-	if q := c.Query("q"); q != "" {
-		posts, err := h.Client.GetPostsByKeyword(c.Request.Context(), q, limit)
-		if err != nil {
-			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusOK, gin.H{"data": posts})
-		return
-	}
-	posts, err := h.Client.GetHotPosts(c.Request.Context(), limit)
+	posts, cacheStatus, err := h.cachedSearch(c.Request.Context(), keyword, limit, skipLogin, refresh)
 	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		respondXHSError(c, err)
 		return
 	}
+	c.Header("X-Cache", cacheStatus)
 	c.JSON(http.StatusOK, gin.H{"data": posts})
 }
 
@@ -64,21 +176,16 @@ func (h *XHSHandler) SearchLinks(c *gin.Context) {
 	h.ensureInit(c)
 
 	keyword := c.DefaultQuery("q", "热门")
-	limitStr := c.DefaultQuery("limit", "5")
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 {
-		limit = 5
-	}
+	limit := parseLimit(c, 5)
 	skipLogin := c.DefaultQuery("skip_login", "false") == "true"
-	if !skipLogin {
-		_, _ = h.Client.CallTool(c.Request.Context(), "login", map[string]any{})
-	}
+	refresh := c.DefaultQuery("refresh", "false") == "true"
 
-	posts, err := h.Client.GetPostsByKeyword(c.Request.Context(), keyword, limit)
+	posts, cacheStatus, err := h.cachedSearch(c.Request.Context(), keyword, limit, skipLogin, refresh)
 	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		respondXHSError(c, err)
 		return
 	}
+	c.Header("X-Cache", cacheStatus)
 	links := make([]string, 0, len(posts))
 	for _, p := range posts {
 		if p.PostURL != "" {
@@ -87,3 +194,133 @@ func (h *XHSHandler) SearchLinks(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"links": links})
 }
+
+// noteRef 解析请求中的笔记引用：优先使用 url 参数，否则使用路径中的笔记 ID 与 xsec_token
+func noteRef(c *gin.Context) (string, bool) {
+	if ref := c.Query("url"); ref != "" {
+		return ref, true
+	}
+	id := c.Param("id")
+	if !mcp.IsXHSNoteID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid note id, expected 24 hex characters or url parameter"})
+		return "", false
+	}
+	return mcp.XHSNoteURL(id, c.Query("xsec_token")), true
+}
+
+// GetNote 笔记详情。:id 为笔记 ID（可配合 xsec_token 参数），也可通过 url 参数传入完整笔记链接
+func (h *XHSHandler) GetNote(c *gin.Context) {
+	h.ensureInit(c)
+
+	ref, ok := noteRef(c)
+	if !ok {
+		return
+	}
+	skipLogin := c.DefaultQuery("skip_login", "false") == "true"
+
+	var note *mcp.XHSNote
+	err := h.withLogin(c.Request.Context(), skipLogin, func() error {
+		var err error
+		note, err = h.Client.GetNoteContent(c.Request.Context(), ref)
+		return err
+	})
+	if err != nil {
+		respondXHSError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": note})
+}
+
+// GetComments 笔记评论（分页）
+func (h *XHSHandler) GetComments(c *gin.Context) {
+	h.ensureInit(c)
+
+	ref, ok := noteRef(c)
+	if !ok {
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	pageSize := parseLimitParam(c, "page_size", 20)
+	skipLogin := c.DefaultQuery("skip_login", "false") == "true"
+
+	var result *mcp.XHSCommentPage
+	err = h.withLogin(c.Request.Context(), skipLogin, func() error {
+		var err error
+		result, err = h.Client.GetNoteComments(c.Request.Context(), ref, page, pageSize)
+		return err
+	})
+	if err != nil {
+		respondXHSError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+type postCommentRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// PostComment 管理接口：发表评论。需开启 XHS_COMMENT_POSTING，且受最小间隔限制
+func (h *XHSHandler) PostComment(c *gin.Context) {
+	if !h.CommentPosting {
+		c.JSON(http.StatusForbidden, gin.H{"error": "comment posting disabled: set XHS_COMMENT_POSTING=true"})
+		return
+	}
+	h.ensureInit(c)
+
+	ref, ok := noteRef(c)
+	if !ok {
+		return
+	}
+	var req postCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Content = strings.TrimSpace(req.Content)
+	if req.Content == "" || utf8.RuneCountInString(req.Content) > maxCommentRunes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("content must be 1-%d characters", maxCommentRunes)})
+		return
+	}
+
+	// 串行发表，并保证两次发表之间至少间隔 CommentMinInterval
+	h.commentMu.Lock()
+	defer h.commentMu.Unlock()
+	if wait := h.CommentMinInterval - time.Since(h.lastComment); wait > 0 {
+		respondXHSError(c, &mcp.RateLimitError{Scope: "comment", RetryAfter: wait})
+		return
+	}
+
+	var out string
+	err := h.withLogin(c.Request.Context(), false, func() error {
+		var err error
+		out, err = h.Client.PostComment(c.Request.Context(), ref, req.Content)
+		return err
+	})
+	if err != nil {
+		respondXHSError(c, err)
+		return
+	}
+	h.lastComment = time.Now()
+	log.Printf("xhs comment posted: note=%s length=%d", ref, utf8.RuneCountInString(req.Content))
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"result": out}})
+}
+
+// SessionStatus 管理接口：查看登录会话状态
+func (h *XHSHandler) SessionStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": h.Session.Status()})
+}
+
+// SessionLogin 管理接口：强制重新登录
+func (h *XHSHandler) SessionLogin(c *gin.Context) {
+	h.ensureInit(c)
+
+	if err := h.Session.Login(c.Request.Context()); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "data": h.Session.Status()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": h.Session.Status()})
+}
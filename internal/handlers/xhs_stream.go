@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangqi/photo-backend/internal/mcp"
+)
+
+// SSE 事件名
+const (
+	sseEventLogin    = "login"
+	sseEventProgress = "progress"
+	sseEventLog      = "log"
+	sseEventPost     = "post"
+	sseEventSummary  = "summary"
+	sseEventError    = "error"
+)
+
+type sseEvent struct {
+	name string
+	data any
+}
+
+// StreamSearch 以 Server-Sent Events 推送搜索过程：登录状态、进度通知、逐条帖子与最终汇总。
+// 客户端断开时取消正在进行的 MCP 调用。
+func (h *XHSHandler) StreamSearch(c *gin.Context) {
+	h.ensureInit(c)
+
+	keyword := c.DefaultQuery("q", "热门")
+	limit := parseLimit(c, 10)
+	skipLogin := c.DefaultQuery("skip_login", "false") == "true"
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	// events 不关闭：通知回调可能在调用结束后才被分发，向已关闭的通道发送会 panic。
+	// 生产者结束时关闭 done
+	events := make(chan sseEvent, 32)
+	done := make(chan struct{})
+	send := func(ev sseEvent) bool {
+		select {
+		case events <- ev:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	// 通知回调运行在 transport 读循环上，缓冲满时丢弃而不是阻塞
+	ctx = mcp.WithNotificationHandler(ctx, func(n mcp.Notification) {
+		ev := sseEvent{name: sseEventProgress, data: n.Progress}
+		if n.Log != nil {
+			ev = sseEvent{name: sseEventLog, data: n.Log}
+		}
+		select {
+		case events <- ev:
+		default:
+		}
+	})
+
+	go func() {
+		defer close(done)
+		start := time.Now()
+
+		// 登录失败时不再在搜索阶段重复登录
+		loginFailed := false
+		if !skipLogin {
+			reused := h.Session.Status().LoggedIn
+			if !send(sseEvent{sseEventLogin, gin.H{"status": "started"}}) {
+				return
+			}
+			if err := h.Session.Ensure(ctx); err != nil {
+				// 与 GetHot 一致：登录失败不中断搜索
+				loginFailed = true
+				if !send(sseEvent{sseEventLogin, gin.H{"status": "failed", "error": err.Error()}}) {
+					return
+				}
+			} else if !send(sseEvent{sseEventLogin, gin.H{"status": "ok", "reused": reused}}) {
+				return
+			}
+		}
+
+		posts, err := h.searchPosts(ctx, keyword, limit, skipLogin || loginFailed)
+		if err != nil {
+			data := gin.H{"error": err.Error()}
+			var rateErr *mcp.RateLimitError
+			if errors.As(err, &rateErr) {
+				data["retry_after_seconds"] = retryAfterSeconds(rateErr.RetryAfter)
+			}
+			send(sseEvent{sseEventError, data})
+			return
+		}
+		for i, p := range posts {
+			if !send(sseEvent{sseEventPost, gin.H{"index": i, "post": p}}) {
+				return
+			}
+		}
+		send(sseEvent{sseEventSummary, gin.H{
+			"keyword":    keyword,
+			"count":      len(posts),
+			"elapsed_ms": time.Since(start).Milliseconds(),
+		}})
+	}()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case ev := <-events:
+			c.SSEvent(ev.name, ev.data)
+			return true
+		case <-done:
+			// 生产者的事件都已进入缓冲，发送完再结束；之后到达的通知丢弃
+			for range len(events) {
+				ev := <-events
+				c.SSEvent(ev.name, ev.data)
+			}
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
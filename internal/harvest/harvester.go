@@ -0,0 +1,167 @@
+package harvest
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/huangqi/photo-backend/internal/catalog"
+	"github.com/huangqi/photo-backend/internal/db"
+	"github.com/huangqi/photo-backend/internal/mcp"
+)
+
+// ErrRunning 已有一次采集正在进行
+var ErrRunning = errors.New("harvest already running")
+
+// Report 一次采集的结果
+type Report struct {
+	StartedAt  time.Time            `json:"started_at"`
+	FinishedAt time.Time            `json:"finished_at"`
+	Queries    int                  `json:"queries"`
+	Links      int                  `json:"links"`
+	Errors     []catalog.QueryError `json:"errors,omitempty"`
+}
+
+// Status 采集器状态
+type Status struct {
+	Running bool       `json:"running"`
+	LastRun *Report    `json:"last_run,omitempty"`
+	NextRun *time.Time `json:"next_run,omitempty"`
+}
+
+// Harvester 定期用目录中的每条搜索短语搜索小红书，并把结果记录到 LinkStore
+type Harvester struct {
+	Catalog *catalog.Catalog
+	Search  catalog.SearchFunc
+	Store   *db.LinkStore
+
+	// PerQuery 每条短语取的结果数；QueryDelay 两次搜索之间的间隔
+	PerQuery   int
+	QueryDelay time.Duration
+
+	mu     sync.Mutex
+	status Status
+}
+
+// Status 返回状态快照
+func (h *Harvester) Status() Status {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.status
+}
+
+// RunOnce 执行一次完整采集，同一时间只允许一次
+func (h *Harvester) RunOnce(ctx context.Context) (*Report, error) {
+	h.mu.Lock()
+	if h.status.Running {
+		h.mu.Unlock()
+		return nil, ErrRunning
+	}
+	h.status.Running = true
+	h.mu.Unlock()
+
+	report := &Report{StartedAt: time.Now()}
+	defer func() {
+		report.FinishedAt = time.Now()
+		h.mu.Lock()
+		h.status.Running = false
+		h.status.LastRun = report
+		h.mu.Unlock()
+	}()
+
+	first := true
+	for _, category := range h.Catalog.Categories {
+		for _, q := range category.Queries {
+			if !first && h.QueryDelay > 0 {
+				select {
+				case <-ctx.Done():
+					return report, ctx.Err()
+				case <-time.After(h.QueryDelay):
+				}
+			}
+			first = false
+
+			n, err := h.harvestQuery(ctx, category.Name, q)
+			report.Queries++
+			report.Links += n
+			if err != nil {
+				report.Errors = append(report.Errors, catalog.QueryError{Query: q.Text, Error: err.Error()})
+				log.Printf("harvest: query %q failed: %v", q.Text, err)
+			}
+			if ctx.Err() != nil {
+				return report, ctx.Err()
+			}
+		}
+	}
+	log.Printf("harvest: %d queries, %d links, %d errors in %s",
+		report.Queries, report.Links, len(report.Errors), time.Since(report.StartedAt).Round(time.Second))
+	return report, nil
+}
+
+func (h *Harvester) harvestQuery(ctx context.Context, category string, q catalog.Query) (int, error) {
+	posts, err := h.Search(ctx, q.Text, h.PerQuery)
+	var rateErr *mcp.RateLimitError
+	if errors.As(err, &rateErr) {
+		// 后台任务不急，等到预算恢复后重试一次
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(rateErr.RetryAfter):
+		}
+		posts, err = h.Search(ctx, q.Text, h.PerQuery)
+	}
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	links := make([]db.XHSLink, 0, len(posts))
+	seen := make(map[string]bool, len(posts))
+	for i, p := range posts {
+		if p.ID == "" || seen[p.ID] {
+			continue
+		}
+		seen[p.ID] = true
+		links = append(links, db.XHSLink{
+			NoteID:      p.ID,
+			URL:         p.PostURL,
+			XsecToken:   p.XsecToken,
+			Title:       p.Title,
+			Category:    category,
+			Query:       q.Text,
+			Rank:        i + 1,
+			FirstSeenAt: now,
+			LastSeenAt:  now,
+		})
+	}
+	if len(links) == 0 {
+		return 0, nil
+	}
+	if err := h.Store.Record(ctx, links); err != nil {
+		return 0, err
+	}
+	return len(links), nil
+}
+
+// Run 每隔 interval 采集一次，直到 ctx 结束
+func (h *Harvester) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	for {
+		next := time.Now().Add(interval)
+		h.mu.Lock()
+		h.status.NextRun = &next
+		h.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		if _, err := h.RunOnce(ctx); err != nil && !errors.Is(err, ErrRunning) {
+			log.Printf("harvest: run failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Package itinerary 把一天要去的多个拍摄地点排成行程：解析坐标、规划两两之间的路线，
+// 再在各地点的最佳光线时段与路上耗时之间权衡，给出访问顺序与时间安排
+package itinerary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/huangqi/photo-backend/internal/geo"
+	"github.com/huangqi/photo-backend/internal/mcp"
+	"github.com/huangqi/photo-backend/internal/solar"
+)
+
+const (
+	// MaxPlaces 一次规划的地点上限，顺序按穷举求解
+	MaxPlaces = 8
+	// DefaultStay 每个地点默认停留时长
+	DefaultStay = 45 * time.Minute
+)
+
+// ErrMapsUnavailable 未配置百度地图，无法解析地点与规划路线
+var ErrMapsUnavailable = errors.New("baidu maps not configured")
+
+// Place 行程中的一个地点。BD09 为空时依次按 UID（需同时给出 Name 用于检索）、Address、Name 解析
+type Place struct {
+	SpotID  *uint      `json:"spot_id,omitempty"`
+	UID     string     `json:"uid,omitempty"`
+	Name    string     `json:"name,omitempty"`
+	Address string     `json:"address,omitempty"`
+	Region  string     `json:"region,omitempty"`
+	BD09    *geo.Point `json:"bd09,omitempty"`
+	// Light 偏好的拍摄时段，取值同机位的 best_time_of_day，为空表示白天任意时段
+	Light []string `json:"light,omitempty"`
+	// Stay 停留时长，为 0 时使用 Request.Stay
+	Stay time.Duration `json:"-"`
+}
+
+// Request 规划请求。Date 为当地日期零点；Start 为出发地，可为空；
+// StartAt 为出发时间，为空时按第一个地点的最佳时段倒推
+type Request struct {
+	Places  []Place
+	Date    time.Time
+	Mode    string
+	Start   *Place
+	StartAt *time.Time
+	Stay    time.Duration
+}
+
+// Plan 一天的行程
+type Plan struct {
+	Date  string `json:"date"`
+	Mode  string `json:"mode"`
+	Stops []Stop `json:"stops"`
+	// Legs 相邻两站之间的路线，From 为 -1 表示出发地
+	Legs  []Leg  `json:"legs"`
+	Start *Place `json:"start,omitempty"`
+
+	DepartAt      time.Time `json:"depart_at"`
+	EndAt         time.Time `json:"end_at"`
+	TravelMinutes int       `json:"travel_minutes"`
+	WaitMinutes   int       `json:"wait_minutes"`
+	// MissedMinutes 各站停留时间落在偏好时段之外的分钟数之和
+	MissedMinutes int `json:"missed_minutes"`
+
+	Reasoning  []string     `json:"reasoning"`
+	Unresolved []Unresolved `json:"unresolved,omitempty"`
+}
+
+// Stop 行程中的一站，Index 为地点在请求中的下标
+type Stop struct {
+	Index int   `json:"index"`
+	Place Place `json:"place"`
+	// Window 本站安排所在的光线时段
+	Window        *Window        `json:"window,omitempty"`
+	ArriveAt      time.Time      `json:"arrive_at"`
+	StartAt       time.Time      `json:"start_at"`
+	LeaveAt       time.Time      `json:"leave_at"`
+	WaitMinutes   int            `json:"wait_minutes"`
+	MissedMinutes int            `json:"missed_minutes"`
+	Sun           solar.Position `json:"sun"`
+	Phase         string         `json:"phase"`
+	Reason        string         `json:"reason"`
+}
+
+// Leg 两站之间的路线。Estimated 为 true 时路线规划失败，按直线距离估算，Error 为失败原因
+type Leg struct {
+	From      int       `json:"from"`
+	To        int       `json:"to"`
+	DistanceM int       `json:"distance_m"`
+	DurationS int       `json:"duration_s"`
+	Estimated bool      `json:"estimated,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	DepartAt  time.Time `json:"depart_at"`
+	ArriveAt  time.Time `json:"arrive_at"`
+}
+
+// Unresolved 无法解析坐标的地点，不参与规划
+type Unresolved struct {
+	Index int    `json:"index"`
+	Place Place  `json:"place"`
+	Error string `json:"error"`
+}
+
+// Build 解析地点坐标、构建路线耗时矩阵并排出行程。至少要有一个地点能解析出坐标
+func Build(ctx context.Context, maps mcp.BaiduMapsClient, req Request) (*Plan, error) {
+	if req.Stay <= 0 {
+		req.Stay = DefaultStay
+	}
+	plan := &Plan{Date: req.Date.Format("2006-01-02"), Mode: req.Mode, Stops: []Stop{}, Legs: []Leg{}}
+
+	var places []Place
+	var indexes []int
+	for i, p := range req.Places {
+		if err := resolve(ctx, maps, &p); err != nil {
+			plan.Unresolved = append(plan.Unresolved, Unresolved{Index: i, Place: p, Error: err.Error()})
+			continue
+		}
+		places = append(places, p)
+		indexes = append(indexes, i)
+	}
+	if len(places) == 0 {
+		return plan, errors.New("no place could be resolved")
+	}
+	points := make([]geo.Point, 0, len(places)+1)
+	if req.Start != nil {
+		if err := resolve(ctx, maps, req.Start); err != nil {
+			return plan, fmt.Errorf("start: %w", err)
+		}
+		plan.Start = req.Start
+		points = append(points, *req.Start.BD09)
+	}
+	for _, p := range places {
+		points = append(points, *p.BD09)
+	}
+
+	m := buildMatrix(ctx, maps, points, req.Mode)
+	offset := 0
+	if req.Start != nil {
+		offset = 1
+	}
+	s := newScheduler(req, places, m, offset)
+	best := s.search()
+	s.fill(plan, best, indexes)
+	if symmetric(req.Mode) && len(points) > 2 {
+		plan.Reasoning = append(plan.Reasoning, "步行、骑行的往返路线按相同耗时估计：每两个地点之间只规划了一个方向")
+	}
+	return plan, nil
+}
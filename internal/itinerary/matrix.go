@@ -0,0 +1,194 @@
+package itinerary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/huangqi/photo-backend/internal/album"
+	"github.com/huangqi/photo-backend/internal/geo"
+	"github.com/huangqi/photo-backend/internal/mcp"
+)
+
+const (
+	// maxConcurrentCalls 构建矩阵时并发调用百度地图的上限
+	maxConcurrentCalls = 4
+	// detourFactor 路线规划失败时，实际路程按直线距离的倍数估算
+	detourFactor = 1.3
+)
+
+// speedsKmh 各出行方式估算耗时用的平均速度
+var speedsKmh = map[string]float64{
+	album.ModeWalking: 4.5,
+	album.ModeRiding:  12,
+	album.ModeDriving: 30,
+	album.ModeTransit: 20,
+}
+
+// route 两点之间的路程与耗时
+type route struct {
+	DistanceM int
+	DurationS int
+	Estimated bool
+	Error     string
+}
+
+// matrix 两两之间的路线，m[i][j] 为 i 到 j
+type matrix [][]route
+
+// symmetric 步行、骑行的往返路线基本相同，每对地点只规划一个方向；
+// 驾车、公交受单行道和线路影响，两个方向分别规划
+func symmetric(mode string) bool {
+	return mode == album.ModeWalking || mode == album.ModeRiding
+}
+
+// resolve 补全地点的 BD-09 坐标
+func resolve(ctx context.Context, maps mcp.BaiduMapsClient, p *Place) error {
+	if p.BD09 != nil {
+		if !p.BD09.Valid() || p.BD09.IsZero() {
+			return errors.New("invalid coordinates")
+		}
+		return nil
+	}
+	if p.UID == "" && p.Address == "" && p.Name == "" {
+		return errors.New("one of spot_id, uid, address, name or bd_lat/bd_lng is required")
+	}
+	if maps == nil {
+		return ErrMapsUnavailable
+	}
+	region := p.Region
+	if region == "" {
+		region = "全国"
+	}
+
+	if p.UID != "" {
+		found, err := maps.GetPlaceDetail(ctx, p.UID)
+		if err != nil {
+			return err
+		}
+		if found.UID == "" {
+			found.UID = p.UID
+		}
+		if err := setFromPlace(p, *found); err != nil {
+			return fmt.Errorf("uid %s: %w", p.UID, err)
+		}
+		return nil
+	}
+	if p.Address != "" {
+		res, err := maps.Geocode(ctx, p.Address)
+		if err != nil {
+			return err
+		}
+		pt := geo.Point{Lat: res.Result.Location.Lat, Lng: res.Result.Location.Lng}
+		if !pt.Valid() || pt.IsZero() {
+			return fmt.Errorf("address %q not found", p.Address)
+		}
+		p.BD09 = &pt
+		if p.Name == "" {
+			p.Name = p.Address
+		}
+		return nil
+	}
+	places, err := maps.SearchPlaces(ctx, p.Name, "", region, "", 0, "", "")
+	if err != nil {
+		return err
+	}
+	for _, found := range places {
+		if err := setFromPlace(p, found); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("place %q not found", p.Name)
+}
+
+func setFromPlace(p *Place, found mcp.Place) error {
+	pt := geo.Point{Lat: found.Location.Lat, Lng: found.Location.Lng}
+	if !pt.Valid() || pt.IsZero() {
+		return errors.New("place has no location")
+	}
+	p.BD09 = &pt
+	p.UID = found.UID
+	p.Name = found.Name
+	if p.Address == "" {
+		p.Address = found.Address
+	}
+	return nil
+}
+
+// buildMatrix 规划两两之间的路线；maps 为 nil 或规划失败时按直线距离估算
+func buildMatrix(ctx context.Context, maps mcp.BaiduMapsClient, points []geo.Point, mode string) matrix {
+	n := len(points)
+	m := make(matrix, n)
+	for i := range m {
+		m[i] = make([]route, n)
+	}
+	mirror := symmetric(mode)
+	sem := make(chan struct{}, maxConcurrentCalls)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i == j || (mirror && j < i) {
+				continue
+			}
+			if maps == nil {
+				m[i][j] = estimate(points[i], points[j], mode, ErrMapsUnavailable.Error())
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					m[i][j] = estimate(points[i], points[j], mode, ctx.Err().Error())
+					return
+				}
+				defer func() { <-sem }()
+				m[i][j] = directions(ctx, maps, points[i], points[j], mode)
+			}()
+		}
+	}
+	wg.Wait()
+	if mirror {
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				m[j][i] = m[i][j]
+			}
+		}
+	}
+	return m
+}
+
+func directions(ctx context.Context, maps mcp.BaiduMapsClient, from, to geo.Point, mode string) route {
+	res, err := maps.GetDirections(ctx, formatPoint(from), formatPoint(to), mode)
+	if err != nil {
+		return estimate(from, to, mode, err.Error())
+	}
+	if len(res.Result.Routes) == 0 {
+		return estimate(from, to, mode, "no route found")
+	}
+	r := res.Result.Routes[0]
+	return route{DistanceM: r.Distance, DurationS: r.Duration}
+}
+
+func estimate(from, to geo.Point, mode, reason string) route {
+	km := geo.DistanceKm(from, to) * detourFactor
+	speed, ok := speedsKmh[mode]
+	if !ok {
+		speed = speedsKmh[album.ModeWalking]
+	}
+	return route{
+		DistanceM: int(math.Round(km * 1000)),
+		DurationS: int(math.Round(km / speed * 3600)),
+		Estimated: true,
+		Error:     reason,
+	}
+}
+
+// formatPoint 百度地图路线规划接受的 "纬度,经度"（BD-09）
+func formatPoint(p geo.Point) string {
+	return strconv.FormatFloat(p.Lat, 'f', 6, 64) + "," + strconv.FormatFloat(p.Lng, 'f', 6, 64)
+}
@@ -0,0 +1,197 @@
+package itinerary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/huangqi/photo-backend/internal/album"
+	"github.com/huangqi/photo-backend/internal/geo"
+	"github.com/huangqi/photo-backend/internal/mcp"
+)
+
+// fakeMaps 按 "起点 终点" 返回固定耗时（秒），未配置的路线返回 noRoute 时失败，否则 600 秒
+type fakeMaps struct {
+	mcp.BaiduMapsClient
+
+	places    map[string]mcp.Place
+	search    []mcp.Place
+	durations map[string]int
+	noRoute   bool
+
+	mu    sync.Mutex
+	calls []string
+}
+
+func (f *fakeMaps) record(call string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, call)
+}
+
+func (f *fakeMaps) GetPlaceDetail(_ context.Context, uid string) (*mcp.Place, error) {
+	f.record("detail " + uid)
+	p, ok := f.places[uid]
+	if !ok {
+		return nil, errors.New("place not found")
+	}
+	return &p, nil
+}
+
+func (f *fakeMaps) Geocode(_ context.Context, address string) (*mcp.GeocodeResult, error) {
+	f.record("geocode " + address)
+	var res mcp.GeocodeResult
+	if p, ok := f.places[address]; ok {
+		res.Result.Location.Lat, res.Result.Location.Lng = p.Location.Lat, p.Location.Lng
+	}
+	return &res, nil
+}
+
+func (f *fakeMaps) SearchPlaces(_ context.Context, query, tag, region, location string, radius int, language, isChina string) ([]mcp.Place, error) {
+	f.record("search " + query + " " + region)
+	return f.search, nil
+}
+
+func (f *fakeMaps) GetDirections(_ context.Context, origin, destination, mode string) (*mcp.DirectionsResult, error) {
+	key := origin + " " + destination
+	f.record("directions " + key)
+	d, ok := f.durations[key]
+	if !ok {
+		if f.noRoute {
+			return nil, errors.New("route failed")
+		}
+		d = 600
+	}
+	var res mcp.DirectionsResult
+	if err := json.Unmarshal([]byte(fmt.Sprintf(`{"result":{"routes":[{"distance":%d,"duration":%d}]}}`, d*2, d)), &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func place(uid, name string, p geo.Point) mcp.Place {
+	var out mcp.Place
+	out.UID, out.Name, out.Address = uid, name, name+"地址"
+	out.Location.Lat, out.Location.Lng = p.Lat, p.Lng
+	return out
+}
+
+var (
+	palace   = geo.Point{Lat: 39.924091, Lng: 116.403414}
+	jingshan = geo.Point{Lat: 39.930946, Lng: 116.403046}
+	beihai   = geo.Point{Lat: 39.931953, Lng: 116.396232}
+)
+
+func TestResolve(t *testing.T) {
+	maps := &fakeMaps{
+		places: map[string]mcp.Place{
+			"uid-palace":  place("", "故宫博物院", palace),
+			"uid-nowhere": place("uid-nowhere", "无坐标", geo.Point{}),
+			"景山前街4号":      place("", "", palace),
+		},
+		search: []mcp.Place{place("uid-empty", "没有坐标的结果", geo.Point{}), place("uid-beihai", "北海公园", beihai)},
+	}
+	tests := []struct {
+		name    string
+		maps    mcp.BaiduMapsClient
+		in      Place
+		want    Place
+		wantErr string
+	}{
+		{"coordinates", maps, Place{Name: "景山", BD09: &jingshan}, Place{Name: "景山", BD09: &jingshan}, ""},
+		{"invalid coordinates", maps, Place{BD09: &geo.Point{Lat: 100, Lng: 0}}, Place{}, "invalid coordinates"},
+		{"nothing to resolve", maps, Place{}, Place{}, "required"},
+		{"no maps", nil, Place{Name: "故宫"}, Place{}, ErrMapsUnavailable.Error()},
+		// 只给 UID 时通过地点详情解析，详情缺少 UID 时保留请求中的
+		{"bare uid", maps, Place{UID: "uid-palace"}, Place{UID: "uid-palace", Name: "故宫博物院", Address: "故宫博物院地址", BD09: &palace}, ""},
+		{"uid not found", maps, Place{UID: "uid-missing"}, Place{}, "place not found"},
+		{"uid without location", maps, Place{UID: "uid-nowhere"}, Place{}, "uid uid-nowhere: place has no location"},
+		{"address", maps, Place{Address: "景山前街4号"}, Place{Name: "景山前街4号", Address: "景山前街4号", BD09: &palace}, ""},
+		{"unknown address", maps, Place{Address: "不存在"}, Place{}, `address "不存在" not found`},
+		{"name search skips results without location", maps, Place{Name: "北海"}, Place{UID: "uid-beihai", Name: "北海公园", Address: "北海公园地址", BD09: &beihai}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.in
+			err := resolve(context.Background(), tt.maps, &p)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.UID != tt.want.UID || p.Name != tt.want.Name || p.Address != tt.want.Address || p.BD09 == nil || *p.BD09 != *tt.want.BD09 {
+				t.Errorf("resolved = %+v (bd09 %v), want %+v (bd09 %v)", p, p.BD09, tt.want, tt.want.BD09)
+			}
+		})
+	}
+	if !slices.Contains(maps.calls, "search 北海 全国") {
+		t.Errorf("name search without region: calls = %q, want region 全国", maps.calls)
+	}
+}
+
+func TestBuildMatrix(t *testing.T) {
+	points := []geo.Point{palace, jingshan, beihai}
+	ab := formatPoint(palace) + " " + formatPoint(jingshan)
+	ba := formatPoint(jingshan) + " " + formatPoint(palace)
+
+	t.Run("walking plans one direction", func(t *testing.T) {
+		maps := &fakeMaps{durations: map[string]int{ab: 300, ba: 900}}
+		m := buildMatrix(context.Background(), maps, points, album.ModeWalking)
+		if len(maps.calls) != 3 {
+			t.Errorf("calls = %q, want one per pair", maps.calls)
+		}
+		for i := range points {
+			for j := range points {
+				if m[i][j] != m[j][i] {
+					t.Errorf("m[%d][%d] = %+v, m[%d][%d] = %+v; want symmetric", i, j, m[i][j], j, i, m[j][i])
+				}
+			}
+		}
+		if m[1][0].DurationS != 300 || m[0][0] != (route{}) {
+			t.Errorf("m[1][0] = %+v, want the planned palace → jingshan route", m[1][0])
+		}
+	})
+
+	t.Run("driving plans both directions", func(t *testing.T) {
+		maps := &fakeMaps{durations: map[string]int{ab: 300, ba: 900}}
+		m := buildMatrix(context.Background(), maps, points, album.ModeDriving)
+		if len(maps.calls) != 6 {
+			t.Errorf("calls = %q, want every ordered pair", maps.calls)
+		}
+		if m[0][1].DurationS != 300 || m[1][0].DurationS != 900 || m[0][1].DistanceM != 600 {
+			t.Errorf("m[0][1] = %+v, m[1][0] = %+v", m[0][1], m[1][0])
+		}
+	})
+
+	t.Run("failed directions fall back to straight-line estimates", func(t *testing.T) {
+		maps := &fakeMaps{durations: map[string]int{ab: 300}, noRoute: true}
+		m := buildMatrix(context.Background(), maps, points, album.ModeDriving)
+		if m[0][1].Estimated || m[0][1].DurationS != 300 {
+			t.Errorf("planned route = %+v", m[0][1])
+		}
+		want := estimate(jingshan, palace, album.ModeDriving, "route failed")
+		if got := m[1][0]; got != want || !got.Estimated {
+			t.Errorf("m[1][0] = %+v, want estimate %+v", got, want)
+		}
+		km := geo.DistanceKm(jingshan, palace) * detourFactor
+		if want.DistanceM != int(km*1000+0.5) || want.DurationS != int(km/speedsKmh[album.ModeDriving]*3600+0.5) {
+			t.Errorf("estimate = %+v for %.3f km", want, km)
+		}
+	})
+
+	t.Run("no maps", func(t *testing.T) {
+		m := buildMatrix(context.Background(), nil, points, album.ModeWalking)
+		if r := m[2][0]; !r.Estimated || r.Error != ErrMapsUnavailable.Error() || r != m[0][2] {
+			t.Errorf("m[2][0] = %+v, want a symmetric estimate", r)
+		}
+	})
+}
@@ -0,0 +1,377 @@
+package itinerary
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/huangqi/photo-backend/internal/geo"
+	"github.com/huangqi/photo-backend/internal/solar"
+)
+
+// 代价权重，单位为每分钟：路上耗时计 1，等待光线计 waitWeight，停留时间落在偏好时段之外计 missWeight
+const (
+	waitWeight = 0.2
+	missWeight = 3
+)
+
+// Window 一个地点可拍摄的光线时段，Name 取值同机位的 best_time_of_day，白天任意时段为 daytime
+type Window struct {
+	Name  string    `json:"name"`
+	Label string    `json:"label"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// lightLabels 偏好时段的中文名称
+var lightLabels = map[string]string{
+	"sunrise":     "日出",
+	"morning":     "上午",
+	"noon":        "正午",
+	"afternoon":   "下午",
+	"golden_hour": "黄金时刻",
+	"sunset":      "日落",
+	"blue_hour":   "蓝调时刻",
+	"night":       "夜景",
+	"daytime":     "白天",
+}
+
+// windowsFor 按偏好时段与当天日照计算地点的可拍摄时段，按开始时间排序；
+// 没有偏好或偏好时段当天都不存在时为整个白天
+func windowsFor(prefs []string, light *solar.Light, date time.Time) []Window {
+	gm, ge := light.GoldenHour.Morning, light.GoldenHour.Evening
+	bm, be := light.BlueHour.Morning, light.BlueHour.Evening
+	noon := light.SolarNoon.Time
+
+	var out []Window
+	add := func(name, label string, start, end time.Time) {
+		if end.After(start) {
+			out = append(out, Window{Name: name, Label: label, Start: start, End: end})
+		}
+	}
+	for _, pref := range prefs {
+		switch pref {
+		case "sunrise":
+			if gm != nil {
+				add(pref, "清晨黄金时刻", gm.Start, gm.End)
+			}
+		case "sunset":
+			if ge != nil {
+				add(pref, "傍晚黄金时刻", ge.Start, ge.End)
+			}
+		case "golden_hour":
+			if gm != nil {
+				add(pref, "清晨黄金时刻", gm.Start, gm.End)
+			}
+			if ge != nil {
+				add(pref, "傍晚黄金时刻", ge.Start, ge.End)
+			}
+		case "blue_hour":
+			if bm != nil {
+				add(pref, "清晨蓝调时刻", bm.Start, bm.End)
+			}
+			if be != nil {
+				add(pref, "傍晚蓝调时刻", be.Start, be.End)
+			}
+		case "morning":
+			if gm != nil {
+				add(pref, "上午", gm.End, noon.Add(-time.Hour))
+			}
+		case "noon":
+			add(pref, "正午", noon.Add(-time.Hour), noon.Add(time.Hour))
+		case "afternoon":
+			if ge != nil {
+				add(pref, "下午", noon.Add(time.Hour), ge.Start)
+			}
+		case "night":
+			if be != nil {
+				add(pref, "夜景", be.End, be.End.Add(3*time.Hour))
+			}
+		}
+	}
+	if len(out) == 0 {
+		start, end := date, date.AddDate(0, 0, 1)
+		if gm != nil {
+			start = gm.Start
+		}
+		if ge != nil {
+			end = ge.End
+		}
+		add("daytime", "白天", start, end)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	return out
+}
+
+type scheduler struct {
+	places  []Place
+	windows [][]Window
+	stays   []time.Duration
+	m       matrix
+	// offset 为 1 时矩阵第 0 个点是出发地
+	offset  int
+	startAt *time.Time
+	tried   int
+}
+
+// visit 在某站的安排
+type visit struct {
+	window               *Window
+	arrive, start, leave time.Time
+	travel, wait, missed time.Duration
+}
+
+type schedule struct {
+	order    []int
+	visits   []visit
+	departAt time.Time
+	cost     float64
+}
+
+func newScheduler(req Request, places []Place, m matrix, offset int) *scheduler {
+	s := &scheduler{places: places, m: m, offset: offset, startAt: req.StartAt}
+	for _, p := range places {
+		wgs := geo.BD09ToWGS84(*p.BD09)
+		light := solar.Compute(req.Date, wgs.Lat, wgs.Lng)
+		s.windows = append(s.windows, windowsFor(p.Light, light, req.Date))
+		stay := p.Stay
+		if stay <= 0 {
+			stay = req.Stay
+		}
+		s.stays = append(s.stays, stay)
+	}
+	return s
+}
+
+// search 穷举所有访问顺序，取代价最小者；同代价时保留先遇到的顺序
+func (s *scheduler) search() schedule {
+	n := len(s.places)
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	best := schedule{cost: math.Inf(1)}
+	var permute func(k int)
+	permute = func(k int) {
+		if k == n {
+			s.tried++
+			if sc := s.simulate(order); sc.cost < best.cost {
+				best = sc
+			}
+			return
+		}
+		for i := k; i < n; i++ {
+			order[k], order[i] = order[i], order[k]
+			permute(k + 1)
+			order[k], order[i] = order[i], order[k]
+		}
+	}
+	permute(0)
+	return best
+}
+
+func (s *scheduler) travel(from, to int) time.Duration {
+	if from < 0 {
+		return 0
+	}
+	return time.Duration(s.m[from][to].DurationS) * time.Second
+}
+
+// simulate 按给定顺序排时间：到达后若偏好时段尚未开始，在等待与错过之间取代价小的一方
+func (s *scheduler) simulate(order []int) schedule {
+	sc := schedule{order: append([]int(nil), order...)}
+	prev := -1
+	if s.offset == 1 {
+		prev = 0
+	}
+	first := order[0]
+	if s.startAt != nil {
+		sc.departAt = *s.startAt
+	} else {
+		sc.departAt = s.windows[first][0].Start.Add(-s.travel(prev, s.offset+first))
+	}
+
+	t := sc.departAt
+	var cost float64
+	for _, idx := range order {
+		node := s.offset + idx
+		tr := s.travel(prev, node)
+		v := s.bestVisit(idx, t.Add(tr))
+		v.travel = tr
+		sc.visits = append(sc.visits, v)
+		cost += tr.Minutes() + waitWeight*v.wait.Minutes() + missWeight*v.missed.Minutes()
+		t = v.leave
+		prev = node
+	}
+	sc.cost = cost
+	return sc
+}
+
+func (s *scheduler) bestVisit(idx int, arrive time.Time) visit {
+	stay := s.stays[idx]
+	windows := s.windows[idx]
+	visitAt := func(start time.Time) visit {
+		v := visit{arrive: arrive, start: start, leave: start.Add(stay), wait: start.Sub(arrive)}
+		var covered, bestOverlap time.Duration
+		for i := range windows {
+			o := overlap(start, v.leave, windows[i].Start, windows[i].End)
+			covered += o
+			if o > bestOverlap {
+				bestOverlap, v.window = o, &windows[i]
+			}
+		}
+		v.missed = max(0, stay-covered)
+		return v
+	}
+	cost := func(v visit) float64 {
+		return waitWeight*v.wait.Minutes() + missWeight*v.missed.Minutes()
+	}
+
+	best := visitAt(arrive)
+	for _, w := range windows {
+		if !w.Start.After(arrive) {
+			continue
+		}
+		if v := visitAt(w.Start); cost(v) < cost(best) {
+			best = v
+		}
+	}
+	return best
+}
+
+// fill 把最优安排写入行程，并生成说明
+func (s *scheduler) fill(plan *Plan, best schedule, indexes []int) {
+	plan.DepartAt = best.departAt
+	if s.startAt == nil {
+		first := best.order[0]
+		w := s.windows[first][0]
+		plan.Reasoning = append(plan.Reasoning, fmt.Sprintf("未指定出发时间，按第一站「%s」的%s开始时间（%s）倒推出发", s.places[first].Name, w.Label, clock(w.Start)))
+	}
+
+	prevStop, prevNode := -1, -1
+	if s.offset == 1 {
+		prevNode = 0
+	}
+	var travel, wait, missed time.Duration
+	for k, idx := range best.order {
+		v := best.visits[k]
+		p := s.places[idx]
+		node := s.offset + idx
+		if prevNode >= 0 {
+			r := s.m[prevNode][node]
+			plan.Legs = append(plan.Legs, Leg{
+				From:      prevStop,
+				To:        k,
+				DistanceM: r.DistanceM,
+				DurationS: r.DurationS,
+				Estimated: r.Estimated,
+				Error:     r.Error,
+				DepartAt:  v.arrive.Add(-v.travel),
+				ArriveAt:  v.arrive,
+			})
+			if r.Estimated {
+				plan.Reasoning = append(plan.Reasoning, fmt.Sprintf("%s → %s 路线规划失败（%s），按直线距离估算约 %d 分钟", s.nodeName(plan, prevStop), p.Name, r.Error, minutes(v.travel)))
+			}
+		}
+		wgs := geo.BD09ToWGS84(*p.BD09)
+		sun := solar.PositionAt(v.start, wgs.Lat, wgs.Lng)
+		stop := Stop{
+			Index:         indexes[idx],
+			Place:         p,
+			Window:        v.window,
+			ArriveAt:      v.arrive,
+			StartAt:       v.start,
+			LeaveAt:       v.leave,
+			WaitMinutes:   minutes(v.wait),
+			MissedMinutes: minutes(v.missed),
+			Sun:           sun,
+			Phase:         solar.PhaseOf(sun.Elevation),
+		}
+		stop.Reason = s.reason(idx, v)
+		plan.Stops = append(plan.Stops, stop)
+		plan.Reasoning = append(plan.Reasoning, stop.Reason)
+
+		travel += v.travel
+		wait += v.wait
+		missed += v.missed
+		prevStop, prevNode = k, node
+	}
+	plan.EndAt = best.visits[len(best.visits)-1].leave
+	plan.TravelMinutes = minutes(travel)
+	plan.WaitMinutes = minutes(wait)
+	plan.MissedMinutes = minutes(missed)
+
+	summary := fmt.Sprintf("共 %d 个地点，比较了 %d 种访问顺序，选出路上耗时、等待与错过偏好时段综合代价最小的一种：路上 %d 分钟，等待 %d 分钟",
+		len(s.places), s.tried, plan.TravelMinutes, plan.WaitMinutes)
+	if plan.MissedMinutes > 0 {
+		summary += fmt.Sprintf("，有 %d 分钟不在偏好时段内", plan.MissedMinutes)
+	}
+	plan.Reasoning = append([]string{summary}, plan.Reasoning...)
+	for _, u := range plan.Unresolved {
+		plan.Reasoning = append(plan.Reasoning, fmt.Sprintf("第 %d 个地点无法解析坐标，未纳入行程：%s", u.Index+1, u.Error))
+	}
+}
+
+func (s *scheduler) nodeName(plan *Plan, stop int) string {
+	if stop < 0 {
+		if plan.Start != nil && plan.Start.Name != "" {
+			return plan.Start.Name
+		}
+		return "出发地"
+	}
+	return plan.Stops[stop].Place.Name
+}
+
+// reason 一站的安排说明
+func (s *scheduler) reason(idx int, v visit) string {
+	p := s.places[idx]
+	var b strings.Builder
+	b.WriteString("「" + p.Name + "」")
+	if len(p.Light) == 0 {
+		b.WriteString("没有时段偏好")
+	} else {
+		labels := make([]string, 0, len(p.Light))
+		for _, l := range p.Light {
+			if label, ok := lightLabels[l]; ok {
+				labels = append(labels, label)
+			}
+		}
+		b.WriteString("偏好" + strings.Join(labels, "、"))
+	}
+	switch {
+	case v.window == nil:
+		b.WriteString(fmt.Sprintf("，%s 到达时不在偏好时段内，直接拍摄", clock(v.arrive)))
+	case v.wait > 0:
+		b.WriteString(fmt.Sprintf("，%s 到达，等待 %d 分钟后在%s（%s-%s）拍摄", clock(v.arrive), minutes(v.wait), v.window.Label, clock(v.window.Start), clock(v.window.End)))
+	default:
+		b.WriteString(fmt.Sprintf("，%s 到达时正值%s（%s-%s）", clock(v.arrive), v.window.Label, clock(v.window.Start), clock(v.window.End)))
+	}
+	if v.window != nil && v.missed > 0 {
+		b.WriteString(fmt.Sprintf("，停留时间有 %d 分钟超出该时段", minutes(v.missed)))
+	}
+	return b.String()
+}
+
+func overlap(aStart, aEnd, bStart, bEnd time.Time) time.Duration {
+	start, end := aStart, aEnd
+	if bStart.After(start) {
+		start = bStart
+	}
+	if bEnd.Before(end) {
+		end = bEnd
+	}
+	if end.After(start) {
+		return end.Sub(start)
+	}
+	return 0
+}
+
+func minutes(d time.Duration) int {
+	return int(math.Round(d.Minutes()))
+}
+
+func clock(t time.Time) string {
+	return t.Format("15:04")
+}
@@ -0,0 +1,224 @@
+package itinerary
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/huangqi/photo-backend/internal/album"
+	"github.com/huangqi/photo-backend/internal/mcp"
+	"github.com/huangqi/photo-backend/internal/solar"
+)
+
+var cst = time.FixedZone("CST", 8*3600)
+
+func hm(hour, minute int) time.Time {
+	return time.Date(2024, 6, 21, hour, minute, 0, 0, cst)
+}
+
+func TestWindowsFor(t *testing.T) {
+	date := hm(0, 0)
+	light := solar.Compute(date, 39.9042, 116.4074)
+	gm, ge := light.GoldenHour.Morning, light.GoldenHour.Evening
+	bm, be := light.BlueHour.Morning, light.BlueHour.Evening
+	noon := light.SolarNoon.Time
+
+	tests := []struct {
+		prefs []string
+		want  []Window
+	}{
+		{nil, []Window{{"daytime", "白天", gm.Start, ge.End}}},
+		{[]string{"unknown"}, []Window{{"daytime", "白天", gm.Start, ge.End}}},
+		{[]string{"sunrise"}, []Window{{"sunrise", "清晨黄金时刻", gm.Start, gm.End}}},
+		// 按开始时间排序
+		{[]string{"sunset", "sunrise"}, []Window{{"sunrise", "清晨黄金时刻", gm.Start, gm.End}, {"sunset", "傍晚黄金时刻", ge.Start, ge.End}}},
+		{[]string{"golden_hour"}, []Window{{"golden_hour", "清晨黄金时刻", gm.Start, gm.End}, {"golden_hour", "傍晚黄金时刻", ge.Start, ge.End}}},
+		{[]string{"blue_hour"}, []Window{{"blue_hour", "清晨蓝调时刻", bm.Start, bm.End}, {"blue_hour", "傍晚蓝调时刻", be.Start, be.End}}},
+		{[]string{"morning", "afternoon"}, []Window{{"morning", "上午", gm.End, noon.Add(-time.Hour)}, {"afternoon", "下午", noon.Add(time.Hour), ge.Start}}},
+		{[]string{"noon"}, []Window{{"noon", "正午", noon.Add(-time.Hour), noon.Add(time.Hour)}}},
+		{[]string{"night"}, []Window{{"night", "夜景", be.End, be.End.Add(3 * time.Hour)}}},
+	}
+	for _, tt := range tests {
+		if got := windowsFor(tt.prefs, light, date); !slices.Equal(got, tt.want) {
+			t.Errorf("windowsFor(%q) = %+v, want %+v", tt.prefs, got, tt.want)
+		}
+	}
+
+	// 极昼时没有蓝调时刻，回落到整个白天
+	polar := solar.Compute(date, 69.6492, 18.9553)
+	got := windowsFor([]string{"blue_hour"}, polar, date)
+	if len(got) != 1 || got[0].Name != "daytime" {
+		t.Errorf("blue hour during midnight sun = %+v, want daytime", got)
+	}
+}
+
+// testScheduler 直接给出各地点的时段与两两耗时（分钟），offset 为 1 时第 0 个点为出发地
+func testScheduler(windows [][]Window, minutes [][]int, offset int, startAt *time.Time) *scheduler {
+	s := &scheduler{windows: windows, offset: offset, startAt: startAt}
+	for i := range windows {
+		s.places = append(s.places, Place{Name: string(rune('A' + i))})
+		s.stays = append(s.stays, 45*time.Minute)
+	}
+	for _, row := range minutes {
+		r := make([]route, len(row))
+		for j, m := range row {
+			r[j] = route{DurationS: m * 60}
+		}
+		s.m = append(s.m, r)
+	}
+	return s
+}
+
+func TestSchedulerBestVisit(t *testing.T) {
+	s := testScheduler([][]Window{
+		{{Name: "sunset", Start: hm(19, 0), End: hm(20, 0)}},
+	}, [][]int{{0}}, 0, nil)
+
+	tests := []struct {
+		name         string
+		arrive       time.Time
+		start        time.Time
+		wait, missed time.Duration
+		inWindow     bool
+	}{
+		{"inside the window", hm(19, 5), hm(19, 5), 0, 0, true},
+		{"overruns the window", hm(19, 30), hm(19, 30), 0, 15 * time.Minute, true},
+		// 等待 30 分钟（代价 6）好过错过 45 分钟（代价 135）
+		{"waits for the light", hm(18, 30), hm(19, 0), 30 * time.Minute, 0, true},
+		// 等待 13 小时的代价超过错过，直接拍摄
+		{"too early to wait", hm(6, 0), hm(6, 0), 0, 45 * time.Minute, false},
+		{"after the window", hm(20, 30), hm(20, 30), 0, 45 * time.Minute, false},
+	}
+	for _, tt := range tests {
+		v := s.bestVisit(0, tt.arrive)
+		if !v.start.Equal(tt.start) || v.wait != tt.wait || v.missed != tt.missed || (v.window != nil) != tt.inWindow || !v.leave.Equal(tt.start.Add(45*time.Minute)) {
+			t.Errorf("%s: visit = start %s, wait %v, missed %v, window %v", tt.name, clock(v.start), v.wait, v.missed, v.window)
+		}
+	}
+}
+
+func TestSchedulerSearch(t *testing.T) {
+	// A 清晨、B 上午、C 白天任意时段，两两之间 30 分钟；先拍 C 再等 B 比先到 B 等得更短
+	windows := [][]Window{
+		{{Name: "sunrise", Start: hm(5, 0), End: hm(6, 0)}},
+		{{Name: "morning", Start: hm(10, 0), End: hm(12, 0)}},
+		{{Name: "daytime", Start: hm(4, 30), End: hm(20, 0)}},
+	}
+	between := [][]int{{0, 30, 30}, {30, 0, 30}, {30, 30, 0}}
+
+	s := testScheduler(windows, between, 0, nil)
+	best := s.search()
+	if !slices.Equal(best.order, []int{0, 2, 1}) {
+		t.Errorf("order = %v, want A, C, B", best.order)
+	}
+	if s.tried != 6 {
+		t.Errorf("tried %d orders, want 6", s.tried)
+	}
+	// 未指定出发时间时按第一站的时段倒推
+	if !best.departAt.Equal(hm(5, 0)) || !best.visits[0].start.Equal(hm(5, 0)) {
+		t.Errorf("depart at %s, first visit at %s; want 05:00", clock(best.departAt), clock(best.visits[0].start))
+	}
+	if v := best.visits[2]; !v.start.Equal(hm(10, 0)) || v.wait != 150*time.Minute || v.missed != 0 {
+		t.Errorf("B visit = start %s, wait %v, missed %v; want to wait for 10:00", clock(v.start), v.wait, v.missed)
+	}
+	if sc := s.simulate([]int{0, 1, 2}); sc.cost <= best.cost {
+		t.Errorf("A, B, C cost %.1f <= best %.1f", sc.cost, best.cost)
+	}
+
+	// 有出发地时，出发时间按出发地到第一站的耗时倒推
+	withStart := [][]int{{0, 20, 90, 40}, {20, 0, 30, 30}, {90, 30, 0, 30}, {40, 30, 30, 0}}
+	s = testScheduler(windows, withStart, 1, nil)
+	best = s.search()
+	if best.order[0] != 0 || !best.departAt.Equal(hm(4, 40)) || best.visits[0].travel != 20*time.Minute {
+		t.Errorf("order %v, depart at %s, first leg %v; want A at 05:00 after 20 minutes", best.order, clock(best.departAt), best.visits[0].travel)
+	}
+
+	// 指定出发时间时从该时刻开始，赶不上清晨的 A 放到最后也只能错过
+	startAt := hm(9, 0)
+	s = testScheduler(windows, withStart, 1, &startAt)
+	best = s.search()
+	if !best.departAt.Equal(startAt) {
+		t.Errorf("depart at %s, want 09:00", clock(best.departAt))
+	}
+	if best.visits[0].arrive.Before(startAt) {
+		t.Errorf("first arrival %s before the start time", clock(best.visits[0].arrive))
+	}
+	var missed time.Duration
+	for _, v := range best.visits {
+		missed += v.missed
+	}
+	if missed != 45*time.Minute {
+		t.Errorf("missed %v, want only A's stay", missed)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	maps := &fakeMaps{
+		places:  map[string]mcp.Place{"uid-palace": place("uid-palace", "故宫角楼", palace)},
+		noRoute: true,
+	}
+	date := hm(0, 0)
+	startAt := hm(16, 0)
+	req := Request{
+		Date: date,
+		Mode: album.ModeWalking,
+		Places: []Place{
+			{UID: "uid-palace", Light: []string{"sunset"}},
+			{Name: "无法解析"},
+			{Name: "景山", BD09: &jingshan, Light: []string{"afternoon"}},
+			{Name: "北海", BD09: &beihai},
+		},
+		StartAt: &startAt,
+	}
+	plan, err := Build(context.Background(), maps, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Date != "2024-06-21" || len(plan.Unresolved) != 1 || plan.Unresolved[0].Index != 1 {
+		t.Errorf("date %s, unresolved %+v", plan.Date, plan.Unresolved)
+	}
+	if len(plan.Stops) != 3 || len(plan.Legs) != 2 {
+		t.Fatalf("%d stops, %d legs", len(plan.Stops), len(plan.Legs))
+	}
+	last := plan.Stops[2]
+	if last.Index != 0 || last.Place.Name != "故宫角楼" || last.Window == nil || last.Window.Name != "sunset" {
+		t.Errorf("last stop = %+v, want the palace at sunset", last)
+	}
+	if !plan.DepartAt.Equal(startAt) || plan.Stops[0].ArriveAt.Before(startAt) {
+		t.Errorf("depart at %s, first arrival %s", clock(plan.DepartAt), clock(plan.Stops[0].ArriveAt))
+	}
+	for _, leg := range plan.Legs {
+		if !leg.Estimated || leg.Error != "route failed" || leg.DurationS == 0 {
+			t.Errorf("leg = %+v, want a straight-line estimate", leg)
+		}
+	}
+	reasoning := strings.Join(plan.Reasoning, "\n")
+	for _, want := range []string{"比较了 6 种访问顺序", "路线规划失败（route failed）", "每两个地点之间只规划了一个方向", "第 2 个地点无法解析坐标"} {
+		if !strings.Contains(reasoning, want) {
+			t.Errorf("reasoning missing %q:\n%s", want, reasoning)
+		}
+	}
+	if strings.Contains(reasoning, "未指定出发时间") {
+		t.Errorf("reasoning mentions a derived start time although StartAt was given")
+	}
+
+	// 不指定出发时间：从第一站的时段开始时间倒推
+	req.StartAt = nil
+	plan, err = Build(context.Background(), maps, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := plan.Stops[0]
+	if first.Window == nil || !first.StartAt.Equal(first.Window.Start) || !plan.DepartAt.Equal(first.ArriveAt) {
+		t.Errorf("first stop = %+v, depart at %s; want to start with its window", first, clock(plan.DepartAt))
+	}
+	if !strings.Contains(strings.Join(plan.Reasoning, "\n"), "未指定出发时间") {
+		t.Errorf("reasoning = %q, want the derived start time explained", plan.Reasoning)
+	}
+
+	if _, err := Build(context.Background(), nil, Request{Date: date, Places: []Place{{Name: "故宫"}}}); err == nil {
+		t.Error("Build without maps or coordinates succeeded")
+	}
+}
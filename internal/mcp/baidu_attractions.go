@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/huangqi/photo-backend/internal/geo"
+)
+
+const (
+	// scenicTag 百度地点检索的景点一级分类
+	scenicTag = "旅游景点"
+	// maxAttractionRadiusM 圆形区域检索的半径上限
+	maxAttractionRadiusM = 50000
+)
+
+// scenicQueries 检索附近景点使用的关键词，结果按 UID 合并
+var scenicQueries = []string{"景点", "公园"}
+
+// baiduAttractionsClient 基于百度地图地点检索实现 MapsClient。
+// 输入输出坐标均为 WGS-84，检索时换算为 BD-09
+type baiduAttractionsClient struct {
+	baidu BaiduMapsClient
+}
+
+// NewBaiduAttractionsClient 用百度地图地点检索查找附近景点
+func NewBaiduAttractionsClient(baidu BaiduMapsClient) MapsClient {
+	return &baiduAttractionsClient{baidu: baidu}
+}
+
+func (c *baiduAttractionsClient) GetNearbyAttractions(ctx context.Context, lat, lng float64, radiusKm float64) ([]Attraction, error) {
+	center := geo.Point{Lat: lat, Lng: lng}
+	if !center.Valid() {
+		return nil, fmt.Errorf("invalid coordinates %f,%f", lat, lng)
+	}
+
+	bd := geo.WGS84ToBD09(center)
+	location := strconv.FormatFloat(bd.Lat, 'f', 6, 64) + "," + strconv.FormatFloat(bd.Lng, 'f', 6, 64)
+	radiusM := min(int(math.Round(radiusKm*1000)), maxAttractionRadiusM)
+	isChina := "true"
+	if geo.OutOfChina(center) {
+		isChina = "false"
+	}
+
+	seen := make(map[string]bool)
+	var items []Attraction
+	var firstErr error
+	succeeded := false
+	for _, query := range scenicQueries {
+		places, err := c.baidu.SearchPlaces(ctx, query, scenicTag, "", location, radiusM, "", isChina)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		succeeded = true
+		for _, p := range places {
+			pt := geo.Point{Lat: p.Location.Lat, Lng: p.Location.Lng}
+			if p.Name == "" || !pt.Valid() || pt.IsZero() {
+				continue
+			}
+			key := p.UID
+			if key == "" {
+				key = p.Name
+			}
+			if seen[key] {
+				continue
+			}
+			wgs := geo.BD09ToWGS84(pt)
+			dist := geo.DistanceKm(center, wgs)
+			if dist > radiusKm {
+				continue
+			}
+			seen[key] = true
+			items = append(items, Attraction{
+				Name:       p.Name,
+				Latitude:   wgs.Lat,
+				Longitude:  wgs.Lng,
+				Address:    p.Address,
+				DistanceKm: math.Round(dist*100) / 100,
+				PlaceID:    p.UID,
+			})
+		}
+	}
+	// 全部关键词都检索失败时才返回错误
+	if !succeeded {
+		return nil, firstErr
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].DistanceKm < items[j].DistanceKm })
+	if items == nil {
+		items = []Attraction{}
+	}
+	return items, nil
+}
+
+// Close 底层百度地图客户端与百度地图接口共用，由创建方关闭
+func (c *baiduAttractionsClient) Close() error {
+	return nil
+}
@@ -0,0 +1,131 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/huangqi/photo-backend/internal/geo"
+)
+
+// fakeSearch 按关键词返回固定结果或错误，并记录每次检索的参数
+type fakeSearch struct {
+	BaiduMapsClient
+
+	results map[string][]Place
+	errs    map[string]error
+	calls   []string
+}
+
+func (f *fakeSearch) SearchPlaces(_ context.Context, query, tag, region, location string, radius int, language, isChina string) ([]Place, error) {
+	f.calls = append(f.calls, fmt.Sprintf("%s|%s|%s|%s|%d|%s", query, tag, region, location, radius, isChina))
+	if err := f.errs[query]; err != nil {
+		return nil, err
+	}
+	return f.results[query], nil
+}
+
+// bdPlace 按 WGS-84 坐标生成百度检索结果（BD-09）
+func bdPlace(uid, name string, wgs geo.Point) Place {
+	bd := geo.WGS84ToBD09(wgs)
+	p := Place{UID: uid, Name: name, Address: name + "地址"}
+	p.Location.Lat, p.Location.Lng = bd.Lat, bd.Lng
+	return p
+}
+
+func TestBaiduAttractions(t *testing.T) {
+	ctx := context.Background()
+	center := geo.Point{Lat: 39.9163, Lng: 116.3972}
+	near := geo.Point{Lat: 39.9253, Lng: 116.3972}   // 约 1 km
+	nearer := geo.Point{Lat: 39.9208, Lng: 116.3972} // 约 0.5 km
+	far := geo.Point{Lat: 40.0963, Lng: 116.3972}    // 约 20 km
+	results := map[string][]Place{
+		"景点": {
+			bdPlace("uid-jingshan", "景山公园", near),
+			bdPlace("", "角楼", nearer),
+			bdPlace("uid-far", "远处景点", far),
+			{UID: "uid-zero", Name: "没有坐标"},
+			bdPlace("uid-noname", "", nearer),
+		},
+		"公园": {
+			bdPlace("uid-jingshan", "景山公园（重复）", near),
+			bdPlace("", "角楼", near),
+			bdPlace("uid-beihai", "北海公园", nearer),
+		},
+	}
+
+	t.Run("queries fan out and merge by uid", func(t *testing.T) {
+		baidu := &fakeSearch{results: results}
+		got, err := NewBaiduAttractionsClient(baidu).GetNearbyAttractions(ctx, center.Lat, center.Lng, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bd := geo.WGS84ToBD09(center)
+		location := fmt.Sprintf("%.6f,%.6f", bd.Lat, bd.Lng)
+		want := []string{"景点|旅游景点||" + location + "|5000|true", "公园|旅游景点||" + location + "|5000|true"}
+		if fmt.Sprint(baidu.calls) != fmt.Sprint(want) {
+			t.Errorf("calls = %q, want %q", baidu.calls, want)
+		}
+
+		// 按距离排序；没有 UID 时按名称合并；无名称、无坐标与超出半径的结果被丢弃
+		names := make([]string, len(got))
+		for i, a := range got {
+			names[i] = a.Name
+		}
+		if fmt.Sprint(names) != "[角楼 北海公园 景山公园]" {
+			t.Fatalf("attractions = %q", names)
+		}
+		a := got[2]
+		if a.PlaceID != "uid-jingshan" || a.Address != "景山公园地址" || a.DistanceKm != 1 || geo.DistanceKm(near, geo.Point{Lat: a.Latitude, Lng: a.Longitude}) > 0.01 {
+			t.Errorf("景山公园 = %+v, want WGS-84 coordinates about 1 km away", a)
+		}
+	})
+
+	t.Run("radius is capped and outside china is flagged", func(t *testing.T) {
+		baidu := &fakeSearch{}
+		got, err := NewBaiduAttractionsClient(baidu).GetNearbyAttractions(ctx, 35.6586, 139.7454, 80)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got == nil || len(got) != 0 {
+			t.Errorf("attractions = %#v, want an empty slice", got)
+		}
+		if len(baidu.calls) != len(scenicQueries) {
+			t.Errorf("calls = %q, want one per query", baidu.calls)
+		}
+		for _, call := range baidu.calls {
+			if !strings.HasSuffix(call, fmt.Sprintf("|%d|false", maxAttractionRadiusM)) {
+				t.Errorf("call %q, want radius %d outside china", call, maxAttractionRadiusM)
+			}
+		}
+	})
+
+	t.Run("one failed query keeps the other results", func(t *testing.T) {
+		baidu := &fakeSearch{results: results, errs: map[string]error{"景点": errors.New("quota exceeded")}}
+		got, err := NewBaiduAttractionsClient(baidu).GetNearbyAttractions(ctx, center.Lat, center.Lng, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 3 || got[0].Name != "北海公园" || got[1].Name != "景山公园（重复）" {
+			t.Errorf("attractions = %+v, want the park results only", got)
+		}
+	})
+
+	t.Run("all queries failed", func(t *testing.T) {
+		first := errors.New("quota exceeded")
+		baidu := &fakeSearch{errs: map[string]error{"景点": first, "公园": errors.New("timeout")}}
+		got, err := NewBaiduAttractionsClient(baidu).GetNearbyAttractions(ctx, center.Lat, center.Lng, 5)
+		if !errors.Is(err, first) || got != nil {
+			t.Errorf("attractions = %+v, err = %v; want the first error", got, err)
+		}
+	})
+
+	t.Run("invalid coordinates", func(t *testing.T) {
+		baidu := &fakeSearch{}
+		if _, err := NewBaiduAttractionsClient(baidu).GetNearbyAttractions(ctx, 91, 0, 5); err == nil || len(baidu.calls) != 0 {
+			t.Errorf("err = %v, calls = %q; want an error before searching", err, baidu.calls)
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 )
 
 // BaiduMapsClient 百度地图客户端接口
@@ -12,6 +13,7 @@ type BaiduMapsClient interface {
 	Geocode(ctx context.Context, address string) (*GeocodeResult, error)
 	ReverseGeocode(ctx context.Context, lat, lng float64) (*ReverseGeocodeResult, error)
 	SearchPlaces(ctx context.Context, query, tag, region, location string, radius int, language, isChina string) ([]Place, error)
+	GetPlaceDetail(ctx context.Context, uid string) (*Place, error)
 	GetDirections(ctx context.Context, origin, destination string, mode string) (*DirectionsResult, error)
 	GetWeather(ctx context.Context, location string, districtID string, isChina string) (*WeatherResult, error)
 	GetIPLocation(ctx context.Context, ip string) (*IPLocationResult, error)
@@ -19,8 +21,17 @@ type BaiduMapsClient interface {
 	Close() error
 }
 
+// 未显式调用 Initialize 时，第一次调用工具前以此身份初始化
+const (
+	baiduMapsClientName    = "photo-backend-server"
+	baiduMapsClientVersion = "1.0.0"
+)
+
 type baiduMapsClient struct {
 	mcp *MCPClient
+
+	initMu      sync.Mutex
+	initialized bool
 }
 
 // NewBaiduMapsClient 创建百度地图客户端
@@ -28,16 +39,34 @@ func NewBaiduMapsClient(mcpClient *MCPClient) BaiduMapsClient {
 	return &baiduMapsClient{mcp: mcpClient}
 }
 
+// Initialize 初始化 MCP 会话。成功后再次调用直接返回，失败时下一次调用（包括工具调用前的自动初始化）重试
 func (c *baiduMapsClient) Initialize(ctx context.Context, name, version string) error {
-	if c.mcp != nil {
-		return c.mcp.Initialize(ctx, name, version)
+	if c.mcp == nil {
+		return nil
 	}
+	c.initMu.Lock()
+	defer c.initMu.Unlock()
+	if c.initialized {
+		return nil
+	}
+	if err := c.mcp.Initialize(ctx, name, version); err != nil {
+		return err
+	}
+	c.initialized = true
 	return nil
 }
 
+// callTool 调用工具，会话尚未初始化时先初始化
+func (c *baiduMapsClient) callTool(ctx context.Context, name string, args map[string]any) (string, error) {
+	if err := c.Initialize(ctx, baiduMapsClientName, baiduMapsClientVersion); err != nil {
+		return "", fmt.Errorf("initialize baidu-maps: %w", err)
+	}
+	return c.mcp.CallTool(ctx, name, args)
+}
+
 // Geocode 地理编码
 func (c *baiduMapsClient) Geocode(ctx context.Context, address string) (*GeocodeResult, error) {
-	result, err := c.mcp.CallTool(ctx, "map_geocode", map[string]any{
+	result, err := c.callTool(ctx, "map_geocode", map[string]any{
 		"address": address,
 	})
 	if err != nil {
@@ -58,7 +87,7 @@ func (c *baiduMapsClient) Geocode(ctx context.Context, address string) (*Geocode
 
 // ReverseGeocode 逆地理编码（兼容 lat/lng 与 latitude/longitude）
 func (c *baiduMapsClient) ReverseGeocode(ctx context.Context, lat, lng float64) (*ReverseGeocodeResult, error) {
-	result, err := c.mcp.CallTool(ctx, "map_reverse_geocode", map[string]any{
+	result, err := c.callTool(ctx, "map_reverse_geocode", map[string]any{
 		"lat":       lat,
 		"lng":       lng,
 		"latitude":  lat,
@@ -67,7 +96,6 @@ func (c *baiduMapsClient) ReverseGeocode(ctx context.Context, lat, lng float64)
 	if err != nil {
 		return nil, err
 	}
-	fmt.Printf("result: %v\n", result)
 
 	var reverseGeocodeResult ReverseGeocodeResult
 	if err := json.Unmarshal([]byte(result), &reverseGeocodeResult); err != nil {
@@ -90,8 +118,7 @@ func (c *baiduMapsClient) SearchPlaces(ctx context.Context, query, tag, region,
 	if radius > 0 {
 		args["radius"] = radius
 	}
-	result, err := c.mcp.CallTool(ctx, "map_search_places", args)
-	fmt.Printf("result: %v\n", result)
+	result, err := c.callTool(ctx, "map_search_places", args)
 	if err != nil {
 		return nil, err
 	}
@@ -114,9 +141,31 @@ func (c *baiduMapsClient) SearchPlaces(ctx context.Context, query, tag, region,
 	return resp.Results, nil
 }
 
+// GetPlaceDetail 按 UID 查询地点详情
+func (c *baiduMapsClient) GetPlaceDetail(ctx context.Context, uid string) (*Place, error) {
+	result, err := c.callTool(ctx, "map_place_details", map[string]any{
+		"uid": uid,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+		Result  Place  `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(result), &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse place detail result: %w", err)
+	}
+	if resp.Status != 0 {
+		return nil, fmt.Errorf("place detail API error: status=%d message=%s", resp.Status, resp.Message)
+	}
+	return &resp.Result, nil
+}
+
 // GetDirections 路线规划
 func (c *baiduMapsClient) GetDirections(ctx context.Context, origin, destination, mode string) (*DirectionsResult, error) {
-	result, err := c.mcp.CallTool(ctx, "map_directions", map[string]any{
+	result, err := c.callTool(ctx, "map_directions", map[string]any{
 		"origin":      origin,
 		"destination": destination,
 		"mode":        mode,
@@ -124,7 +173,6 @@ func (c *baiduMapsClient) GetDirections(ctx context.Context, origin, destination
 	if err != nil {
 		return nil, err
 	}
-	fmt.Printf("result: %v\n", result)
 	var directionsResult DirectionsResult
 	if err := json.Unmarshal([]byte(result), &directionsResult); err != nil {
 		return nil, fmt.Errorf("failed to parse directions result: %w", err)
@@ -150,8 +198,7 @@ func (c *baiduMapsClient) GetWeather(ctx context.Context, location string, distr
 		args["is_china"] = isChina
 	}
 
-	result, err := c.mcp.CallTool(ctx, "map_weather", args)
-	fmt.Printf("result: %v\n", result)
+	result, err := c.callTool(ctx, "map_weather", args)
 	if err != nil {
 		return nil, err
 	}
@@ -174,7 +221,7 @@ func (c *baiduMapsClient) GetWeather(ctx context.Context, location string, distr
 
 // GetIPLocation IP定位
 func (c *baiduMapsClient) GetIPLocation(ctx context.Context, ip string) (*IPLocationResult, error) {
-	result, err := c.mcp.CallTool(ctx, "map_ip_location", map[string]any{
+	result, err := c.callTool(ctx, "map_ip_location", map[string]any{
 		"ip": ip,
 	})
 	if err != nil {
@@ -191,7 +238,7 @@ func (c *baiduMapsClient) GetIPLocation(ctx context.Context, ip string) (*IPLoca
 
 // GetTraffic 路况查询（兼容 road 与 road_name）
 func (c *baiduMapsClient) GetTraffic(ctx context.Context, road, city string) (*TrafficResult, error) {
-	result, err := c.mcp.CallTool(ctx, "map_road_traffic", map[string]any{
+	result, err := c.callTool(ctx, "map_road_traffic", map[string]any{
 		"road":      road,
 		"road_name": road,
 		"city":      city,
@@ -398,24 +445,27 @@ type WeatherResult struct {
 			Date      string `json:"date"`
 			Week      string `json:"week"`
 		} `json:"forecasts"`
-		ForecastHours []struct {
-			Text      string  `json:"text"`
-			TempFc    int     `json:"temp_fc"`
-			WindClass string  `json:"wind_class"`
-			WindDir   string  `json:"wind_dir"`
-			Rh        int     `json:"rh"`
-			Prec1h    float64 `json:"prec_1h"`
-			Clouds    int     `json:"clouds"`
-			WindAngle int     `json:"wind_angle"`
-			Pop       int     `json:"pop"`
-			Uvi       int     `json:"uvi"`
-			Pressure  int     `json:"pressure"`
-			Dpt       int     `json:"dpt"`
-			DataTime  string  `json:"data_time"`
-		} `json:"forecast_hours"`
+		ForecastHours []WeatherForecastHour `json:"forecast_hours"`
 	} `json:"result"`
 }
 
+// WeatherForecastHour 逐小时预报，DataTime 格式为 "2006-01-02 15:04:05"
+type WeatherForecastHour struct {
+	Text      string  `json:"text"`
+	TempFc    int     `json:"temp_fc"`
+	WindClass string  `json:"wind_class"`
+	WindDir   string  `json:"wind_dir"`
+	Rh        int     `json:"rh"`
+	Prec1h    float64 `json:"prec_1h"`
+	Clouds    int     `json:"clouds"`
+	WindAngle int     `json:"wind_angle"`
+	Pop       int     `json:"pop"`
+	Uvi       int     `json:"uvi"`
+	Pressure  int     `json:"pressure"`
+	Dpt       int     `json:"dpt"`
+	DataTime  string  `json:"data_time"`
+}
+
 type IPLocationResult struct {
 	IP        string  `json:"ip"`
 	City      string  `json:"city"`
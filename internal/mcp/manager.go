@@ -28,7 +28,7 @@ func BuildTransportsFromMCPConfig(cfg *appcfg.MCPConfig, httpClient *http.Client
 
 		if srv.Command != "" {
 			// 使用 stdio 传输创建 MCP 客户端
-			client, err := NewStdioMCPClient(srv.Command, srv.Args, srv.Env)
+			client, err := newStdioMCPClient(key, srv.Command, srv.Args, srv.Env)
 			if err != nil {
 				return nil, err
 			}
@@ -51,17 +51,25 @@ func BuildTransportsFromMCPConfig(cfg *appcfg.MCPConfig, httpClient *http.Client
 	return reg, nil
 }
 
-func (r *ClientRegistry) FindByKeyOrName(key string) *MCPClient {
+// FindByKey 按键精确查找，原样匹配不到时再按小写匹配，不做模糊匹配
+func (r *ClientRegistry) FindByKey(key string) *MCPClient {
 	if r == nil {
 		return nil
 	}
 	if t, ok := r.Clients[key]; ok {
 		return t
 	}
-	lower := strings.ToLower(key)
-	if t, ok := r.Clients[lower]; ok {
+	return r.Clients[strings.ToLower(key)]
+}
+
+func (r *ClientRegistry) FindByKeyOrName(key string) *MCPClient {
+	if r == nil {
+		return nil
+	}
+	if t := r.FindByKey(key); t != nil {
 		return t
 	}
+	lower := strings.ToLower(key)
 	// fuzzy contains search on names/keys
 	for k, t := range r.Clients {
 		if strings.Contains(strings.ToLower(k), lower) {
@@ -0,0 +1,33 @@
+package mcp
+
+import "testing"
+
+func TestClientRegistryFind(t *testing.T) {
+	maps, baidu := &MCPClient{}, &MCPClient{}
+	r := &ClientRegistry{Clients: map[string]*MCPClient{"maps": maps, "baidu-maps": baidu}}
+
+	tests := []struct {
+		key         string
+		byKey       *MCPClient
+		byKeyOrName *MCPClient
+	}{
+		{"maps", maps, maps},
+		{"MAPS", maps, maps},
+		{"baidu-maps", baidu, baidu},
+		{"baidu", nil, baidu},
+		{"xhs", nil, nil},
+	}
+	for _, tt := range tests {
+		if got := r.FindByKey(tt.key); got != tt.byKey {
+			t.Errorf("FindByKey(%q) = %p, want %p", tt.key, got, tt.byKey)
+		}
+		if got := r.FindByKeyOrName(tt.key); got != tt.byKeyOrName {
+			t.Errorf("FindByKeyOrName(%q) = %p, want %p", tt.key, got, tt.byKeyOrName)
+		}
+	}
+
+	var nilRegistry *ClientRegistry
+	if nilRegistry.FindByKey("maps") != nil || nilRegistry.FindByKeyOrName("maps") != nil {
+		t.Error("nil registry returned a client")
+	}
+}
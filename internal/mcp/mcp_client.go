@@ -3,6 +3,7 @@ package mcp
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 
 	"github.com/mark3labs/mcp-go/client"
 	"github.com/mark3labs/mcp-go/mcp"
@@ -11,10 +12,16 @@ import (
 // MCPClient 使用官方 mcp-go 库的客户端包装器
 type MCPClient struct {
 	client client.MCPClient
+	notify *notifier
 }
 
 // NewStdioMCPClient 创建基于 stdio 的 MCP 客户端
 func NewStdioMCPClient(command string, args []string, env map[string]string) (*MCPClient, error) {
+	return newStdioMCPClient(filepath.Base(command), command, args, env)
+}
+
+// newStdioMCPClient name 用作通知的来源，需在注册通知回调前确定
+func newStdioMCPClient(name, command string, args []string, env map[string]string) (*MCPClient, error) {
 	var envVars []string
 	for k, v := range env {
 		envVars = append(envVars, fmt.Sprintf("%s=%s", k, v))
@@ -23,7 +30,9 @@ func NewStdioMCPClient(command string, args []string, env map[string]string) (*M
 	if err != nil {
 		return nil, err
 	}
-	return &MCPClient{client: client}, nil
+	c := &MCPClient{client: client, notify: newNotifier(name)}
+	client.OnNotification(c.notify.dispatch)
+	return c, nil
 }
 
 // Initialize 初始化 MCP 客户端
@@ -39,8 +48,15 @@ func (c *MCPClient) Initialize(ctx context.Context, name, version string) error
 		Version: version,
 	}
 
-	_, err := c.client.Initialize(ctx, initRequest)
-	return err
+	result, err := c.client.Initialize(ctx, initRequest)
+	if err != nil {
+		return err
+	}
+	// 服务端支持日志时，默认接收 info 及以上级别的日志通知
+	if result.Capabilities.Logging != nil {
+		_ = c.SetLogLevel(ctx, string(mcp.LoggingLevelInfo))
+	}
+	return nil
 }
 
 // Ping 发送 ping 请求
@@ -94,6 +110,19 @@ func (c *MCPClient) CallTool(ctx context.Context, name string, args map[string]a
 	toolRequest.Params.Name = name
 	toolRequest.Params.Arguments = args
 
+	// 调用方通过 context 订阅通知时，请求进度并只转发本次调用的进度
+	if h := notificationHandlerFromContext(ctx); h != nil && c.notify != nil {
+		token := nextProgressToken()
+		toolRequest.Params.Meta = &mcp.Meta{ProgressToken: token}
+		unsubscribe := c.notify.subscribe(func(n Notification) {
+			if n.Progress != nil && n.Progress.Token != token {
+				return
+			}
+			h(n)
+		})
+		defer unsubscribe()
+	}
+
 	result, err := c.client.CallTool(ctx, toolRequest)
 	if err != nil {
 		return "", err
@@ -111,6 +140,25 @@ func (c *MCPClient) CallTool(ctx context.Context, name string, args map[string]a
 	return "", fmt.Errorf("empty MCP tools/call result")
 }
 
+// Subscribe 订阅该连接上的全部进度与日志通知，返回取消订阅函数
+func (c *MCPClient) Subscribe(h NotificationHandler) func() {
+	if c.notify == nil {
+		return func() {}
+	}
+	return c.notify.subscribe(h)
+}
+
+// SetLogLevel 设置服务端推送日志通知的最低级别（debug/info/warning/error ...）
+func (c *MCPClient) SetLogLevel(ctx context.Context, level string) error {
+	if c.client == nil {
+		return fmt.Errorf("client not initialized")
+	}
+
+	req := mcp.SetLevelRequest{}
+	req.Params.Level = mcp.LoggingLevel(level)
+	return c.client.SetLevel(ctx, req)
+}
+
 // Close 关闭客户端连接
 func (c *MCPClient) Close() error {
 	if c.client != nil {
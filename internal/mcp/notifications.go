@@ -0,0 +1,131 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// 通知类型
+const (
+	NotificationProgress = "progress"
+	NotificationLog      = "log"
+)
+
+// ProgressEvent 工具调用的进度通知（notifications/progress）
+type ProgressEvent struct {
+	Token    string  `json:"token"`
+	Progress float64 `json:"progress"`
+	Total    float64 `json:"total,omitempty"`
+	Message  string  `json:"message,omitempty"`
+}
+
+// LogEvent 服务端日志通知（notifications/message）
+type LogEvent struct {
+	Level  string `json:"level"`
+	Logger string `json:"logger,omitempty"`
+	Data   any    `json:"data"`
+}
+
+// Notification 对外暴露的通知，Progress 与 Log 二选一
+type Notification struct {
+	Type     string         `json:"type"`
+	Progress *ProgressEvent `json:"progress,omitempty"`
+	Log      *LogEvent      `json:"log,omitempty"`
+}
+
+// NotificationHandler 通知回调，需尽快返回，不要阻塞 transport 读循环
+type NotificationHandler func(Notification)
+
+// notifier 管理一个 MCP 连接上的通知订阅
+type notifier struct {
+	name      string
+	mu        sync.RWMutex
+	nextID    int
+	listeners map[int]NotificationHandler
+}
+
+var progressSeq atomic.Int64
+
+func newNotifier(name string) *notifier {
+	return &notifier{name: name, listeners: make(map[int]NotificationHandler)}
+}
+
+func (n *notifier) subscribe(h NotificationHandler) func() {
+	n.mu.Lock()
+	id := n.nextID
+	n.nextID++
+	n.listeners[id] = h
+	n.mu.Unlock()
+	return func() {
+		n.mu.Lock()
+		delete(n.listeners, id)
+		n.mu.Unlock()
+	}
+}
+
+// dispatch 解析原始 JSON-RPC 通知，写日志并分发给订阅者
+func (n *notifier) dispatch(raw mcp.JSONRPCNotification) {
+	var note Notification
+	params := raw.Params.AdditionalFields
+	switch raw.Method {
+	case "notifications/progress":
+		ev := &ProgressEvent{
+			Token:   fmt.Sprint(params["progressToken"]),
+			Message: stringField(params, "message"),
+		}
+		ev.Progress, _ = params["progress"].(float64)
+		ev.Total, _ = params["total"].(float64)
+		note = Notification{Type: NotificationProgress, Progress: ev}
+		log.Printf("mcp progress: server=%s token=%s progress=%v total=%v message=%q",
+			n.name, ev.Token, ev.Progress, ev.Total, ev.Message)
+	case "notifications/message":
+		ev := &LogEvent{
+			Level:  stringField(params, "level"),
+			Logger: stringField(params, "logger"),
+			Data:   params["data"],
+		}
+		note = Notification{Type: NotificationLog, Log: ev}
+		log.Printf("mcp log: server=%s level=%s logger=%s data=%v", n.name, ev.Level, ev.Logger, ev.Data)
+	default:
+		return
+	}
+
+	n.mu.RLock()
+	handlers := make([]NotificationHandler, 0, len(n.listeners))
+	for _, h := range n.listeners {
+		handlers = append(handlers, h)
+	}
+	n.mu.RUnlock()
+	for _, h := range handlers {
+		h(note)
+	}
+}
+
+func stringField(m map[string]any, key string) string {
+	if v, ok := m[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+func nextProgressToken() string {
+	return fmt.Sprintf("photo-backend-%d", progressSeq.Add(1))
+}
+
+type notificationHandlerKey struct{}
+
+// WithNotificationHandler 返回携带通知回调的 context。
+// 使用该 context 发起的 CallTool 会请求进度通知，并把本次调用的进度与期间的服务端日志回调给 h。
+func WithNotificationHandler(ctx context.Context, h NotificationHandler) context.Context {
+	return context.WithValue(ctx, notificationHandlerKey{}, h)
+}
+
+func notificationHandlerFromContext(ctx context.Context) NotificationHandler {
+	h, _ := ctx.Value(notificationHandlerKey{}).(NotificationHandler)
+	return h
+}
@@ -0,0 +1,184 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimitConfig XHS 工具调用的限流参数，速率为 0 表示不限制对应维度
+type RateLimitConfig struct {
+	// GlobalPerMinute / GlobalBurst 所有工具调用共享的令牌桶
+	GlobalPerMinute float64
+	GlobalBurst     int
+	// KeywordPerMinute / KeywordBurst 同一搜索关键词的令牌桶
+	KeywordPerMinute float64
+	KeywordBurst     int
+	// MinSpacing 相邻两次调用的最小间隔
+	MinSpacing time.Duration
+	// MaxWait 排队等待的上限，超过则直接返回 RateLimitError
+	MaxWait time.Duration
+}
+
+// RateLimitError 预算耗尽，RetryAfter 为建议的重试等待时间
+type RateLimitError struct {
+	Scope      string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("xhs rate limit exceeded (%s), retry after %s", e.Scope, e.RetryAfter.Round(time.Second))
+}
+
+// maxKeywordBuckets 关键词桶数量超过该值时清理已回满的桶
+const maxKeywordBuckets = 1024
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	rate   float64 // 每秒补充的令牌数
+	burst  float64
+}
+
+func newTokenBucket(perMinute float64, burst int, now time.Time) *tokenBucket {
+	if burst <= 0 {
+		burst = 1
+	}
+	return &tokenBucket{tokens: float64(burst), last: now, rate: perMinute / 60, burst: float64(burst)}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if now.After(b.last) {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+}
+
+// delay 取得一个令牌需要等待的时间
+func (b *tokenBucket) delay(now time.Time) time.Duration {
+	b.refill(now)
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// rateLimiter 以预约方式排队：计算出可执行时间后立即扣减令牌（可为负），再在锁外等待；
+// 等待期间 ctx 结束时归还预约
+type rateLimiter struct {
+	cfg RateLimitConfig
+	// now / sleep 可在测试中替换；sleep 在 ctx 结束时返回 ctx.Err()
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+
+	mu       sync.Mutex
+	global   *tokenBucket
+	keywords map[string]*tokenBucket
+	nextSlot time.Time
+}
+
+func newRateLimiter(cfg RateLimitConfig) *rateLimiter {
+	l := &rateLimiter{cfg: cfg, now: time.Now, sleep: sleepContext, keywords: make(map[string]*tokenBucket)}
+	if cfg.GlobalPerMinute > 0 {
+		l.global = newTokenBucket(cfg.GlobalPerMinute, cfg.GlobalBurst, l.now())
+	}
+	return l
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Wait 等待一次调用的配额；keyword 为空时只检查全局限制
+func (l *rateLimiter) Wait(ctx context.Context, keyword string) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := l.now()
+
+	var kb *tokenBucket
+	if keyword != "" && l.cfg.KeywordPerMinute > 0 {
+		kb = l.keywords[keyword]
+		if kb == nil {
+			l.pruneKeywords(now)
+			kb = newTokenBucket(l.cfg.KeywordPerMinute, l.cfg.KeywordBurst, now)
+			l.keywords[keyword] = kb
+		}
+	}
+
+	wait, scope := time.Duration(0), ""
+	if l.global != nil {
+		if d := l.global.delay(now); d > wait {
+			wait, scope = d, "global"
+		}
+	}
+	if kb != nil {
+		if d := kb.delay(now); d > wait {
+			wait, scope = d, "keyword"
+		}
+	}
+	if d := l.nextSlot.Sub(now); d > wait {
+		wait, scope = d, "spacing"
+	}
+	if l.cfg.MaxWait >= 0 && wait > l.cfg.MaxWait {
+		l.mu.Unlock()
+		return &RateLimitError{Scope: scope, RetryAfter: wait}
+	}
+
+	if l.global != nil {
+		l.global.tokens--
+	}
+	if kb != nil {
+		kb.tokens--
+	}
+	prevSlot := l.nextSlot
+	slot := now.Add(wait + l.cfg.MinSpacing)
+	l.nextSlot = slot
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	if err := l.sleep(ctx, wait); err != nil {
+		l.release(kb, prevSlot, slot)
+		return err
+	}
+	return nil
+}
+
+// release 归还未使用的预约。之后已有调用排在本次预约之后时，时间槽无法收回，只归还令牌
+func (l *rateLimiter) release(kb *tokenBucket, prevSlot, slot time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	for _, b := range []*tokenBucket{l.global, kb} {
+		if b != nil {
+			b.refill(now)
+			b.tokens = min(b.burst, b.tokens+1)
+		}
+	}
+	if l.nextSlot.Equal(slot) {
+		l.nextSlot = prevSlot
+	}
+}
+
+func (l *rateLimiter) pruneKeywords(now time.Time) {
+	if len(l.keywords) < maxKeywordBuckets {
+		return
+	}
+	for k, b := range l.keywords {
+		b.refill(now)
+		if b.tokens >= b.burst {
+			delete(l.keywords, k)
+		}
+	}
+}
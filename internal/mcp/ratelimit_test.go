@@ -0,0 +1,171 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeClock sleep 直接推进时间；ctx 已结束时不推进并返回 ctx.Err()
+type fakeClock struct {
+	t      time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) sleep(ctx context.Context, d time.Duration) error {
+	c.sleeps = append(c.sleeps, d)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.t = c.t.Add(d)
+	return nil
+}
+
+func newTestLimiter(cfg RateLimitConfig) (*rateLimiter, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2025, 9, 1, 10, 0, 0, 0, time.UTC)}
+	l := newRateLimiter(cfg)
+	l.now, l.sleep = clock.now, clock.sleep
+	if l.global != nil {
+		l.global.last = clock.t
+	}
+	return l, clock
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	type call struct {
+		keyword string
+		cancel  bool
+		// advance 调用前推进的时间
+		advance time.Duration
+		// wantScope 非空时期望 RateLimitError
+		wantScope string
+		wantRetry time.Duration
+		// wantSleep 本次调用的等待时间
+		wantSleep time.Duration
+	}
+	tests := []struct {
+		name  string
+		cfg   RateLimitConfig
+		calls []call
+	}{
+		{
+			name: "global burst then refill",
+			cfg:  RateLimitConfig{GlobalPerMinute: 60, GlobalBurst: 3},
+			calls: []call{
+				{}, {}, {},
+				{wantScope: "global", wantRetry: time.Second},
+				{advance: time.Second},
+			},
+		},
+		{
+			name: "per keyword limit",
+			cfg:  RateLimitConfig{KeywordPerMinute: 6, KeywordBurst: 1},
+			calls: []call{
+				{keyword: "故宫"},
+				{keyword: "故宫", wantScope: "keyword", wantRetry: 10 * time.Second},
+				{keyword: "天坛"},
+				{keyword: ""},
+				{keyword: "故宫", advance: 10 * time.Second},
+			},
+		},
+		{
+			name: "min spacing queues callers",
+			cfg:  RateLimitConfig{MinSpacing: 2 * time.Second, MaxWait: time.Minute},
+			calls: []call{
+				{},
+				{wantSleep: 2 * time.Second},
+				{wantSleep: 2 * time.Second},
+				{advance: 5 * time.Second},
+			},
+		},
+		{
+			name: "max wait",
+			cfg:  RateLimitConfig{GlobalPerMinute: 6, GlobalBurst: 1, MinSpacing: 5 * time.Second, MaxWait: 5 * time.Second},
+			calls: []call{
+				{},
+				{advance: 4 * time.Second, wantScope: "global", wantRetry: 6 * time.Second},
+				{advance: time.Second, wantSleep: 5 * time.Second},
+			},
+		},
+		{
+			name: "cancelled wait returns the tokens",
+			cfg:  RateLimitConfig{GlobalPerMinute: 60, GlobalBurst: 1, KeywordPerMinute: 6, KeywordBurst: 1, MaxWait: time.Minute},
+			calls: []call{
+				{keyword: "故宫"},
+				{keyword: "天坛", cancel: true, wantSleep: time.Second},
+				// 没有归还时全局桶为 -1，需要等 2s
+				{keyword: "天坛", wantSleep: time.Second},
+			},
+		},
+		{
+			name: "cancelled wait gives back the slot",
+			cfg:  RateLimitConfig{MinSpacing: 3 * time.Second, MaxWait: time.Minute},
+			calls: []call{
+				{},
+				{cancel: true, wantSleep: 3 * time.Second},
+				{wantSleep: 3 * time.Second},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, clock := newTestLimiter(tt.cfg)
+			for i, c := range tt.calls {
+				clock.t = clock.t.Add(c.advance)
+				ctx, cancel := context.WithCancel(context.Background())
+				if c.cancel {
+					cancel()
+				}
+				before := len(clock.sleeps)
+				err := l.Wait(ctx, c.keyword)
+				cancel()
+
+				var rl *RateLimitError
+				switch {
+				case c.wantScope != "":
+					if !errors.As(err, &rl) || rl.Scope != c.wantScope || rl.RetryAfter.Round(time.Millisecond) != c.wantRetry {
+						t.Fatalf("call %d: err = %v, want %s limit retry after %s", i, err, c.wantScope, c.wantRetry)
+					}
+				case c.cancel:
+					if !errors.Is(err, context.Canceled) {
+						t.Fatalf("call %d: err = %v, want context.Canceled", i, err)
+					}
+				case err != nil:
+					t.Fatalf("call %d: unexpected err %v", i, err)
+				}
+
+				var slept time.Duration
+				if len(clock.sleeps) > before {
+					slept = clock.sleeps[before]
+				}
+				if slept.Round(time.Millisecond) != c.wantSleep {
+					t.Errorf("call %d: waited %s, want %s", i, slept, c.wantSleep)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterReleaseKeepsLaterReservations(t *testing.T) {
+	l, clock := newTestLimiter(RateLimitConfig{MinSpacing: 3 * time.Second, MaxWait: time.Minute})
+	if err := l.Wait(context.Background(), ""); err != nil {
+		t.Fatal(err)
+	}
+	// 第二个调用预约 t0+3 的时间槽；在它放弃之前，第三个调用已排到 t0+6
+	prev, slot := l.nextSlot, clock.t.Add(6*time.Second)
+	l.nextSlot = clock.t.Add(9 * time.Second)
+	l.release(nil, prev, slot)
+	if !l.nextSlot.Equal(clock.t.Add(9 * time.Second)) {
+		t.Errorf("nextSlot moved back to %v although a later caller holds it", l.nextSlot)
+	}
+}
+
+func TestNilRateLimiter(t *testing.T) {
+	var l *rateLimiter
+	if err := l.Wait(context.Background(), "x"); err != nil {
+		t.Errorf("nil limiter returned %v", err)
+	}
+}
@@ -3,29 +3,70 @@ package mcp
 import (
 	"context"
 	"fmt"
-	"regexp"
+	"log"
+	"net/url"
 	"strings"
 )
 
 type XHSPost struct {
-	ID      string `json:"id"`
-	Title   string `json:"title"`
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	Author    string `json:"author"`
+	Likes     int    `json:"likes"`
+	Excerpt   string `json:"excerpt"`
+	PostURL   string `json:"post_url"`
+	XsecToken string `json:"xsec_token,omitempty"`
+	CoverURL  string `json:"cover_url,omitempty"`
+}
+
+// XHSNote 笔记详情（get_note_content）
+type XHSNote struct {
+	ID          string   `json:"id"`
+	XsecToken   string   `json:"xsec_token,omitempty"`
+	URL         string   `json:"url"`
+	Title       string   `json:"title"`
+	Content     string   `json:"content"`
+	Tags        []string `json:"tags"`
+	ImageURLs   []string `json:"image_urls"`
+	Author      string   `json:"author"`
+	PublishTime string   `json:"publish_time,omitempty"`
+	Likes       int      `json:"likes"`
+	Collects    int      `json:"collects"`
+	Comments    int      `json:"comments"`
+	Shares      int      `json:"shares"`
+}
+
+// XHSComment 笔记评论
+type XHSComment struct {
 	Author  string `json:"author"`
+	Content string `json:"content"`
 	Likes   int    `json:"likes"`
-	Excerpt string `json:"excerpt"`
-	PostURL string `json:"post_url"`
+	Time    string `json:"time,omitempty"`
+}
+
+// XHSCommentPage 分页后的评论列表。get_note_comments 一次返回已加载的全部评论，分页在本地完成
+type XHSCommentPage struct {
+	Comments []XHSComment `json:"comments"`
+	Page     int          `json:"page"`
+	PageSize int          `json:"page_size"`
+	Total    int          `json:"total"`
+	HasMore  bool         `json:"has_more"`
 }
 
 type XHSClient interface {
 	Initialize(ctx context.Context, name, version string) error
 	GetHotPosts(ctx context.Context, limit int) ([]XHSPost, error)
 	GetPostsByKeyword(ctx context.Context, keyword string, limit int) ([]XHSPost, error)
+	GetNoteContent(ctx context.Context, noteRef string) (*XHSNote, error)
+	GetNoteComments(ctx context.Context, noteRef string, page, pageSize int) (*XHSCommentPage, error)
+	PostComment(ctx context.Context, noteRef, content string) (string, error)
 	CallTool(ctx context.Context, name string, args map[string]any) (string, error)
 	Close() error
 }
 
 type xhsClient struct {
-	mcp *MCPClient
+	mcp     *MCPClient
+	limiter *rateLimiter
 }
 
 // NewXHSClient 直接使用 MCP 客户端创建 XHS 客户端
@@ -33,6 +74,12 @@ func NewXHSClient(mcpClient *MCPClient) XHSClient {
 	return &xhsClient{mcp: mcpClient}
 }
 
+// NewRateLimitedXHSClient 创建带限流的 XHS 客户端：搜索同时受全局与关键词限制，
+// 笔记详情、评论等其他工具只受全局限制，login 不限流（由 XHSSession 串行化）
+func NewRateLimitedXHSClient(mcpClient *MCPClient, cfg RateLimitConfig) XHSClient {
+	return &xhsClient{mcp: mcpClient, limiter: newRateLimiter(cfg)}
+}
+
 func (c *xhsClient) Initialize(ctx context.Context, name, version string) error {
 	if c.mcp != nil {
 		return c.mcp.Initialize(ctx, name, version)
@@ -45,6 +92,9 @@ func (c *xhsClient) GetHotPosts(ctx context.Context, limit int) ([]XHSPost, erro
 }
 
 func (c *xhsClient) GetPostsByKeyword(ctx context.Context, keyword string, limit int) ([]XHSPost, error) {
+	if err := c.limiter.Wait(ctx, strings.TrimSpace(keyword)); err != nil {
+		return nil, err
+	}
 	out, err := c.mcp.CallTool(ctx, "search_notes", map[string]any{
 		"keywords": keyword,
 		"limit":    limit,
@@ -52,7 +102,133 @@ func (c *xhsClient) GetPostsByKeyword(ctx context.Context, keyword string, limit
 	if err != nil {
 		return nil, err
 	}
-	return parseSearchNotesOutput(out), nil
+	posts, unparsed := parseSearchNotesOutput(out)
+	if len(posts) == 0 && containsAny(out, xhsLoginRequiredMarkers) {
+		return nil, ErrXHSLoginRequired
+	}
+	if len(unparsed) > 0 {
+		log.Printf("xhs search_notes: %d unparsed lines for %q, first: %q", len(unparsed), keyword, unparsed[0])
+	}
+	return posts, nil
+}
+
+// XHSNoteURL 由笔记 ID 与可选的 xsec_token 拼出笔记链接
+func XHSNoteURL(id, xsecToken string) string {
+	u := "https://www.xiaohongshu.com/explore/" + url.PathEscape(id)
+	if xsecToken != "" {
+		u += "?xsec_token=" + url.QueryEscape(xsecToken) + "&xsec_source=pc_search"
+	}
+	return u
+}
+
+// GetNoteContent 获取笔记详情，noteRef 可以是笔记链接或笔记 ID
+func (c *xhsClient) GetNoteContent(ctx context.Context, noteRef string) (*XHSNote, error) {
+	if err := c.limiter.Wait(ctx, ""); err != nil {
+		return nil, err
+	}
+	noteURL := noteURLFromRef(noteRef)
+	out, err := c.mcp.CallTool(ctx, "get_note_content", map[string]any{
+		"url": noteURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	note, unparsed := parseNoteContentOutput(out)
+	if note.Title == "" && note.Content == "" {
+		if containsAny(out, xhsLoginRequiredMarkers) {
+			return nil, ErrXHSLoginRequired
+		}
+		return nil, fmt.Errorf("failed to parse note content; raw response: %s", truncateRunes(out, 200))
+	}
+	if len(unparsed) > 0 {
+		log.Printf("xhs get_note_content: %d unparsed lines for %s, first: %q", len(unparsed), noteURL, unparsed[0])
+	}
+	if note.URL == "" {
+		note.URL = noteURL
+	}
+	id, token := ParseXHSNoteURL(noteURL)
+	if note.ID == "" {
+		note.ID = id
+	}
+	if note.XsecToken == "" {
+		note.XsecToken = token
+	}
+	return note, nil
+}
+
+// truncateRunes 按字符截断到 n 个字符，不会切断多字节字符
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "..."
+}
+
+// noteURLFromRef 笔记 ID 转换为链接，链接原样返回
+func noteURLFromRef(noteRef string) string {
+	ref := strings.TrimSpace(noteRef)
+	if reNoteID.MatchString(ref) {
+		return XHSNoteURL(ref, "")
+	}
+	return ref
+}
+
+// GetNoteComments 获取笔记评论，page 从 1 开始
+func (c *xhsClient) GetNoteComments(ctx context.Context, noteRef string, page, pageSize int) (*XHSCommentPage, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+	if err := c.limiter.Wait(ctx, ""); err != nil {
+		return nil, err
+	}
+	noteURL := noteURLFromRef(noteRef)
+	out, err := c.mcp.CallTool(ctx, "get_note_comments", map[string]any{
+		"url": noteURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	comments, unparsed := parseNoteCommentsOutput(out)
+	if len(comments) == 0 && containsAny(out, xhsLoginRequiredMarkers) {
+		return nil, ErrXHSLoginRequired
+	}
+	if len(unparsed) > 0 {
+		log.Printf("xhs get_note_comments: %d unparsed lines for %s, first: %q", len(unparsed), noteURL, unparsed[0])
+	}
+
+	result := &XHSCommentPage{Page: page, PageSize: pageSize, Total: len(comments), Comments: []XHSComment{}}
+	start := (page - 1) * pageSize
+	if start < len(comments) {
+		end := min(start+pageSize, len(comments))
+		result.Comments = comments[start:end]
+		result.HasMore = end < len(comments)
+	}
+	return result, nil
+}
+
+// PostComment 在笔记下发表评论，返回工具的原始结果文本
+func (c *xhsClient) PostComment(ctx context.Context, noteRef, content string) (string, error) {
+	if err := c.limiter.Wait(ctx, ""); err != nil {
+		return "", err
+	}
+	out, err := c.mcp.CallTool(ctx, "post_comment", map[string]any{
+		"url":     noteURLFromRef(noteRef),
+		"comment": content,
+	})
+	if err != nil {
+		return "", err
+	}
+	if containsAny(out, xhsLoginRequiredMarkers) {
+		return "", ErrXHSLoginRequired
+	}
+	if containsAny(out, []string{"失败", "错误"}) {
+		return "", fmt.Errorf("xhs post comment failed: %s", strings.TrimSpace(out))
+	}
+	return out, nil
 }
 
 // CallTool 直接调用 MCP 工具
@@ -69,21 +245,3 @@ func (c *xhsClient) Close() error {
 	}
 	return nil
 }
-
-var (
-	reItem = regexp.MustCompile(`(?m)^\s*\d+\.\s*(.+?)\s*\n\s*链接:\s*(\S+)\s*$`)
-)
-
-func parseSearchNotesOutput(text string) []XHSPost {
-	var posts []XHSPost
-	matches := reItem.FindAllStringSubmatch(text, -1)
-	for _, m := range matches {
-		if len(m) >= 3 {
-			posts = append(posts, XHSPost{
-				Title:   strings.TrimSpace(m[1]),
-				PostURL: strings.TrimSpace(m[2]),
-			})
-		}
-	}
-	return posts
-}
@@ -0,0 +1,410 @@
+package mcp
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// 条目标题行：1. 标题 / 1、标题 / 1) 标题
+	reItemTitle = regexp.MustCompile(`^\s*(\d+)\s*[.、)）]\s*(.+?)\s*$`)
+	// 字段行：键: 值（兼容全角冒号）
+	reItemField = regexp.MustCompile(`^\s*[-*•]?\s*([^:：]{1,12}?)\s*[:：]\s*(.*?)\s*$`)
+	// 笔记 ID 为 24 位十六进制
+	reNoteID = regexp.MustCompile(`^[0-9a-fA-F]{24}$`)
+	reURL    = regexp.MustCompile(`https?://\S+`)
+)
+
+// xhsFieldAliases 字段名到 XHSPost 字段的映射
+var xhsFieldAliases = map[string]string{
+	"链接":      "url",
+	"笔记链接":    "url",
+	"url":     "url",
+	"link":    "url",
+	"作者":      "author",
+	"博主":      "author",
+	"用户":      "author",
+	"author":  "author",
+	"点赞":      "likes",
+	"点赞数":     "likes",
+	"赞":       "likes",
+	"likes":   "likes",
+	"封面":      "cover",
+	"封面图":     "cover",
+	"cover":   "cover",
+	"摘要":      "excerpt",
+	"内容":      "excerpt",
+	"描述":      "excerpt",
+	"简介":      "excerpt",
+	"excerpt": "excerpt",
+	"id":      "id",
+	"笔记id":    "id",
+}
+
+// xhsIgnoredLines 工具输出中的提示性文本，不计入未解析行
+var xhsIgnoredLines = []string{"搜索结果", "共找到", "以下是"}
+
+// parseSearchNotesOutput 解析 search_notes 的文本输出，返回帖子与无法识别的行。
+// 编号行只有在缩进不深于第一个条目、且序号与上一条目连续时才开始新条目，
+// 摘要里的编号列表会作为摘要的后续行。
+func parseSearchNotesOutput(text string) ([]XHSPost, []string) {
+	var (
+		posts     []XHSPost
+		unparsed  []string
+		cur       *XHSPost
+		indent    int // 第一个条目标题行的缩进
+		nextNum   int // 下一个条目的序号
+		inExcerpt bool
+	)
+	flush := func() {
+		if cur == nil {
+			return
+		}
+		finalizeXHSPost(cur)
+		if cur.Title != "" || cur.PostURL != "" {
+			posts = append(posts, *cur)
+		}
+		cur = nil
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if m := reItemTitle.FindStringSubmatch(line); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			lineIndent := len(line) - len(strings.TrimLeft(line, " \t"))
+			if cur == nil || (lineIndent <= indent && n == nextNum) {
+				if cur == nil {
+					indent = lineIndent
+				}
+				flush()
+				cur = &XHSPost{Title: m[2]}
+				nextNum = n + 1
+				inExcerpt = false
+				continue
+			}
+		}
+		if cur != nil {
+			if m := reItemField.FindStringSubmatch(line); m != nil {
+				if field, ok := xhsFieldAliases[strings.ToLower(m[1])]; ok {
+					applyXHSField(cur, field, m[2])
+					inExcerpt = field == "excerpt"
+					continue
+				}
+			}
+			// 单独一行的链接
+			if cur.PostURL == "" && reURL.MatchString(trimmed) {
+				cur.PostURL = reURL.FindString(trimmed)
+				inExcerpt = false
+				continue
+			}
+			// 多行摘要
+			if inExcerpt {
+				cur.Excerpt = strings.TrimSpace(cur.Excerpt + "\n" + trimmed)
+				continue
+			}
+		}
+		if !containsAny(trimmed, xhsIgnoredLines) {
+			unparsed = append(unparsed, trimmed)
+		}
+	}
+	flush()
+	return posts, unparsed
+}
+
+func applyXHSField(p *XHSPost, field, value string) {
+	switch field {
+	case "url":
+		if u := reURL.FindString(value); u != "" {
+			p.PostURL = u
+		}
+	case "author":
+		p.Author = value
+	case "likes":
+		if n, ok := parseXHSCount(value); ok {
+			p.Likes = n
+		}
+	case "cover":
+		if u := reURL.FindString(value); u != "" {
+			p.CoverURL = u
+		}
+	case "excerpt":
+		p.Excerpt = value
+	case "id":
+		if reNoteID.MatchString(value) {
+			p.ID = value
+		}
+	}
+}
+
+// finalizeXHSPost 从链接中补全 ID 与 xsec_token
+func finalizeXHSPost(p *XHSPost) {
+	p.PostURL = strings.Trim(p.PostURL, `"',`)
+	id, token := ParseXHSNoteURL(p.PostURL)
+	if p.ID == "" {
+		p.ID = id
+	}
+	if p.XsecToken == "" {
+		p.XsecToken = token
+	}
+}
+
+// IsXHSNoteID 判断是否为笔记 ID（24 位十六进制）
+func IsXHSNoteID(s string) bool {
+	return reNoteID.MatchString(s)
+}
+
+// ParseXHSNoteURL 从笔记链接中提取笔记 ID 与 xsec_token。
+// 支持 /explore/{id}、/search_result/{id}、/discovery/item/{id} 等路径。
+func ParseXHSNoteURL(raw string) (id, xsecToken string) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", ""
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		if reNoteID.MatchString(segments[i]) {
+			id = segments[i]
+			break
+		}
+	}
+	return id, u.Query().Get("xsec_token")
+}
+
+// parseXHSCount 解析互动数，兼容 "1234"、"1,234"、"1.2万"、"3.5w"、"2千"、"1.1k"、"10万+"
+func parseXHSCount(s string) (int, bool) {
+	s = strings.TrimSpace(strings.ReplaceAll(s, ",", ""))
+	s = strings.TrimSuffix(s, "+")
+	if s == "" {
+		return 0, false
+	}
+	mult := 1.0
+	lower := strings.ToLower(s)
+	for suffix, m := range map[string]float64{"万": 1e4, "w": 1e4, "千": 1e3, "k": 1e3, "亿": 1e8} {
+		if strings.HasSuffix(lower, suffix) {
+			mult = m
+			lower = strings.TrimSuffix(lower, suffix)
+			break
+		}
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(lower), 64)
+	if err != nil || f < 0 {
+		return 0, false
+	}
+	return int(f*mult + 0.5), true
+}
+
+var (
+	reHashTag   = regexp.MustCompile(`#([^#\s\[\]]+?)(?:\[话题\])?#?(?:\s|$)`)
+	reNoteCount = regexp.MustCompile(`(点赞|收藏|评论|分享)\s*[:：]?\s*([\d.,]+\s*[万千wWkK亿]?\+?)`)
+)
+
+// noteFieldAliases get_note_content 输出的字段名映射
+var noteFieldAliases = map[string]string{
+	"标题":      "title",
+	"title":   "title",
+	"作者":      "author",
+	"博主":      "author",
+	"author":  "author",
+	"发布时间":    "published",
+	"时间":      "published",
+	"日期":      "published",
+	"链接":      "url",
+	"url":     "url",
+	"内容":      "content",
+	"正文":      "content",
+	"content": "content",
+	"标签":      "tags",
+	"话题":      "tags",
+	"tags":    "tags",
+	"图片":      "images",
+	"图片链接":    "images",
+	"images":  "images",
+	"点赞":      "likes",
+	"收藏":      "collects",
+	"评论":      "comments",
+	"评论数":     "comments",
+	"分享":      "shares",
+	"互动":      "counts",
+	"互动数据":    "counts",
+}
+
+// parseNoteContentOutput 解析 get_note_content 的文本输出。
+// "内容" 字段可以跨多行，直到遇到下一个已知字段为止。
+func parseNoteContentOutput(text string) (*XHSNote, []string) {
+	note := &XHSNote{}
+	var (
+		unparsed []string
+		body     []string
+		inBody   bool
+	)
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if m := reItemField.FindStringSubmatch(line); m != nil {
+			if field, ok := noteFieldAliases[strings.ToLower(m[1])]; ok {
+				inBody = field == "content"
+				if inBody {
+					if m[2] != "" {
+						body = append(body, m[2])
+					}
+					continue
+				}
+				applyNoteField(note, field, m[2])
+				continue
+			}
+		}
+		switch {
+		case inBody:
+			body = append(body, strings.TrimRight(line, " \t"))
+		case trimmed == "":
+		case reURL.MatchString(trimmed) && isXHSImageURL(trimmed):
+			note.ImageURLs = append(note.ImageURLs, reURL.FindString(trimmed))
+		case strings.HasPrefix(trimmed, "#"):
+			note.Tags = appendUnique(note.Tags, extractHashTags(trimmed)...)
+		default:
+			if !containsAny(trimmed, []string{"笔记内容", "获取成功"}) {
+				unparsed = append(unparsed, trimmed)
+			}
+		}
+	}
+
+	note.Content = strings.TrimSpace(strings.Join(body, "\n"))
+	note.Tags = appendUnique(note.Tags, extractHashTags(note.Content)...)
+	note.ID, note.XsecToken = ParseXHSNoteURL(note.URL)
+	return note, unparsed
+}
+
+func applyNoteField(n *XHSNote, field, value string) {
+	switch field {
+	case "title":
+		n.Title = value
+	case "author":
+		n.Author = value
+	case "published":
+		n.PublishTime = value
+	case "url":
+		if u := reURL.FindString(value); u != "" {
+			n.URL = u
+		}
+	case "tags":
+		for _, t := range strings.FieldsFunc(value, func(r rune) bool {
+			return r == ',' || r == '，' || r == '、' || r == ' ' || r == '#'
+		}) {
+			n.Tags = appendUnique(n.Tags, t)
+		}
+	case "images":
+		n.ImageURLs = append(n.ImageURLs, reURL.FindAllString(value, -1)...)
+	case "likes":
+		n.Likes, _ = parseXHSCount(value)
+	case "collects":
+		n.Collects, _ = parseXHSCount(value)
+	case "comments":
+		n.Comments, _ = parseXHSCount(value)
+	case "shares":
+		n.Shares, _ = parseXHSCount(value)
+	case "counts":
+		// 互动: 点赞 1.2万 收藏 300 评论 20
+		for _, m := range reNoteCount.FindAllStringSubmatch(value, -1) {
+			applyNoteField(n, noteFieldAliases[m[1]], m[2])
+		}
+	}
+}
+
+func isXHSImageURL(s string) bool {
+	return strings.Contains(s, "xhscdn.com") || strings.Contains(s, "sns-img") ||
+		strings.HasSuffix(strings.ToLower(s), ".jpg") || strings.HasSuffix(strings.ToLower(s), ".webp")
+}
+
+func extractHashTags(text string) []string {
+	var tags []string
+	for _, m := range reHashTag.FindAllStringSubmatch(text, -1) {
+		tags = append(tags, m[1])
+	}
+	return tags
+}
+
+func appendUnique(list []string, items ...string) []string {
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		dup := false
+		for _, existing := range list {
+			if existing == item {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+var (
+	// 评论行：1. 用户名（时间）: 内容 / 1. 用户名: 内容
+	reCommentLine = regexp.MustCompile(`^\s*\d+\s*[.、)）]\s*(.+?)\s*(?:[（(]([^）)]*)[)）])?\s*[:：]\s*(.*?)\s*$`)
+	reCommentLike = regexp.MustCompile(`(?:点赞|赞|likes?)\s*[:：]?\s*([\d.,]+\s*[万千wWkK]?\+?)`)
+)
+
+// parseNoteCommentsOutput 解析 get_note_comments 的文本输出
+func parseNoteCommentsOutput(text string) ([]XHSComment, []string) {
+	var (
+		comments []XHSComment
+		unparsed []string
+		cur      *XHSComment
+	)
+	flush := func() {
+		if cur != nil && (cur.Content != "" || cur.Author != "") {
+			comments = append(comments, *cur)
+		}
+		cur = nil
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if m := reCommentLine.FindStringSubmatch(line); m != nil {
+			flush()
+			cur = &XHSComment{Author: m[1], Time: m[2], Content: m[3]}
+			continue
+		}
+		if cur != nil {
+			if m := reItemField.FindStringSubmatch(line); m != nil {
+				switch strings.ToLower(m[1]) {
+				case "点赞", "赞", "likes", "like":
+					cur.Likes, _ = parseXHSCount(m[2])
+					continue
+				case "时间", "日期", "time":
+					cur.Time = m[2]
+					continue
+				case "内容", "评论", "content":
+					cur.Content = m[2]
+					continue
+				case "作者", "用户", "author":
+					cur.Author = m[2]
+					continue
+				}
+			}
+			if m := reCommentLike.FindStringSubmatch(trimmed); m != nil && len(trimmed) < 32 {
+				cur.Likes, _ = parseXHSCount(m[1])
+				continue
+			}
+			// 多行评论内容
+			cur.Content = strings.TrimSpace(cur.Content + "\n" + trimmed)
+			continue
+		}
+		if !containsAny(trimmed, []string{"评论", "共"}) {
+			unparsed = append(unparsed, trimmed)
+		}
+	}
+	flush()
+	return comments, unparsed
+}
@@ -0,0 +1,176 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 修改解析逻辑后用 go test ./internal/mcp -run Golden -update 重新生成 .golden.json
+var update = flag.Bool("update", false, "update golden files")
+
+// checkGolden 把 got 序列化为 JSON，与 testdata/<name>.golden.json 比较
+func checkGolden(t *testing.T, name string, got any) {
+	t.Helper()
+	data, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(data, '\n')
+	path := filepath.Join("testdata", name+".golden.json")
+	if *update {
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("%s mismatch\n got: %s\nwant: %s", path, data, want)
+	}
+}
+
+func readTestdata(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name+".txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParseSearchNotesOutputGolden(t *testing.T) {
+	for _, name := range []string{"search_notes_basic", "search_notes_rich"} {
+		t.Run(name, func(t *testing.T) {
+			posts, unparsed := parseSearchNotesOutput(readTestdata(t, name))
+			checkGolden(t, name, map[string]any{"posts": posts, "unparsed": unparsed})
+		})
+	}
+}
+
+func TestParseNoteContentOutputGolden(t *testing.T) {
+	note, unparsed := parseNoteContentOutput(readTestdata(t, "note_content"))
+	checkGolden(t, "note_content", map[string]any{"note": note, "unparsed": unparsed})
+}
+
+func TestParseNoteCommentsOutputGolden(t *testing.T) {
+	comments, unparsed := parseNoteCommentsOutput(readTestdata(t, "note_comments"))
+	checkGolden(t, "note_comments", map[string]any{"comments": comments, "unparsed": unparsed})
+}
+
+func TestParseSearchNotesOutputItemTitle(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		titles  []string
+		excerpt string // 第一条帖子的摘要
+	}{
+		{
+			name:   "numbering styles",
+			input:  "1. 甲\n2、乙\n3) 丙\n4）丁",
+			titles: []string{"甲", "乙", "丙", "丁"},
+		},
+		{
+			name:    "indented list in excerpt",
+			input:   "1. 甲\n   摘要: 路线\n   1. 故宫\n   2. 景山\n2. 乙",
+			titles:  []string{"甲", "乙"},
+			excerpt: "路线\n1. 故宫\n2. 景山",
+		},
+		{
+			name:    "non-sequential number in excerpt",
+			input:   "1. 甲\n摘要: 路线\n1. 故宫\n3. 景山\n2. 乙",
+			titles:  []string{"甲", "乙"},
+			excerpt: "路线\n1. 故宫\n3. 景山",
+		},
+		{
+			name:   "first item may start at any number",
+			input:  "6. 甲\n7. 乙",
+			titles: []string{"甲", "乙"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			posts, _ := parseSearchNotesOutput(tt.input)
+			var titles []string
+			for _, p := range posts {
+				titles = append(titles, p.Title)
+			}
+			if strings.Join(titles, "|") != strings.Join(tt.titles, "|") {
+				t.Fatalf("titles = %q, want %q", titles, tt.titles)
+			}
+			if posts[0].Excerpt != tt.excerpt {
+				t.Errorf("excerpt = %q, want %q", posts[0].Excerpt, tt.excerpt)
+			}
+		})
+	}
+}
+
+func TestParseSearchNotesOutputUnparsed(t *testing.T) {
+	input := "搜索结果：\n页面加载中\n1. 甲\n   链接: https://www.xiaohongshu.com/explore/6639eb29000000001e03aa87\n   收藏: 12\n"
+	posts, unparsed := parseSearchNotesOutput(input)
+	if len(posts) != 1 {
+		t.Fatalf("got %d posts, want 1", len(posts))
+	}
+	want := []string{"页面加载中", "收藏: 12"}
+	if strings.Join(unparsed, "|") != strings.Join(want, "|") {
+		t.Errorf("unparsed = %q, want %q", unparsed, want)
+	}
+}
+
+func TestParseXHSNoteURL(t *testing.T) {
+	tests := []struct {
+		url, id, token string
+	}{
+		{"https://www.xiaohongshu.com/explore/6639eb29000000001e03aa87?xsec_token=ABwr85ve=&xsec_source=pc_search", "6639eb29000000001e03aa87", "ABwr85ve="},
+		{"https://www.xiaohongshu.com/search_result/68a85a11000000001d0233d9?xsec_token=ABxxx&xsec_source=", "68a85a11000000001d0233d9", "ABxxx"},
+		{"https://www.xiaohongshu.com/discovery/item/65a1b2c3d4e5f60718293a4b", "65a1b2c3d4e5f60718293a4b", ""},
+		{"  https://www.xiaohongshu.com/explore/6639EB29000000001E03AA87/  ", "6639EB29000000001E03AA87", ""},
+		{"https://www.xiaohongshu.com/user/profile/5f0e1d2c", "", ""},
+		{"https://www.xiaohongshu.com/explore/6639eb29?xsec_token=T", "", "T"},
+		{"", "", ""},
+		{"%zz", "", ""},
+	}
+	for _, tt := range tests {
+		id, token := ParseXHSNoteURL(tt.url)
+		if id != tt.id || token != tt.token {
+			t.Errorf("ParseXHSNoteURL(%q) = %q, %q; want %q, %q", tt.url, id, token, tt.id, tt.token)
+		}
+	}
+}
+
+func TestParseXHSCount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+		ok   bool
+	}{
+		{"0", 0, true},
+		{"1234", 1234, true},
+		{" 56 ", 56, true},
+		{"1,234", 1234, true},
+		{"1.2万", 12000, true},
+		{"10万+", 100000, true},
+		{"3.5w", 35000, true},
+		{"3.5W", 35000, true},
+		{"2千", 2000, true},
+		{"1.1k", 1100, true},
+		{"999+", 999, true},
+		{"1.5亿", 150000000, true},
+		{"", 0, false},
+		{"赞", 0, false},
+		{"-3", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseXHSCount(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseXHSCount(%q) = %d, %v; want %d, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
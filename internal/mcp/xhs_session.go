@@ -0,0 +1,251 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrXHSLoginRequired 工具输出提示需要登录时返回
+var ErrXHSLoginRequired = errors.New("xhs login required")
+
+const (
+	// xhsProbeKeyword 后台校验会话时搜索的关键词，只取 1 条结果
+	xhsProbeKeyword = "摄影"
+	// xhsMaxLoginBackoff 后台校验或重新登录连续失败时的最长间隔
+	xhsMaxLoginBackoff = 2 * time.Hour
+)
+
+// 登录失败 / 未登录的输出特征（Redbook-Search-Comment MCP 返回中文提示文本）
+var (
+	xhsLoginFailedMarkers   = []string{"登录失败", "登录超时", "请重试"}
+	xhsLoginRequiredMarkers = []string{"未登录", "请先登录", "需要登录", "扫码登录"}
+)
+
+func containsAny(text string, markers []string) bool {
+	for _, m := range markers {
+		if strings.Contains(text, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// XHSSessionStatus 登录会话状态
+type XHSSessionStatus struct {
+	LoggedIn      bool       `json:"logged_in"`
+	LastLoginAt   *time.Time `json:"last_login_at,omitempty"`
+	LastCheckAt   *time.Time `json:"last_check_at,omitempty"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"` // 最近一次成功的工具调用
+	LastError     string     `json:"last_error,omitempty"`
+	LoginAttempts int        `json:"login_attempts"`
+	LoginInFlight bool       `json:"login_in_flight"`
+}
+
+// XHSSession 管理小红书登录状态：仅在需要时调用 login 工具，并串行化并发的登录尝试
+type XHSSession struct {
+	client XHSClient
+	ttl    time.Duration
+
+	loginMu sync.Mutex // 串行化 login 调用
+
+	mu     sync.RWMutex
+	status XHSSessionStatus
+}
+
+// NewXHSSession ttl 为登录状态的有效期，超过后下一次 Ensure 会重新校验
+func NewXHSSession(client XHSClient, ttl time.Duration) *XHSSession {
+	return &XHSSession{client: client, ttl: ttl}
+}
+
+// Status 返回当前会话状态快照
+func (s *XHSSession) Status() XHSSessionStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.status
+}
+
+func (s *XHSSession) fresh() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.status.LoggedIn || s.status.LastCheckAt == nil {
+		return false
+	}
+	return s.ttl <= 0 || time.Since(*s.status.LastCheckAt) < s.ttl
+}
+
+// Ensure 确保已登录；状态有效时直接返回，否则登录一次
+func (s *XHSSession) Ensure(ctx context.Context) error {
+	if s.fresh() {
+		return nil
+	}
+	return s.login(ctx, false)
+}
+
+// Do 按需登录后执行 fn；fn 返回 ErrXHSLoginRequired 时使会话失效、重新登录并重试一次。
+// 登录失败不阻止 fn 执行，工具可能仍能使用浏览器中已有的登录态。
+func (s *XHSSession) Do(ctx context.Context, fn func() error) error {
+	_ = s.Ensure(ctx)
+	err := fn()
+	if errors.Is(err, ErrXHSLoginRequired) {
+		s.Invalidate(err.Error())
+		if err := s.Ensure(ctx); err != nil {
+			return err
+		}
+		err = fn()
+	}
+	if err == nil {
+		s.markActive()
+	}
+	return err
+}
+
+// markActive 记录一次成功的工具调用，刷新会话的校验时间
+func (s *XHSSession) markActive() {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.LoggedIn = true
+	s.status.LastCheckAt = &now
+	s.status.LastSuccessAt = &now
+	s.status.LastError = ""
+}
+
+// Login 强制重新校验登录状态
+func (s *XHSSession) Login(ctx context.Context) error {
+	return s.login(ctx, true)
+}
+
+// Invalidate 标记会话失效，下一次 Ensure 会重新登录
+func (s *XHSSession) Invalidate(reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.LoggedIn = false
+	s.status.LastError = reason
+}
+
+func (s *XHSSession) login(ctx context.Context, force bool) error {
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+
+	// 等锁期间其他请求可能已完成登录
+	if !force && s.fresh() {
+		return nil
+	}
+
+	s.mu.Lock()
+	s.status.LoginInFlight = true
+	s.status.LoginAttempts++
+	s.mu.Unlock()
+
+	out, err := s.client.CallTool(ctx, "login", map[string]any{})
+	if err == nil && containsAny(out, xhsLoginFailedMarkers) {
+		err = fmt.Errorf("xhs login failed: %s", strings.TrimSpace(out))
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.LoginInFlight = false
+	s.status.LastCheckAt = &now
+	if err != nil {
+		s.status.LoggedIn = false
+		s.status.LastError = err.Error()
+		log.Printf("xhs session: login failed: %v", err)
+		return err
+	}
+	s.status.LoggedIn = true
+	s.status.LastLoginAt = &now
+	s.status.LastError = ""
+	return nil
+}
+
+// Check 用一次只取 1 条结果的搜索校验登录状态，不调用 login 工具。
+// 工具提示未登录时标记会话失效并返回 ErrXHSLoginRequired
+func (s *XHSSession) Check(ctx context.Context) error {
+	_, err := s.client.GetPostsByKeyword(ctx, xhsProbeKeyword, 1)
+	if err == nil {
+		s.markActive()
+		return nil
+	}
+	var rl *RateLimitError
+	if errors.As(err, &rl) {
+		return err
+	}
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.LastCheckAt = &now
+	s.status.LastError = err.Error()
+	if errors.Is(err, ErrXHSLoginRequired) {
+		s.status.LoggedIn = false
+	}
+	return err
+}
+
+// Run 每隔 interval 校验一次会话，直到 ctx 结束。校验失败（含重新登录失败）时按 interval 的倍数退避，最长 xhsMaxLoginBackoff
+func (s *XHSSession) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	failures := 0
+	var next time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if now.Before(next) {
+				continue
+			}
+			if err := s.checkOnce(ctx, interval); err != nil {
+				failures++
+				next = now.Add(loginBackoff(interval, failures))
+				log.Printf("xhs session: check failed (%d in a row): %v", failures, err)
+				continue
+			}
+			failures = 0
+		}
+	}
+}
+
+// checkOnce 一次后台校验：最近一个间隔内有成功的工具调用时跳过；工具提示未登录时重新登录。
+// 从未建立过的会话不在后台登录（需要扫码），首次登录由请求或管理接口触发
+func (s *XHSSession) checkOnce(ctx context.Context, interval time.Duration) error {
+	st := s.Status()
+	if st.LoginInFlight {
+		return nil
+	}
+	if st.LoggedIn && st.LastSuccessAt != nil && time.Since(*st.LastSuccessAt) < interval {
+		return nil
+	}
+	checkCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	err := s.Check(checkCtx)
+	cancel()
+	var rl *RateLimitError
+	if err == nil || errors.As(err, &rl) {
+		// 限流时把预算留给用户请求，下个间隔再查
+		return nil
+	}
+	if !errors.Is(err, ErrXHSLoginRequired) || (st.LastLoginAt == nil && st.LastSuccessAt == nil) {
+		return err
+	}
+	loginCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	return s.login(loginCtx, true)
+}
+
+// loginBackoff 第 failures 次失败后到下一次登录的间隔
+func loginBackoff(interval time.Duration, failures int) time.Duration {
+	d := interval << min(failures, 8)
+	if d <= 0 || d > xhsMaxLoginBackoff {
+		return xhsMaxLoginBackoff
+	}
+	return d
+}
@@ -0,0 +1,131 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeXHSClient 只实现会话用到的搜索与 login
+type fakeXHSClient struct {
+	XHSClient
+	searchErr error
+	loginOut  string
+	searches  int
+	logins    int
+}
+
+func (f *fakeXHSClient) GetPostsByKeyword(ctx context.Context, keyword string, limit int) ([]XHSPost, error) {
+	f.searches++
+	return nil, f.searchErr
+}
+
+func (f *fakeXHSClient) CallTool(ctx context.Context, name string, args map[string]any) (string, error) {
+	if name == "login" {
+		f.logins++
+	}
+	return f.loginOut, nil
+}
+
+func TestXHSSessionCheckOnce(t *testing.T) {
+	now := time.Now()
+	recent, stale := now.Add(-time.Minute), now.Add(-time.Hour)
+	tests := []struct {
+		name         string
+		status       XHSSessionStatus
+		searchErr    error
+		loginOut     string
+		wantErr      bool
+		wantSearches int
+		wantLogins   int
+		wantLoggedIn bool
+	}{
+		{
+			name:         "recent success skips the probe",
+			status:       XHSSessionStatus{LoggedIn: true, LastSuccessAt: &recent},
+			wantLoggedIn: true,
+		},
+		{
+			name:         "probe refreshes a quiet session",
+			status:       XHSSessionStatus{LoggedIn: true, LastSuccessAt: &stale},
+			wantSearches: 1,
+			wantLoggedIn: true,
+		},
+		{
+			name:         "expired session logs in again",
+			status:       XHSSessionStatus{LoggedIn: true, LastLoginAt: &stale, LastSuccessAt: &stale},
+			searchErr:    ErrXHSLoginRequired,
+			loginOut:     "已登录",
+			wantSearches: 1,
+			wantLogins:   1,
+			wantLoggedIn: true,
+		},
+		{
+			name:         "failed re-login is reported",
+			status:       XHSSessionStatus{LoggedIn: true, LastLoginAt: &stale},
+			searchErr:    ErrXHSLoginRequired,
+			loginOut:     "登录超时，请重试",
+			wantErr:      true,
+			wantSearches: 1,
+			wantLogins:   1,
+		},
+		{
+			name:         "never established session is not logged in",
+			searchErr:    ErrXHSLoginRequired,
+			wantErr:      true,
+			wantSearches: 1,
+		},
+		{
+			name:         "rate limited probe is skipped",
+			status:       XHSSessionStatus{LoggedIn: true, LastSuccessAt: &stale},
+			searchErr:    &RateLimitError{Scope: "global", RetryAfter: time.Second},
+			wantSearches: 1,
+			wantLoggedIn: true,
+		},
+		{
+			name:         "transport errors keep the login state",
+			status:       XHSSessionStatus{LoggedIn: true, LastSuccessAt: &stale},
+			searchErr:    errors.New("broken pipe"),
+			wantErr:      true,
+			wantSearches: 1,
+			wantLoggedIn: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeXHSClient{searchErr: tt.searchErr, loginOut: tt.loginOut}
+			s := NewXHSSession(client, 30*time.Minute)
+			s.status = tt.status
+
+			err := s.checkOnce(context.Background(), 10*time.Minute)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if client.searches != tt.wantSearches || client.logins != tt.wantLogins {
+				t.Errorf("searches = %d, logins = %d; want %d, %d", client.searches, client.logins, tt.wantSearches, tt.wantLogins)
+			}
+			if got := s.Status().LoggedIn; got != tt.wantLoggedIn {
+				t.Errorf("LoggedIn = %v, want %v", got, tt.wantLoggedIn)
+			}
+		})
+	}
+}
+
+func TestLoginBackoff(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, 20 * time.Minute},
+		{2, 40 * time.Minute},
+		{3, 80 * time.Minute},
+		{4, xhsMaxLoginBackoff},
+		{100, xhsMaxLoginBackoff},
+	}
+	for _, tt := range tests {
+		if got := loginBackoff(10*time.Minute, tt.failures); got != tt.want {
+			t.Errorf("loginBackoff(10m, %d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
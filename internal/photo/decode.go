@@ -0,0 +1,33 @@
+package photo
+
+import "context"
+
+// DecodeLimiter 限制同时完整解码图片的数量。解码占用的内存与像素数成正比，
+// 派生图生成与感知哈希计算共用同一个上限
+type DecodeLimiter struct {
+	sem chan struct{}
+}
+
+// NewDecodeLimiter n 为同时解码的上限，至少为 1
+func NewDecodeLimiter(n int) *DecodeLimiter {
+	return &DecodeLimiter{sem: make(chan struct{}, max(n, 1))}
+}
+
+// Limit 同时解码的上限
+func (l *DecodeLimiter) Limit() int {
+	return cap(l.sem)
+}
+
+// Do 占用一个名额执行 fn；排队期间 ctx 结束则返回 ctx.Err()，不执行 fn。nil 表示不限制
+func (l *DecodeLimiter) Do(ctx context.Context, fn func() error) error {
+	if l == nil {
+		return fn()
+	}
+	select {
+	case l.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-l.sem }()
+	return fn()
+}
@@ -0,0 +1,165 @@
+// Package photo 照片元数据解析与处理
+package photo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/rwcarlsen/goexif/exif"
+	"github.com/rwcarlsen/goexif/tiff"
+)
+
+// ErrNoEXIF 图片不含 EXIF（PNG、截图或已被清除）
+var ErrNoEXIF = errors.New("no exif data")
+
+// EXIF 从照片中提取的拍摄信息，缺失的字段为零值
+type EXIF struct {
+	TakenAt *time.Time `json:"taken_at,omitempty"`
+
+	CameraMake  string `json:"camera_make,omitempty"`
+	CameraModel string `json:"camera_model,omitempty"`
+	LensModel   string `json:"lens_model,omitempty"`
+
+	// FocalLength 实际焦距（mm），FocalLength35mm 等效焦距
+	FocalLength     float64 `json:"focal_length,omitempty"`
+	FocalLength35mm int     `json:"focal_length_35mm,omitempty"`
+	// ExposureTime 快门速度，如 "1/250" 或 "2"
+	ExposureTime string  `json:"exposure_time,omitempty"`
+	FNumber      float64 `json:"f_number,omitempty"`
+	ISO          int     `json:"iso,omitempty"`
+
+	// Orientation EXIF 方向 1-8，0 表示未记录
+	Orientation int `json:"orientation,omitempty"`
+
+	// GPS 为 WGS-84 坐标
+	HasGPS   bool    `json:"has_gps"`
+	Lat      float64 `json:"lat,omitempty"`
+	Lng      float64 `json:"lng,omitempty"`
+	Altitude float64 `json:"altitude,omitempty"`
+}
+
+// ParseEXIF 解析 JPEG/TIFF 中的 EXIF。没有时区信息的拍摄时间按 loc 解释
+func ParseEXIF(r io.Reader, loc *time.Location) (*EXIF, error) {
+	x, err := exif.Decode(r)
+	// 非关键错误（部分标签损坏）时 x 仍可用
+	if err != nil && (x == nil || exif.IsCriticalError(err)) {
+		return nil, fmt.Errorf("%w: %v", ErrNoEXIF, err)
+	}
+
+	meta := &EXIF{
+		CameraMake:      stringTag(x, exif.Make),
+		CameraModel:     stringTag(x, exif.Model),
+		LensModel:       stringTag(x, exif.LensModel),
+		FocalLength:     ratTag(x, exif.FocalLength),
+		FocalLength35mm: intTag(x, exif.FocalLengthIn35mmFilm),
+		ExposureTime:    exposureTag(x),
+		FNumber:         ratTag(x, exif.FNumber),
+		ISO:             intTag(x, exif.ISOSpeedRatings),
+		Orientation:     intTag(x, exif.Orientation),
+	}
+	if t, ok := takenAt(x, loc); ok {
+		meta.TakenAt = &t
+	}
+	if lat, lng, err := x.LatLong(); err == nil && validGPS(lat, lng) {
+		meta.HasGPS = true
+		meta.Lat, meta.Lng = lat, lng
+		meta.Altitude = altitude(x)
+	}
+	return meta, nil
+}
+
+func takenAt(x *exif.Exif, loc *time.Location) (time.Time, bool) {
+	tag, err := x.Get(exif.DateTimeOriginal)
+	if err != nil {
+		if tag, err = x.Get(exif.DateTime); err != nil {
+			return time.Time{}, false
+		}
+	}
+	raw, err := tag.StringVal()
+	if err != nil {
+		return time.Time{}, false
+	}
+	raw = strings.TrimSpace(strings.TrimRight(raw, "\x00"))
+	if loc == nil {
+		loc = time.Local
+	}
+	t, err := time.ParseInLocation("2006:01:02 15:04:05", raw, loc)
+	if err != nil || t.Year() < 1900 {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// validGPS 排除未定位时相机写入的 0,0
+func validGPS(lat, lng float64) bool {
+	if math.IsNaN(lat) || math.IsNaN(lng) || (lat == 0 && lng == 0) {
+		return false
+	}
+	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180
+}
+
+func altitude(x *exif.Exif) float64 {
+	alt := ratTag(x, exif.GPSAltitude)
+	// GPSAltitudeRef 为 1 表示海平面以下
+	if tag, err := x.Get(exif.GPSAltitudeRef); err == nil && len(tag.Val) > 0 && tag.Val[0] == 1 {
+		alt = -alt
+	}
+	return alt
+}
+
+func stringTag(x *exif.Exif, name exif.FieldName) string {
+	tag, err := x.Get(name)
+	if err != nil || tag.Format() != tiff.StringVal {
+		return ""
+	}
+	s, _ := tag.StringVal()
+	return strings.TrimSpace(strings.TrimRight(s, "\x00"))
+}
+
+func intTag(x *exif.Exif, name exif.FieldName) int {
+	tag, err := x.Get(name)
+	if err != nil || tag.Format() != tiff.IntVal {
+		return 0
+	}
+	v, _ := tag.Int(0)
+	return v
+}
+
+func ratTag(x *exif.Exif, name exif.FieldName) float64 {
+	tag, err := x.Get(name)
+	if err != nil || tag.Format() != tiff.RatVal {
+		return 0
+	}
+	num, den, err := tag.Rat2(0)
+	if err != nil || den == 0 {
+		return 0
+	}
+	return math.Round(float64(num)/float64(den)*100) / 100
+}
+
+// exposureTag 快于 1 秒时写作 1/N
+func exposureTag(x *exif.Exif) string {
+	tag, err := x.Get(exif.ExposureTime)
+	if err != nil || tag.Format() != tiff.RatVal {
+		return ""
+	}
+	num, den, err := tag.Rat2(0)
+	if err != nil || num <= 0 || den <= 0 {
+		return ""
+	}
+	if num >= den {
+		return trimFloat(float64(num) / float64(den))
+	}
+	return fmt.Sprintf("1/%s", trimFloat(float64(den)/float64(num)))
+}
+
+func trimFloat(f float64) string {
+	if f == math.Trunc(f) {
+		return fmt.Sprintf("%d", int64(f))
+	}
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.1f", f), "0"), ".")
+}
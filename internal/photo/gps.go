@@ -0,0 +1,110 @@
+package photo
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+const tagGPSIFD = 0x8825
+
+// tiffTypeSizes TIFF 字段类型对应的单个值字节数
+var tiffTypeSizes = map[uint16]uint32{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8}
+
+// StripJPEGGPS 清除 JPEG 中 EXIF 的 GPS 信息，其他标签（方向、相机等）保留。
+// 原地清零 GPS IFD 的数据并把条目数置 0，不改变文件结构。返回副本及是否有改动；非 JPEG 或无 GPS 时原样返回
+func StripJPEGGPS(data []byte) ([]byte, bool) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return data, false
+	}
+	out := bytes.Clone(data)
+	changed := false
+	for pos := 2; pos+4 <= len(out); {
+		if out[pos] != 0xFF {
+			break
+		}
+		marker := out[pos+1]
+		if marker == 0xD8 || marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			pos += 2
+			continue
+		}
+		// SOS 之后是压缩数据，不再有 APP 段
+		if marker == 0xDA || marker == 0xD9 {
+			break
+		}
+		length := int(binary.BigEndian.Uint16(out[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(out) {
+			break
+		}
+		seg := out[pos+4 : end]
+		if marker == 0xE1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+			if stripTIFFGPS(seg[6:]) {
+				changed = true
+			}
+		}
+		pos = end
+	}
+	if !changed {
+		return data, false
+	}
+	return out, true
+}
+
+func stripTIFFGPS(tiff []byte) bool {
+	if len(tiff) < 8 {
+		return false
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return false
+	}
+	ifd0 := order.Uint32(tiff[4:])
+	gps, ok := findIFDPointer(tiff, order, ifd0, tagGPSIFD)
+	if !ok || uint64(gps)+2 > uint64(len(tiff)) {
+		return false
+	}
+	n := uint32(order.Uint16(tiff[gps:]))
+	if n == 0 {
+		return false
+	}
+	for i := uint32(0); i < n; i++ {
+		entry := uint64(gps) + 2 + uint64(i)*12
+		if entry+12 > uint64(len(tiff)) {
+			break
+		}
+		typ := order.Uint16(tiff[entry+2:])
+		count := order.Uint32(tiff[entry+4:])
+		size := uint64(tiffTypeSizes[typ]) * uint64(count)
+		if size > 4 {
+			off := uint64(order.Uint32(tiff[entry+8:]))
+			if off+size <= uint64(len(tiff)) {
+				clear(tiff[off : off+size])
+			}
+		}
+		clear(tiff[entry : entry+12])
+	}
+	order.PutUint16(tiff[gps:], 0)
+	return true
+}
+
+func findIFDPointer(tiff []byte, order binary.ByteOrder, ifd uint32, tag uint16) (uint32, bool) {
+	if uint64(ifd)+2 > uint64(len(tiff)) {
+		return 0, false
+	}
+	n := uint64(order.Uint16(tiff[ifd:]))
+	for i := uint64(0); i < n; i++ {
+		entry := uint64(ifd) + 2 + i*12
+		if entry+12 > uint64(len(tiff)) {
+			return 0, false
+		}
+		if order.Uint16(tiff[entry:]) == tag {
+			return order.Uint32(tiff[entry+8:]), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,161 @@
+package photo
+
+import (
+	"math/bits"
+	"sort"
+	"sync"
+)
+
+// HashMatch 索引中与目标近似的照片及两种哈希的距离
+type HashMatch struct {
+	ID            uint
+	DHashDistance int
+	PHashDistance int
+}
+
+// HashIndex 感知哈希的内存索引。按 DHash 的汉明距离建 BK 树，查询只访问距离可能满足阈值的分支；
+// 分组结果按阈值缓存，有新哈希加入时失效。并发安全
+type HashIndex struct {
+	mu     sync.RWMutex
+	root   *bkNode
+	hashes map[uint]Hash
+	groups map[int][][]uint
+}
+
+type bkNode struct {
+	id       uint
+	hash     Hash
+	children map[int]*bkNode
+}
+
+func NewHashIndex() *HashIndex {
+	return &HashIndex{hashes: make(map[uint]Hash), groups: make(map[int][][]uint)}
+}
+
+// Len 已索引的照片数
+func (x *HashIndex) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.hashes)
+}
+
+// Add 加入一张照片的哈希。照片内容不变，哈希也不变，已索引的 ID 被忽略
+func (x *HashIndex) Add(id uint, h Hash) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if _, ok := x.hashes[id]; ok {
+		return
+	}
+	x.hashes[id] = h
+	clear(x.groups)
+
+	node := &bkNode{id: id, hash: h}
+	if x.root == nil {
+		x.root = node
+		return
+	}
+	cur := x.root
+	for {
+		d := bits.OnesCount64(cur.hash.DHash ^ h.DHash)
+		next, ok := cur.children[d]
+		if !ok {
+			if cur.children == nil {
+				cur.children = make(map[int]*bkNode)
+			}
+			cur.children[d] = node
+			return
+		}
+		cur = next
+	}
+}
+
+// Search 两种哈希距离都不超过 threshold 的照片，按距离之和从近到远，相同时按 ID
+func (x *HashIndex) Search(target Hash, threshold int) []HashMatch {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	matches := x.search(target, threshold)
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if da, db := a.DHashDistance+a.PHashDistance, b.DHashDistance+b.PHashDistance; da != db {
+			return da < db
+		}
+		return a.ID < b.ID
+	})
+	return matches
+}
+
+func (x *HashIndex) search(target Hash, threshold int) []HashMatch {
+	var matches []HashMatch
+	if x.root == nil {
+		return nil
+	}
+	stack := []*bkNode{x.root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		d := bits.OnesCount64(node.hash.DHash ^ target.DHash)
+		if d <= threshold {
+			if p := bits.OnesCount64(node.hash.PHash ^ target.PHash); p <= threshold {
+				matches = append(matches, HashMatch{ID: node.id, DHashDistance: d, PHashDistance: p})
+			}
+		}
+		// 三角不等式：只有与本节点距离在 [d-threshold, d+threshold] 内的子树可能命中
+		for k := max(d-threshold, 0); k <= min(d+threshold, 64); k++ {
+			if child, ok := node.children[k]; ok {
+				stack = append(stack, child)
+			}
+		}
+	}
+	return matches
+}
+
+// Groups 把两两相似的照片连成组（传递闭包），返回成员数不少于 2 的组。
+// 组内 ID 升序，组按最小 ID 排序。结果在下次 Add 前被缓存，调用方不要修改
+func (x *HashIndex) Groups(threshold int) [][]uint {
+	x.mu.RLock()
+	groups, ok := x.groups[threshold]
+	x.mu.RUnlock()
+	if ok {
+		return groups
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if groups, ok := x.groups[threshold]; ok {
+		return groups
+	}
+	parent := make(map[uint]uint, len(x.hashes))
+	var find func(id uint) uint
+	find = func(id uint) uint {
+		p, ok := parent[id]
+		if !ok || p == id {
+			return id
+		}
+		root := find(p)
+		parent[id] = root
+		return root
+	}
+	for id, h := range x.hashes {
+		for _, m := range x.search(h, threshold) {
+			if a, b := find(id), find(m.ID); a != b {
+				parent[max(a, b)] = min(a, b)
+			}
+		}
+	}
+
+	members := make(map[uint][]uint)
+	for id := range x.hashes {
+		r := find(id)
+		members[r] = append(members[r], id)
+	}
+	groups = [][]uint{}
+	for _, ids := range members {
+		if len(ids) > 1 {
+			sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+			groups = append(groups, ids)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i][0] < groups[j][0] })
+	x.groups[threshold] = groups
+	return groups
+}
@@ -0,0 +1,94 @@
+package photo
+
+import (
+	"math/rand/v2"
+	"reflect"
+	"testing"
+)
+
+// flip 翻转 v 的前 n 位
+func flip(v uint64, n int) uint64 {
+	for i := range n {
+		v ^= 1 << i
+	}
+	return v
+}
+
+func TestHashIndexSearch(t *testing.T) {
+	base := Hash{DHash: 0x00ff00ff00ff00ff, PHash: 0x0f0f0f0f0f0f0f0f}
+	x := NewHashIndex()
+	x.Add(1, base)
+	x.Add(2, Hash{DHash: flip(base.DHash, 3), PHash: flip(base.PHash, 1)})
+	x.Add(3, Hash{DHash: flip(base.DHash, 2), PHash: flip(base.PHash, 20)}) // PHash 太远
+	x.Add(4, Hash{DHash: ^base.DHash, PHash: base.PHash})                   // DHash 太远
+	x.Add(5, base)
+	x.Add(1, Hash{}) // 已存在的 ID 被忽略
+
+	got := x.Search(base, 4)
+	want := []HashMatch{{ID: 1}, {ID: 5}, {ID: 2, DHashDistance: 3, PHashDistance: 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Search = %+v, want %+v", got, want)
+	}
+	if x.Len() != 5 {
+		t.Errorf("Len = %d, want 5", x.Len())
+	}
+	if got := NewHashIndex().Search(base, 10); len(got) != 0 {
+		t.Errorf("Search on empty index = %+v", got)
+	}
+}
+
+// TestHashIndexMatchesLinearScan BK 树的剪枝不能漏掉结果
+func TestHashIndexMatchesLinearScan(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	x := NewHashIndex()
+	var all []Hash
+	for i := range 2000 {
+		h := Hash{DHash: r.Uint64(), PHash: r.Uint64()}
+		if i%3 == 0 && len(all) > 0 {
+			// 与已有哈希相近，保证有命中
+			prev := all[r.IntN(len(all))]
+			h = Hash{DHash: prev.DHash ^ 1<<r.IntN(64) ^ 1<<r.IntN(64), PHash: prev.PHash ^ 1<<r.IntN(64)}
+		}
+		all = append(all, h)
+		x.Add(uint(i+1), h)
+	}
+	for _, threshold := range []int{0, 4, 10, 32} {
+		for q := range 50 {
+			target := all[q*37%len(all)]
+			want := 0
+			for _, h := range all {
+				if h.Similar(target, threshold) {
+					want++
+				}
+			}
+			if got := len(x.Search(target, threshold)); got != want {
+				t.Fatalf("threshold %d: Search found %d, linear scan %d", threshold, got, want)
+			}
+		}
+	}
+}
+
+func TestHashIndexGroups(t *testing.T) {
+	base := Hash{DHash: 0x00ff00ff00ff00ff, PHash: 0x0f0f0f0f0f0f0f0f}
+	x := NewHashIndex()
+	// 5—3—1 两两相邻的距离为 4，5 与 1 的距离为 8：阈值 4 时传递连成一组
+	x.Add(5, base)
+	x.Add(3, Hash{DHash: flip(base.DHash, 4), PHash: base.PHash})
+	x.Add(1, Hash{DHash: flip(base.DHash, 8), PHash: base.PHash})
+	x.Add(2, Hash{DHash: ^base.DHash, PHash: ^base.PHash})
+	x.Add(4, Hash{DHash: ^base.DHash, PHash: ^base.PHash})
+	x.Add(6, Hash{DHash: 0xaaaaaaaaaaaaaaaa, PHash: 0x5555555555555555})
+
+	want := [][]uint{{1, 3, 5}, {2, 4}}
+	if got := x.Groups(4); !reflect.DeepEqual(got, want) {
+		t.Errorf("Groups(4) = %v, want %v", got, want)
+	}
+	if got := x.Groups(1); !reflect.DeepEqual(got, [][]uint{{2, 4}}) {
+		t.Errorf("Groups(1) = %v, want [[2 4]]", got)
+	}
+	// 新哈希使缓存失效
+	x.Add(7, Hash{DHash: 0xaaaaaaaaaaaaaaab, PHash: 0x5555555555555555})
+	if got := x.Groups(1); !reflect.DeepEqual(got, [][]uint{{2, 4}, {6, 7}}) {
+		t.Errorf("Groups(1) after Add = %v, want [[2 4] [6 7]]", got)
+	}
+}
@@ -0,0 +1,104 @@
+package photo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/huangqi/photo-backend/internal/geo"
+	"github.com/huangqi/photo-backend/internal/mcp"
+)
+
+const (
+	// nearbyPOIQuery / nearbyPOIRadius 拍摄点附近 POI 的检索条件，半径单位为米
+	nearbyPOIQuery  = "景点"
+	nearbyPOIRadius = 1000
+	maxNearbyPOIs   = 5
+)
+
+// NearbyPOI 拍摄点附近的地点，坐标为 BD-09
+type NearbyPOI struct {
+	Name      string  `json:"name"`
+	UID       string  `json:"uid"`
+	Address   string  `json:"address,omitempty"`
+	Tag       string  `json:"tag,omitempty"`
+	DistanceM int     `json:"distance_m"`
+	BDLat     float64 `json:"bd_lat"`
+	BDLng     float64 `json:"bd_lng"`
+}
+
+// Location 逆地理编码结果
+type Location struct {
+	BD09             geo.Point   `json:"bd09"`
+	FormattedAddress string      `json:"formatted_address"`
+	Province         string      `json:"province,omitempty"`
+	City             string      `json:"city,omitempty"`
+	District         string      `json:"district,omitempty"`
+	Street           string      `json:"street,omitempty"`
+	Business         string      `json:"business,omitempty"`
+	POIs             []NearbyPOI `json:"pois,omitempty"`
+	// POIError 附近 POI 检索失败的原因，不影响逆地理编码结果
+	POIError string `json:"poi_error,omitempty"`
+}
+
+// Locate 把 WGS-84 坐标转为 BD-09 后逆地理编码，并检索附近 POI。
+// 逆地理编码失败时返回错误；POI 检索失败只记录在 POIError 中
+func Locate(ctx context.Context, maps mcp.BaiduMapsClient, wgs geo.Point) (*Location, error) {
+	bd := geo.WGS84ToBD09(wgs)
+	rev, err := maps.ReverseGeocode(ctx, bd.Lat, bd.Lng)
+	if err != nil {
+		return nil, err
+	}
+	if rev.Status != 0 {
+		return nil, fmt.Errorf("reverse geocode status %d: %s", rev.Status, rev.Message)
+	}
+	comp := rev.Result.AddressComponent
+	loc := &Location{
+		BD09:             bd,
+		FormattedAddress: rev.Result.FormattedAddress,
+		Province:         comp.Province,
+		City:             comp.City,
+		District:         comp.District,
+		Street:           comp.Street,
+		Business:         rev.Result.Business,
+	}
+	pois, err := nearbyPOIs(ctx, maps, bd)
+	if err != nil {
+		loc.POIError = err.Error()
+	}
+	loc.POIs = pois
+	return loc, nil
+}
+
+// nearbyPOIs 按距离排序，最多返回 maxNearbyPOIs 个
+func nearbyPOIs(ctx context.Context, maps mcp.BaiduMapsClient, bd geo.Point) ([]NearbyPOI, error) {
+	location := fmt.Sprintf("%f,%f", bd.Lat, bd.Lng)
+	places, err := maps.SearchPlaces(ctx, nearbyPOIQuery, "", "", location, nearbyPOIRadius, "", "")
+	if err != nil {
+		return nil, err
+	}
+	pois := make([]NearbyPOI, 0, len(places))
+	for _, p := range places {
+		if p.Name == "" {
+			continue
+		}
+		dist := p.DetailInfo.Distance
+		if dist == 0 {
+			dist = int(geo.DistanceKm(bd, geo.Point{Lat: p.Location.Lat, Lng: p.Location.Lng}) * 1000)
+		}
+		pois = append(pois, NearbyPOI{
+			Name:      p.Name,
+			UID:       p.UID,
+			Address:   p.Address,
+			Tag:       p.DetailInfo.Tag,
+			DistanceM: dist,
+			BDLat:     p.Location.Lat,
+			BDLng:     p.Location.Lng,
+		})
+	}
+	sort.SliceStable(pois, func(i, j int) bool { return pois[i].DistanceM < pois[j].DistanceM })
+	if len(pois) > maxNearbyPOIs {
+		pois = pois[:maxNearbyPOIs]
+	}
+	return pois, nil
+}
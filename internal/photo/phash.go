@@ -0,0 +1,217 @@
+package photo
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+)
+
+// Hash 感知哈希。DHash 比较相邻像素的亮度梯度，PHash 取 DCT 低频分量，
+// 二者都先应用 EXIF 方向并忽略宽高比，因此同一张照片经不同设备重新编码、缩放后距离仍然很小
+type Hash struct {
+	DHash uint64
+	PHash uint64
+}
+
+// Distance 两个哈希各自的汉明距离（0-64）
+func (h Hash) Distance(o Hash) (dhash, phash int) {
+	return bits.OnesCount64(h.DHash ^ o.DHash), bits.OnesCount64(h.PHash ^ o.PHash)
+}
+
+// Similar 两种哈希的距离都不超过 threshold
+func (h Hash) Similar(o Hash, threshold int) bool {
+	d, p := h.Distance(o)
+	return d <= threshold && p <= threshold
+}
+
+// FormatHash 16 位十六进制，便于存储与比较
+func FormatHash(v uint64) string {
+	return fmt.Sprintf("%016x", v)
+}
+
+// ParseHash 解析 FormatHash 的结果
+func ParseHash(s string) (uint64, error) {
+	if len(s) != 16 {
+		return 0, fmt.Errorf("invalid hash %q", s)
+	}
+	return strconv.ParseUint(s, 16, 64)
+}
+
+// ParseHashes 解析一对存储的哈希
+func ParseHashes(dhash, phash string) (Hash, error) {
+	d, err := ParseHash(dhash)
+	if err != nil {
+		return Hash{}, err
+	}
+	p, err := ParseHash(phash)
+	if err != nil {
+		return Hash{}, err
+	}
+	return Hash{DHash: d, PHash: p}, nil
+}
+
+// 计算哈希前先把图片按面积平均缩成 grayEdge×grayEdge 的灰度图
+const (
+	grayEdge  = 64
+	phashEdge = 32
+)
+
+// HashImage 解码图片并计算感知哈希
+func HashImage(r io.Reader, orientation int) (Hash, error) {
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return Hash{}, fmt.Errorf("decode image: %w", err)
+	}
+	return ComputeHash(src, orientation), nil
+}
+
+// ComputeHash 计算已解码图片的感知哈希
+func ComputeHash(src image.Image, orientation int) Hash {
+	gray := ApplyOrientation(grayThumb(src), orientation)
+	lum := make([]float64, grayEdge*grayEdge)
+	for y := 0; y < grayEdge; y++ {
+		for x := 0; x < grayEdge; x++ {
+			r, _, _, _ := gray.At(gray.Bounds().Min.X+x, gray.Bounds().Min.Y+y).RGBA()
+			lum[y*grayEdge+x] = float64(r >> 8)
+		}
+	}
+	return Hash{
+		DHash: dhash(shrink(lum, grayEdge, grayEdge, 9, 8)),
+		PHash: phash(shrink(lum, grayEdge, grayEdge, phashEdge, phashEdge)),
+	}
+}
+
+// dhash 9×8 灰度图，每行相邻像素左亮于右记 1
+func dhash(lum []float64) uint64 {
+	var h uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			h <<= 1
+			if lum[y*9+x] > lum[y*9+x+1] {
+				h |= 1
+			}
+		}
+	}
+	return h
+}
+
+// phash 32×32 灰度图做二维 DCT，取左上 8×8 低频系数，大于中位数记 1
+func phash(lum []float64) uint64 {
+	const n = phashEdge
+	// 先对行、再对列做 DCT-II，只计算需要的前 8 个频率
+	rows := make([]float64, n*8)
+	for y := 0; y < n; y++ {
+		for u := 0; u < 8; u++ {
+			var sum float64
+			for x := 0; x < n; x++ {
+				sum += lum[y*n+x] * dctCos(x, u, n)
+			}
+			rows[y*8+u] = sum
+		}
+	}
+	coeffs := make([]float64, 64)
+	for v := 0; v < 8; v++ {
+		for u := 0; u < 8; u++ {
+			var sum float64
+			for y := 0; y < n; y++ {
+				sum += rows[y*8+u] * dctCos(y, v, n)
+			}
+			coeffs[v*8+u] = sum
+		}
+	}
+	sorted := append([]float64(nil), coeffs...)
+	sort.Float64s(sorted)
+	median := (sorted[31] + sorted[32]) / 2
+
+	var h uint64
+	for _, c := range coeffs {
+		h <<= 1
+		if c > median {
+			h |= 1
+		}
+	}
+	return h
+}
+
+func dctCos(x, u, n int) float64 {
+	return math.Cos(float64(2*x+1) * float64(u) * math.Pi / float64(2*n))
+}
+
+// grayThumb 按面积平均缩成 grayEdge×grayEdge 灰度图。JPEG 解码结果直接取 Y 平面
+func grayThumb(src image.Image) *image.Gray {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	sums := make([]float64, grayEdge*grayEdge)
+	counts := make([]int, grayEdge*grayEdge)
+	add := func(x, y int, v float64) {
+		i := (y*grayEdge/h)*grayEdge + x*grayEdge/w
+		sums[i] += v
+		counts[i]++
+	}
+	switch img := src.(type) {
+	case *image.YCbCr:
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				add(x, y, float64(img.Y[img.YOffset(b.Min.X+x, b.Min.Y+y)]))
+			}
+		}
+	case *image.Gray:
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				add(x, y, float64(img.Pix[img.PixOffset(b.Min.X+x, b.Min.Y+y)]))
+			}
+		}
+	default:
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				add(x, y, float64(color.GrayModel.Convert(src.At(b.Min.X+x, b.Min.Y+y)).(color.Gray).Y))
+			}
+		}
+	}
+	out := image.NewGray(image.Rect(0, 0, grayEdge, grayEdge))
+	for i := range sums {
+		if counts[i] > 0 {
+			out.Pix[i] = uint8(math.Round(sums[i] / float64(counts[i])))
+		}
+	}
+	// 小于 grayEdge 的图片有些行列没有像素，复制左侧/上方的行列补齐
+	if w < grayEdge || h < grayEdge {
+		for x := 1; x < grayEdge; x++ {
+			if (x*w+grayEdge-1)/grayEdge == ((x+1)*w+grayEdge-1)/grayEdge {
+				for y := 0; y < grayEdge; y++ {
+					out.Pix[y*grayEdge+x] = out.Pix[y*grayEdge+x-1]
+				}
+			}
+		}
+		for y := 1; y < grayEdge; y++ {
+			if (y*h+grayEdge-1)/grayEdge == ((y+1)*h+grayEdge-1)/grayEdge {
+				copy(out.Pix[y*grayEdge:(y+1)*grayEdge], out.Pix[(y-1)*grayEdge:y*grayEdge])
+			}
+		}
+	}
+	return out
+}
+
+// shrink 按面积平均把 sw×sh 的亮度矩阵缩成 dw×dh
+func shrink(src []float64, sw, sh, dw, dh int) []float64 {
+	sums := make([]float64, dw*dh)
+	counts := make([]int, dw*dh)
+	for y := 0; y < sh; y++ {
+		for x := 0; x < sw; x++ {
+			i := (y*dh/sh)*dw + x*dw/sw
+			sums[i] += src[y*sw+x]
+			counts[i]++
+		}
+	}
+	for i := range sums {
+		if counts[i] > 0 {
+			sums[i] /= float64(counts[i])
+		}
+	}
+	return sums
+}
@@ -0,0 +1,183 @@
+package photo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/huangqi/photo-backend/internal/storage"
+)
+
+// ImmutableCacheControl 内容寻址的对象永不变化
+const ImmutableCacheControl = "public, max-age=31536000, immutable"
+
+// ErrQueueFull 后台队列已满，派生图会在首次请求时生成
+var ErrQueueFull = errors.New("variant queue full")
+
+// ErrUnknownVariant 未配置的派生图名称
+var ErrUnknownVariant = errors.New("unknown variant")
+
+// Source 生成派生图所需的原图信息
+type Source struct {
+	Key         string
+	SHA256      string
+	Orientation int
+}
+
+// Pipeline 用固定数量的 worker 生成派生图并写入存储。
+// 上传后通过 Enqueue 后台生成；请求时尚未生成则由 Ensure 同步生成，二者共享并发上限
+type Pipeline struct {
+	store storage.BlobStore
+	specs []VariantSpec
+	queue chan Source
+	// decodes 限制同时解码/编码的数量，避免大图占满内存
+	decodes *DecodeLimiter
+
+	mu       sync.Mutex
+	inflight map[string]*renderCall
+}
+
+type renderCall struct {
+	done chan struct{}
+	err  error
+}
+
+// NewPipeline decodes 为与其他解码共用的并发上限，后台 worker 数与之相同；queueSize 为后台队列长度
+func NewPipeline(store storage.BlobStore, specs []VariantSpec, decodes *DecodeLimiter, queueSize int) *Pipeline {
+	return &Pipeline{
+		store:    store,
+		specs:    specs,
+		queue:    make(chan Source, max(queueSize, 0)),
+		decodes:  decodes,
+		inflight: make(map[string]*renderCall),
+	}
+}
+
+// Specs 已配置的派生图规格
+func (p *Pipeline) Specs() []VariantSpec {
+	return p.specs
+}
+
+// Spec 按名称查找规格
+func (p *Pipeline) Spec(name string) (VariantSpec, bool) {
+	for _, s := range p.specs {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return VariantSpec{}, false
+}
+
+// Run 启动后台 worker，直到 ctx 结束
+func (p *Pipeline) Run(ctx context.Context) {
+	for i := 0; i < p.decodes.Limit(); i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case src := <-p.queue:
+					if err := p.render(ctx, src); err != nil {
+						log.Printf("photo: variants of %s failed: %v", src.SHA256, err)
+					}
+				}
+			}
+		}()
+	}
+}
+
+// Enqueue 非阻塞地提交后台生成，队列满时返回 ErrQueueFull
+func (p *Pipeline) Enqueue(src Source) error {
+	select {
+	case p.queue <- src:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Ensure 确保派生图已存在，必要时同步生成（会同时生成其他缺失的规格）
+func (p *Pipeline) Ensure(ctx context.Context, src Source, name string) (VariantSpec, error) {
+	spec, ok := p.Spec(name)
+	if !ok {
+		return spec, fmt.Errorf("%w %q", ErrUnknownVariant, name)
+	}
+	if _, err := p.store.Stat(ctx, spec.Key(src.SHA256)); err == nil {
+		return spec, nil
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return spec, err
+	}
+	return spec, p.render(ctx, src)
+}
+
+// render 同一原图同时只生成一次，后到的调用等待前一次的结果
+func (p *Pipeline) render(ctx context.Context, src Source) error {
+	p.mu.Lock()
+	if call, ok := p.inflight[src.SHA256]; ok {
+		p.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	call := &renderCall{done: make(chan struct{})}
+	p.inflight[src.SHA256] = call
+	p.mu.Unlock()
+
+	// 不随单个请求取消，避免生成到一半被丢弃
+	call.err = p.renderMissing(context.WithoutCancel(ctx), src)
+	p.mu.Lock()
+	delete(p.inflight, src.SHA256)
+	p.mu.Unlock()
+	close(call.done)
+	return call.err
+}
+
+func (p *Pipeline) renderMissing(ctx context.Context, src Source) error {
+	var missing []VariantSpec
+	for _, spec := range p.specs {
+		_, err := p.store.Stat(ctx, spec.Key(src.SHA256))
+		if errors.Is(err, storage.ErrNotFound) {
+			missing = append(missing, spec)
+		} else if err != nil {
+			return err
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	var rendered map[string][]byte
+	if err := p.decodes.Do(ctx, func() error {
+		rc, _, err := p.store.Get(ctx, src.Key)
+		if err != nil {
+			return fmt.Errorf("read original: %w", err)
+		}
+		original, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("read original: %w", err)
+		}
+		rendered, err = RenderVariants(original, src.Orientation, missing)
+		return err
+	}); err != nil {
+		return err
+	}
+	for _, spec := range missing {
+		data := rendered[spec.Name]
+		if _, err := p.store.Put(ctx, spec.Key(src.SHA256), bytes.NewReader(data), storage.PutOptions{
+			ContentType:  spec.ContentType(),
+			CacheControl: ImmutableCacheControl,
+			Size:         int64(len(data)),
+		}); err != nil {
+			return fmt.Errorf("store %s: %w", spec.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package photo
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+)
+
+// 输出格式
+const (
+	FormatJPEG = "jpeg"
+	FormatWebP = "webp"
+)
+
+const defaultJPEGQuality = 82
+
+// VariantSpec 一种派生图：长边不超过 MaxEdge（不放大）。
+// WebP 为无损编码（nativewebp 只支持无损），体积通常大于同尺寸的 JPEG，用于需要透明或无损的场景，不用于节省流量
+type VariantSpec struct {
+	Name    string `json:"name"`
+	MaxEdge int    `json:"max_edge"`
+	Format  string `json:"format"`
+	Quality int    `json:"quality,omitempty"`
+}
+
+// Ext 文件扩展名
+func (s VariantSpec) Ext() string {
+	if s.Format == FormatWebP {
+		return ".webp"
+	}
+	return ".jpg"
+}
+
+// ContentType MIME 类型
+func (s VariantSpec) ContentType() string {
+	if s.Format == FormatWebP {
+		return "image/webp"
+	}
+	return "image/jpeg"
+}
+
+// Key 派生图的存储 key，由原图哈希与规格决定，规格变化后生成新的 key。
+// JPEG 带质量，如 "variants/<sha256>/thumb_320_q82.jpg"；WebP 无损，不带质量
+func (s VariantSpec) Key(sha256 string) string {
+	if s.Format == FormatWebP {
+		return fmt.Sprintf("variants/%s/%s_%d%s", sha256, s.Name, s.MaxEdge, s.Ext())
+	}
+	return fmt.Sprintf("variants/%s/%s_%d_q%d%s", sha256, s.Name, s.MaxEdge, s.quality(), s.Ext())
+}
+
+// Version 规格中决定输出内容的部分，如 "320jpegq82"、"320webp"。
+// 写入变体地址，规格变化后地址随之变化，因此地址可以长期缓存
+func (s VariantSpec) Version() string {
+	v := strconv.Itoa(s.MaxEdge) + s.Format
+	if s.Format != FormatWebP {
+		v += "q" + strconv.Itoa(s.quality())
+	}
+	return v
+}
+
+// quality JPEG 质量，未设置时为默认值
+func (s VariantSpec) quality() int {
+	if s.Quality == 0 {
+		return defaultJPEGQuality
+	}
+	return s.Quality
+}
+
+// ParseVariantSpecs 解析 "name:max_edge[:format[:quality]]" 的逗号分隔列表，
+// 如 "thumb:320:jpeg,medium:1280:jpeg:85,thumb_webp:320:webp"。WebP 无损，不接受质量
+func ParseVariantSpecs(raw string) ([]VariantSpec, error) {
+	var specs []VariantSpec
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.Split(item, ":")
+		if len(parts) < 2 || len(parts) > 4 {
+			return nil, fmt.Errorf("invalid variant %q (want name:max_edge[:format[:quality]])", item)
+		}
+		spec := VariantSpec{Name: parts[0], Format: FormatJPEG, Quality: defaultJPEGQuality}
+		if spec.Name == "" || strings.ContainsAny(spec.Name, "/.") || seen[spec.Name] {
+			return nil, fmt.Errorf("invalid or duplicate variant name %q", spec.Name)
+		}
+		edge, err := strconv.Atoi(parts[1])
+		if err != nil || edge <= 0 {
+			return nil, fmt.Errorf("invalid max edge in variant %q", item)
+		}
+		spec.MaxEdge = edge
+		if len(parts) > 2 {
+			switch f := strings.ToLower(parts[2]); f {
+			case FormatJPEG, "jpg":
+				spec.Format = FormatJPEG
+			case FormatWebP:
+				spec.Format = FormatWebP
+			default:
+				return nil, fmt.Errorf("unsupported format %q in variant %q", parts[2], item)
+			}
+		}
+		if len(parts) > 3 {
+			if spec.Format == FormatWebP {
+				return nil, fmt.Errorf("quality is not supported for lossless webp in variant %q", item)
+			}
+			q, err := strconv.Atoi(parts[3])
+			if err != nil || q < 1 || q > 100 {
+				return nil, fmt.Errorf("invalid quality in variant %q", item)
+			}
+			spec.Quality = q
+		}
+		seen[spec.Name] = true
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// RenderVariants 解码原图、应用 EXIF 方向后按规格生成派生图。
+// 从大到小依次缩放，小图从上一张结果缩放以减少计算。输出不含任何 EXIF
+func RenderVariants(original []byte, orientation int, specs []VariantSpec) (map[string][]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(original))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	src = ApplyOrientation(src, orientation)
+
+	ordered := append([]VariantSpec(nil), specs...)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].MaxEdge > ordered[j].MaxEdge })
+
+	out := make(map[string][]byte, len(specs))
+	current := src
+	for _, spec := range ordered {
+		current = fit(current, spec.MaxEdge)
+		var buf bytes.Buffer
+		if err := encode(&buf, current, spec); err != nil {
+			return nil, fmt.Errorf("encode %s: %w", spec.Name, err)
+		}
+		out[spec.Name] = buf.Bytes()
+	}
+	return out, nil
+}
+
+// fit 等比缩小到长边不超过 maxEdge
+func fit(src image.Image, maxEdge int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxEdge && h <= maxEdge {
+		return src
+	}
+	if w >= h {
+		h = max(1, h*maxEdge/w)
+		w = maxEdge
+	} else {
+		w = max(1, w*maxEdge/h)
+		h = maxEdge
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Rect, src, b, draw.Src, nil)
+	return dst
+}
+
+func encode(buf *bytes.Buffer, img image.Image, spec VariantSpec) error {
+	if spec.Format == FormatWebP {
+		return nativewebp.Encode(buf, img, nil)
+	}
+	return jpeg.Encode(buf, flatten(img), &jpeg.Options{Quality: spec.quality()})
+}
+
+// flatten 透明区域铺白底，JPEG 不支持透明
+func flatten(img image.Image) image.Image {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		return img
+	}
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Rect, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Rect, img, b.Min, draw.Over)
+	return dst
+}
@@ -1,27 +1,207 @@
 package server
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/huangqi/photo-backend/internal/catalog"
+	"github.com/huangqi/photo-backend/internal/db"
 	"github.com/huangqi/photo-backend/internal/handlers"
+	"github.com/huangqi/photo-backend/internal/harvest"
 	"github.com/huangqi/photo-backend/internal/mcp"
+	"github.com/huangqi/photo-backend/internal/photo"
+	"github.com/huangqi/photo-backend/internal/storage"
+	"gorm.io/gorm"
 )
 
-func NewRouter(maps mcp.MapsClient, baiduMaps mcp.BaiduMapsClient) *gin.Engine {
+// Deps 路由依赖。可选模块的客户端为 nil 时，对应路由返回 503 并附带原因。
+type Deps struct {
+	// Maps 为 nil 时附近景点接口返回 503
+	Maps      mcp.MapsClient
+	BaiduMaps mcp.BaiduMapsClient
+
+	XHS        mcp.XHSClient
+	XHSSession *mcp.XHSSession
+	// XHSUnavailableReason 说明 XHS 为 nil 的原因
+	XHSUnavailableReason string
+	// XHSRepo 为 nil 时不缓存搜索结果
+	XHSRepo     db.XHSRepository
+	XHSCacheTTL time.Duration
+	// XHSCommentPosting 是否允许管理接口发表评论，XHSCommentMinInterval 为发表最小间隔
+	XHSCommentPosting     bool
+	XHSCommentMinInterval time.Duration
+
+	Catalog *catalog.Catalog
+	// CatalogUnavailableReason 说明 Catalog 为 nil 的原因
+	CatalogUnavailableReason string
+
+	// Harvester 与 Links 为 nil 时采集管理接口返回 503
+	Harvester                *harvest.Harvester
+	Links                    *db.LinkStore
+	HarvestUnavailableReason string
+
+	// Spots 为 nil 时机位接口返回 503
+	Spots                  db.SpotRepository
+	SpotsUnavailableReason string
+
+	// Photos 或 PhotoStore 为 nil 时照片接口返回 503
+	Photos                  db.PhotoRepository
+	PhotoStore              storage.BlobStore
+	PhotosUnavailableReason string
+	PhotoMaxBytes           int64
+	PhotoMaxPixels          int64
+	PhotoLocation           *time.Location
+	// PhotoVariants 为 nil 时不生成派生图；PhotoStripGPS 下载原图时清除 GPS
+	PhotoVariants *photo.Pipeline
+	// PhotoDecodes 限制同时解码的图片数，与 PhotoVariants 共用；为 nil 时不限制
+	PhotoDecodes  *photo.DecodeLimiter
+	PhotoStripGPS bool
+	// PhotoDupThreshold 近似重复查找的默认汉明距离
+	PhotoDupThreshold int
+
+	// Albums 为 nil 时相册接口返回 503
+	Albums                  db.AlbumRepository
+	AlbumsUnavailableReason string
+
+	// DB 用于健康检查与连接池指标，为 nil 时 DBUnavailableReason 说明原因
+	DB                  *gorm.DB
+	DBUnavailableReason string
+
+	// AdminToken 管理接口令牌，为空时 /api/admin 全部拒绝
+	AdminToken string
+}
+
+func NewRouter(deps Deps) *gin.Engine {
 	r := gin.New()
 	r.Use(gin.Recovery())
 
-	//xhsHandler := handlers.NewXHSHandler(xhs)
-	travelHandler := handlers.NewTravelHandler(maps)
-	baiduMapsHandler := handlers.NewBaiduMapsHandler(baiduMaps)
+	travelHandler := handlers.NewTravelHandler(deps.Maps, deps.PhotoLocation)
+	travelHandler.BaiduMaps = deps.BaiduMaps
+	travelHandler.Spots = deps.Spots
+	baiduMapsHandler := handlers.NewBaiduMapsHandler(deps.BaiduMaps)
+	systemHandler := handlers.NewSystemHandler(deps.DB, deps.DBUnavailableReason)
+
+	r.GET("/healthz", systemHandler.Health)
+	r.GET("/metrics", systemHandler.Metrics)
 
 	api := r.Group("/api")
 	{
-		// xhsGroup := api.Group("/xhs")
-		// xhsGroup.GET("/hot", xhsHandler.GetHot)
-		// xhsGroup.GET("/search", xhsHandler.SearchLinks)
+		xhsGroup := api.Group("/xhs")
+		adminGroup := api.Group("/admin", requireAdmin(deps.AdminToken))
+		adminXHSGroup := adminGroup.Group("/xhs")
+		var xhsHandler *handlers.XHSHandler
+		if deps.XHS != nil {
+			xhsHandler = handlers.NewXHSHandler(deps.XHS, deps.XHSSession)
+			xhsHandler.Repo = deps.XHSRepo
+			xhsHandler.CacheTTL = deps.XHSCacheTTL
+			xhsHandler.CommentPosting = deps.XHSCommentPosting
+			xhsHandler.CommentMinInterval = deps.XHSCommentMinInterval
+			xhsGroup.GET("/hot", xhsHandler.GetHot)
+			xhsGroup.GET("/search", xhsHandler.SearchLinks)
+			xhsGroup.GET("/search/stream", xhsHandler.StreamSearch)
+			xhsGroup.GET("/notes/:id", xhsHandler.GetNote)
+			xhsGroup.GET("/notes/:id/comments", xhsHandler.GetComments)
+
+			adminXHSGroup.GET("/session", xhsHandler.SessionStatus)
+			adminXHSGroup.POST("/session/login", xhsHandler.SessionLogin)
+			adminXHSGroup.POST("/notes/:id/comments", xhsHandler.PostComment)
+		} else {
+			xhsUnavailable := moduleUnavailable("xhs", deps.XHSUnavailableReason)
+			xhsGroup.Any("/*path", xhsUnavailable)
+			adminXHSGroup.Any("/*path", xhsUnavailable)
+		}
+
+		adminHarvestGroup := adminGroup.Group("/harvest")
+		if deps.Harvester != nil && deps.Links != nil {
+			harvestHandler := handlers.NewHarvestHandler(deps.Harvester, deps.Links)
+			adminHarvestGroup.GET("", harvestHandler.Status)
+			adminHarvestGroup.POST("/run", harvestHandler.Run)
+			adminHarvestGroup.GET("/links", harvestHandler.ListLinks)
+			adminHarvestGroup.GET("/links.csv", harvestHandler.ExportLinksCSV)
+		} else {
+			adminHarvestGroup.Any("/*path", moduleUnavailable("harvest", deps.HarvestUnavailableReason))
+		}
+
+		inspirationGroup := api.Group("/inspiration")
+		if deps.Catalog != nil {
+			inspirationHandler := handlers.NewInspirationHandler(deps.Catalog, xhsHandler)
+			inspirationGroup.GET("/categories", inspirationHandler.ListCategories)
+			inspirationGroup.GET("/categories/:name", inspirationHandler.GetCategory)
+			inspirationGroup.GET("/categories/:name/posts", inspirationHandler.SearchCategory)
+		} else {
+			inspirationGroup.Any("/*path", moduleUnavailable("inspiration", deps.CatalogUnavailableReason))
+		}
+
+		spotsGroup := api.Group("/spots")
+		if deps.Spots != nil {
+			spotHandler := handlers.NewSpotHandler(deps.Spots, deps.Catalog)
+			spotsGroup.GET("", spotHandler.List)
+			spotsGroup.POST("", spotHandler.Create)
+			spotsGroup.GET("/:id", spotHandler.Get)
+			spotsGroup.PUT("/:id", spotHandler.Update)
+			spotsGroup.DELETE("/:id", spotHandler.Delete)
+		} else {
+			spotsGroup.Any("/*path", moduleUnavailable("spots", deps.SpotsUnavailableReason))
+		}
+
+		var photoHandler *handlers.PhotoHandler
+		photosGroup := api.Group("/photos")
+		adminPhotosGroup := adminGroup.Group("/photos")
+		if deps.Photos != nil && deps.PhotoStore != nil {
+			photoHandler = handlers.NewPhotoHandler(deps.Photos, deps.BaiduMaps, deps.PhotoStore, deps.PhotoMaxBytes, deps.PhotoLocation)
+			photoHandler.MaxPixels = deps.PhotoMaxPixels
+			photoHandler.Variants = deps.PhotoVariants
+			photoHandler.Decodes = deps.PhotoDecodes
+			photoHandler.StripGPS = deps.PhotoStripGPS
+			photoHandler.DupThreshold = deps.PhotoDupThreshold
+			photosGroup.POST("", photoHandler.Upload)
+			photosGroup.POST("/duplicates", photoHandler.CheckDuplicates)
+			photosGroup.GET("/groups", photoHandler.Groups)
+			photosGroup.GET("/:id", photoHandler.Get)
+			photosGroup.GET("/:id/duplicates", photoHandler.Duplicates)
+			photosGroup.GET("/:id/file", photoHandler.File)
+			photosGroup.GET("/:id/variants/:variant", photoHandler.Variant)
+			adminPhotosGroup.POST("/rehash", photoHandler.Rehash)
+		} else {
+			photosGroup.Any("/*path", moduleUnavailable("photos", deps.PhotosUnavailableReason))
+			adminPhotosGroup.Any("/*path", moduleUnavailable("photos", deps.PhotosUnavailableReason))
+		}
+
+		albumsGroup := api.Group("/albums")
+		if deps.Albums != nil && deps.Photos != nil && deps.Spots != nil {
+			albumHandler := handlers.NewAlbumHandler(deps.Albums, deps.Photos, deps.Spots, deps.BaiduMaps, deps.PhotoLocation)
+			albumHandler.Photo = photoHandler
+			albumsGroup.GET("", albumHandler.List)
+			albumsGroup.POST("", albumHandler.Create)
+			albumsGroup.GET("/:id", albumHandler.Get)
+			albumsGroup.PUT("/:id", albumHandler.Update)
+			albumsGroup.DELETE("/:id", albumHandler.Delete)
+			albumsGroup.POST("/:id/photos", albumHandler.AddPhotos)
+			albumsGroup.DELETE("/:id/photos/:photo_id", albumHandler.RemovePhoto)
+			albumsGroup.GET("/:id/timeline", albumHandler.Timeline)
+		} else {
+			albumsGroup.Any("/*path", moduleUnavailable("albums", deps.AlbumsUnavailableReason))
+		}
+
+		lightHandler := handlers.NewLightHandler(deps.PhotoLocation, deps.BaiduMaps)
+		photoGroup := api.Group("/photo")
+		lightHandler.Spots = deps.Spots
+		photoGroup.GET("/light", lightHandler.Get)
+		photoGroup.GET("/sun", lightHandler.Sun)
+		if deps.BaiduMaps != nil {
+			photoGroup.GET("/conditions", lightHandler.Conditions)
+		} else {
+			photoGroup.GET("/conditions", moduleUnavailable("conditions", "baidu maps not configured"))
+		}
 
 		travelGroup := api.Group("/travel")
-		travelGroup.GET("/nearby", travelHandler.GetNearby)
+		if deps.Maps != nil {
+			travelGroup.GET("/nearby", travelHandler.GetNearby)
+		} else {
+			travelGroup.GET("/nearby", moduleUnavailable("maps", "no maps or baidu-maps MCP server configured"))
+		}
+		travelGroup.POST("/itinerary", travelHandler.Itinerary)
 
 		baiduMapsGroup := api.Group("/baidu-maps")
 		baiduMapsGroup.GET("/geocode", baiduMapsHandler.Geocode)
@@ -35,3 +215,16 @@ func NewRouter(maps mcp.MapsClient, baiduMaps mcp.BaiduMapsClient) *gin.Engine {
 
 	return r
 }
+
+// moduleUnavailable 未启用模块的兜底处理
+func moduleUnavailable(module, reason string) gin.HandlerFunc {
+	if reason == "" {
+		reason = "module not configured"
+	}
+	return func(c *gin.Context) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":  module + " module unavailable",
+			"reason": reason,
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package solar
+
+import "time"
+
+// 全天日照状态
+const (
+	// PolarDay 极昼，全天太阳都在地平线以上
+	PolarDay = "day"
+	// PolarNight 极夜，全天太阳都在地平线以下
+	PolarNight = "night"
+)
+
+// 由太阳高度划分的光线阶段
+const (
+	PhaseNight      = "night"
+	PhaseBlueHour   = "blue_hour"
+	PhaseGoldenHour = "golden_hour"
+	PhaseDaylight   = "daylight"
+)
+
+// PhaseOf 太阳高度所处的光线阶段
+func PhaseOf(elevation float64) string {
+	switch {
+	case elevation < AltitudeBlueLow:
+		return PhaseNight
+	case elevation < AltitudeGoldenLow:
+		return PhaseBlueHour
+	case elevation <= AltitudeGoldenHigh:
+		return PhaseGoldenHour
+	}
+	return PhaseDaylight
+}
+
+// Window 一段时间窗口
+type Window struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Duration 窗口时长
+func (w Window) Duration() time.Duration { return w.End.Sub(w.Start) }
+
+// Event 某个时刻及当时的太阳位置
+type Event struct {
+	Time time.Time `json:"time"`
+	Position
+}
+
+// Windows 一天中上午与傍晚的两个窗口，某一侧不存在时为 nil
+type Windows struct {
+	Morning *Window `json:"morning,omitempty"`
+	Evening *Window `json:"evening,omitempty"`
+}
+
+// Light 某地某天的日照信息，时间均为所给时区
+type Light struct {
+	Date      string  `json:"date"`
+	Latitude  float64 `json:"lat"`
+	Longitude float64 `json:"lng"`
+	// Polar 高纬度地区的极昼/极夜，其余情况为空，此时 Sunrise/Sunset 为 nil
+	Polar      string `json:"polar,omitempty"`
+	Sunrise    *Event `json:"sunrise,omitempty"`
+	Sunset     *Event `json:"sunset,omitempty"`
+	SolarNoon  Event  `json:"solar_noon"`
+	DayLengthM int    `json:"day_length_minutes"`
+	// GoldenHour 太阳高度 -4° 到 6°，光线柔和偏暖；太阳全天不超过 6° 时两段在正午相接
+	GoldenHour Windows `json:"golden_hour"`
+	// BlueHour 太阳高度 -6° 到 -4°，天空呈深蓝色
+	BlueHour Windows `json:"blue_hour"`
+}
+
+// Compute 计算当地日期 date（取其年月日与时区）的日照信息
+func Compute(date time.Time, lat, lng float64) *Light {
+	noon := SolarNoon(date, lng)
+	l := &Light{
+		Date:      date.Format("2006-01-02"),
+		Latitude:  lat,
+		Longitude: lng,
+		SolarNoon: eventAt(noon, lat, lng),
+	}
+
+	rise, set, always, never := Crossing(date, lat, lng, AltitudeSunrise)
+	switch {
+	case always:
+		l.Polar = PolarDay
+		l.DayLengthM = 24 * 60
+	case never:
+		l.Polar = PolarNight
+	default:
+		sunrise, sunset := eventAt(rise, lat, lng), eventAt(set, lat, lng)
+		l.Sunrise, l.Sunset = &sunrise, &sunset
+		l.DayLengthM = int(set.Sub(rise).Minutes())
+	}
+
+	l.GoldenHour = band(date, noon, lat, lng, AltitudeGoldenLow, AltitudeGoldenHigh)
+	l.BlueHour = band(date, noon, lat, lng, AltitudeBlueLow, AltitudeGoldenLow)
+	return l
+}
+
+// Track 从 from 到 to 每隔 step 的太阳位置
+func Track(from, to time.Time, step time.Duration, lat, lng float64) []Event {
+	if step <= 0 || to.Before(from) {
+		return nil
+	}
+	var out []Event
+	for t := from; !t.After(to); t = t.Add(step) {
+		out = append(out, eventAt(t, lat, lng))
+	}
+	return out
+}
+
+// band 太阳高度在 [low, high] 之间的上午与傍晚窗口
+func band(date, noon time.Time, lat, lng, low, high float64) Windows {
+	lowRise, lowSet, lowAlways, lowNever := Crossing(date, lat, lng, low)
+	if lowNever {
+		return Windows{}
+	}
+	highRise, highSet, highAlways, highNever := Crossing(date, lat, lng, high)
+	if highAlways {
+		return Windows{}
+	}
+	switch {
+	case lowAlways && highNever:
+		// 全天都在区间内，取整个自然日
+		y, m, d := date.Date()
+		start := time.Date(y, m, d, 0, 0, 0, 0, date.Location())
+		return Windows{Morning: &Window{start, noon}, Evening: &Window{noon, start.AddDate(0, 0, 1)}}
+	case lowAlways:
+		// 太阳不落到 low 以下：只有正午前后越过 high 的两段
+		y, m, d := date.Date()
+		start := time.Date(y, m, d, 0, 0, 0, 0, date.Location())
+		return Windows{Morning: &Window{start, highRise}, Evening: &Window{highSet, start.AddDate(0, 0, 1)}}
+	case highNever:
+		// 太阳升不到 high：从 low 升起到正午、正午到 low 落下
+		return Windows{Morning: &Window{lowRise, noon}, Evening: &Window{noon, lowSet}}
+	}
+	return Windows{Morning: &Window{lowRise, highRise}, Evening: &Window{highSet, lowSet}}
+}
+
+func eventAt(t time.Time, lat, lng float64) Event {
+	return Event{Time: t, Position: PositionAt(t, lat, lng)}
+}
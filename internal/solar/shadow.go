@@ -0,0 +1,107 @@
+package solar
+
+import "math"
+
+// 构图的光线方向
+const (
+	LightingFront     = "front"
+	LightingSideLeft  = "side_left"
+	LightingSideRight = "side_right"
+	LightingBack      = "back"
+)
+
+// maxShadowRatio 太阳很低时影长趋于无穷，超过该值不再给出
+const maxShadowRatio = 50
+
+// Shadow 竖直物体的影子：Direction 为影子指向的方位角，LengthRatio 为影长与物高之比。
+// 太阳很低时 TooLong 为 true，不给出 LengthRatio
+type Shadow struct {
+	Direction     float64 `json:"direction"`
+	DirectionName string  `json:"direction_name"`
+	LengthRatio   float64 `json:"length_ratio,omitempty"`
+	TooLong       bool    `json:"too_long,omitempty"`
+}
+
+// Composition 一种光线方向的构图建议。CameraBearing 为镜头朝向（从机位指向主体），
+// StandBearing 为机位相对主体的方位（从主体指向机位）
+type Composition struct {
+	Lighting        string  `json:"lighting"`
+	CameraBearing   float64 `json:"camera_bearing"`
+	CameraDirection string  `json:"camera_direction"`
+	StandBearing    float64 `json:"stand_bearing"`
+	StandDirection  string  `json:"stand_direction"`
+	Tip             string  `json:"tip"`
+}
+
+// ShadowOf 太阳在地平线以下时返回 nil
+func ShadowOf(p Position) *Shadow {
+	if p.Elevation <= 0 {
+		return nil
+	}
+	dir := normalize(p.Azimuth + 180)
+	s := &Shadow{Direction: dir, DirectionName: Compass(dir)}
+	ratio := 1 / math.Tan(rad(p.Elevation))
+	if ratio > maxShadowRatio {
+		s.TooLong = true
+	} else {
+		s.LengthRatio = round2(ratio)
+	}
+	return s
+}
+
+// Compositions 顺光、左右侧光与逆光四种构图的镜头朝向与机位方位
+func Compositions(p Position) []Composition {
+	backTip := "逆光：镜头朝向太阳，主体边缘有轮廓光，适合剪影与发丝光，注意遮光与补光"
+	switch {
+	case p.Elevation <= 0:
+		backTip = "逆光：太阳已在地平线下，天空余晖作背景，适合剪影"
+	case p.Elevation < 10:
+		backTip = "逆光：太阳很低，可把太阳放进画面或藏在主体身后做剪影、光晕"
+	case p.Elevation > 45:
+		backTip = "逆光：太阳较高，逆光效果弱，主体顶部受光，可找遮挡物控制眩光"
+	}
+	frontTip := "顺光：太阳在身后，主体受光均匀、色彩饱和，注意自己的影子不要入镜"
+	if p.Elevation > 45 {
+		frontTip = "顺光：太阳高，顶光明显，人像眼窝阴影重，可压低帽檐或找阴影处"
+	}
+	sideTip := "侧光：光影对比强，立体感好，适合建筑纹理与人像轮廓"
+
+	return []Composition{
+		composition(LightingFront, p.Azimuth+180, frontTip),
+		composition(LightingSideLeft, p.Azimuth+90, "太阳在镜头左侧。"+sideTip),
+		composition(LightingSideRight, p.Azimuth-90, "太阳在镜头右侧。"+sideTip),
+		composition(LightingBack, p.Azimuth, backTip),
+	}
+}
+
+func composition(lighting string, camera float64, tip string) Composition {
+	camera = normalize(camera)
+	stand := normalize(camera + 180)
+	return Composition{
+		Lighting:        lighting,
+		CameraBearing:   round2(camera),
+		CameraDirection: Compass(camera),
+		StandBearing:    round2(stand),
+		StandDirection:  Compass(stand),
+		Tip:             tip,
+	}
+}
+
+var compassNames = []string{"北", "东北", "东", "东南", "南", "西南", "西", "西北"}
+
+// Compass 方位角对应的八方位名称，如 "西南"
+func Compass(bearing float64) string {
+	i := int(math.Floor(normalize(bearing)/45+0.5)) % len(compassNames)
+	return compassNames[i]
+}
+
+func normalize(bearing float64) float64 {
+	b := round2(math.Mod(bearing, 360))
+	if b < 0 {
+		b += 360
+	}
+	if b >= 360 {
+		b -= 360
+	}
+	return b
+}
@@ -0,0 +1,187 @@
+// Package solar 计算太阳位置与日出日落等时刻，算法取自 NOAA Solar Calculator，
+// 1800-2100 年间中低纬度误差在 1 分钟以内。角度单位均为度，方位角自正北顺时针
+package solar
+
+import (
+	"math"
+	"time"
+)
+
+// 太阳中心的高度角阈值
+const (
+	// AltitudeSunrise 日出日落：太阳上边缘与地平线相切（含大气折射与视半径）
+	AltitudeSunrise = -0.833
+	// AltitudeGoldenHigh / AltitudeGoldenLow 黄金时刻：太阳高度在 -4° 到 6° 之间
+	AltitudeGoldenHigh = 6.0
+	AltitudeGoldenLow  = -4.0
+	// AltitudeBlueLow 蓝调时刻：太阳高度在 -6° 到 -4° 之间，-6° 也是民用晨昏蒙影的边界
+	AltitudeBlueLow = -6.0
+)
+
+// Position 太阳位置。Elevation 含大气折射修正
+type Position struct {
+	Azimuth   float64 `json:"azimuth"`
+	Elevation float64 `json:"elevation"`
+}
+
+// PositionAt 某时刻某地的太阳位置
+func PositionAt(t time.Time, lat, lng float64) Position {
+	jc := julianCentury(t)
+	decl, eqTime := declination(jc), equationOfTime(jc)
+
+	utc := t.UTC()
+	minutes := float64(utc.Hour()*60+utc.Minute()) + float64(utc.Second())/60 + float64(utc.Nanosecond())/6e10
+	trueSolarTime := math.Mod(minutes+eqTime+4*lng, 1440)
+	if trueSolarTime < 0 {
+		trueSolarTime += 1440
+	}
+	hourAngle := trueSolarTime/4 - 180
+
+	latR, declR, haR := rad(lat), rad(decl), rad(hourAngle)
+	cosZenith := math.Sin(latR)*math.Sin(declR) + math.Cos(latR)*math.Cos(declR)*math.Cos(haR)
+	zenith := deg(math.Acos(clamp(cosZenith)))
+
+	var azimuth float64
+	if denom := math.Cos(latR) * math.Sin(rad(zenith)); math.Abs(denom) > 1e-9 {
+		a := deg(math.Acos(clamp((math.Sin(latR)*math.Cos(rad(zenith)) - math.Sin(declR)) / denom)))
+		if hourAngle > 0 {
+			azimuth = math.Mod(a+180, 360)
+		} else {
+			azimuth = math.Mod(540-a, 360)
+		}
+	} else if lat > 0 {
+		// 太阳在天顶或观测者在极点，方位角没有意义，取正南/正北
+		azimuth = 180
+	}
+
+	elevation := 90 - zenith
+	return Position{Azimuth: round2(azimuth), Elevation: round2(elevation + refraction(elevation))}
+}
+
+// SolarNoon 当地日期 date（取其年月日与时区）的太阳正午
+func SolarNoon(date time.Time, lng float64) time.Time {
+	y, m, d := date.Date()
+	loc := date.Location()
+	noon := solarNoonUTC(time.Date(y, m, d, 0, 0, 0, 0, time.UTC), lng)
+	// 经度与时区相差较大时，UTC 日期上的正午可能落在当地的前一天或后一天
+	for i := 0; i < 2; i++ {
+		ly, lm, ld := noon.In(loc).Date()
+		switch diff := time.Date(ly, lm, ld, 0, 0, 0, 0, time.UTC).Sub(time.Date(y, m, d, 0, 0, 0, 0, time.UTC)); {
+		case diff > 0:
+			noon = solarNoonUTC(time.Date(y, m, d-1, 0, 0, 0, 0, time.UTC), lng)
+		case diff < 0:
+			noon = solarNoonUTC(time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC), lng)
+		default:
+			return noon.In(loc)
+		}
+	}
+	return noon.In(loc)
+}
+
+// Crossing 当地日期 date 太阳中心经过高度角 altitude 的上升与下降时刻。
+// 全天都在 altitude 之上时 always 为 true，全天都在之下时 never 为 true，此时 rise/set 为零值
+func Crossing(date time.Time, lat, lng, altitude float64) (rise, set time.Time, always, never bool) {
+	noon := SolarNoon(date, lng)
+	rise, always, never = crossingNear(noon, lat, lng, altitude, -1)
+	if always || never {
+		return time.Time{}, time.Time{}, always, never
+	}
+	set, always, never = crossingNear(noon, lat, lng, altitude, 1)
+	if always || never {
+		return time.Time{}, time.Time{}, always, never
+	}
+	return rise.In(date.Location()), set.In(date.Location()), false, false
+}
+
+// crossingNear 从正午出发迭代求高度角为 altitude 的时刻，sign 为 -1 求上午、1 求下午
+func crossingNear(noon time.Time, lat, lng, altitude, sign float64) (time.Time, bool, bool) {
+	t := noon
+	for i := 0; i < 3; i++ {
+		jc := julianCentury(t)
+		decl := rad(declination(jc))
+		latR := rad(lat)
+		cosHA := (math.Sin(rad(altitude)) - math.Sin(latR)*math.Sin(decl)) / (math.Cos(latR) * math.Cos(decl))
+		if cosHA < -1 {
+			return time.Time{}, true, false
+		}
+		if cosHA > 1 {
+			return time.Time{}, false, true
+		}
+		ha := deg(math.Acos(cosHA))
+		// 以当次估计时刻的均时差修正正午
+		noonAt := noon.Add(time.Duration((equationOfTime(julianCentury(noon)) - equationOfTime(jc)) * float64(time.Minute)))
+		t = noonAt.Add(time.Duration(sign * 4 * ha * float64(time.Minute)))
+	}
+	return t.Truncate(time.Second), false, false
+}
+
+func solarNoonUTC(midnightUTC time.Time, lng float64) time.Time {
+	// 先用正午近似值求均时差，再迭代一次
+	t := midnightUTC.Add(time.Duration((720 - 4*lng) * float64(time.Minute)))
+	for i := 0; i < 2; i++ {
+		minutes := 720 - 4*lng - equationOfTime(julianCentury(t))
+		t = midnightUTC.Add(time.Duration(minutes * float64(time.Minute)))
+	}
+	return t.Truncate(time.Second)
+}
+
+func julianCentury(t time.Time) float64 {
+	jd := float64(t.UnixNano())/86400e9 + 2440587.5
+	return (jd - 2451545) / 36525
+}
+
+func sunParams(jc float64) (meanLong, meanAnom, ecc float64) {
+	meanLong = math.Mod(280.46646+jc*(36000.76983+jc*0.0003032), 360)
+	meanAnom = 357.52911 + jc*(35999.05029-0.0001537*jc)
+	ecc = 0.016708634 - jc*(0.000042037+0.0000001267*jc)
+	return
+}
+
+func obliquity(jc float64) float64 {
+	mean := 23 + (26+(21.448-jc*(46.815+jc*(0.00059-jc*0.001813)))/60)/60
+	return mean + 0.00256*math.Cos(rad(125.04-1934.136*jc))
+}
+
+// declination 太阳赤纬
+func declination(jc float64) float64 {
+	meanLong, meanAnom, _ := sunParams(jc)
+	m := rad(meanAnom)
+	center := math.Sin(m)*(1.914602-jc*(0.004817+0.000014*jc)) + math.Sin(2*m)*(0.019993-0.000101*jc) + math.Sin(3*m)*0.000289
+	appLong := meanLong + center - 0.00569 - 0.00478*math.Sin(rad(125.04-1934.136*jc))
+	return deg(math.Asin(math.Sin(rad(obliquity(jc))) * math.Sin(rad(appLong))))
+}
+
+// equationOfTime 均时差（分钟）
+func equationOfTime(jc float64) float64 {
+	meanLong, meanAnom, ecc := sunParams(jc)
+	y := math.Pow(math.Tan(rad(obliquity(jc))/2), 2)
+	l, m := rad(meanLong), rad(meanAnom)
+	e := y*math.Sin(2*l) - 2*ecc*math.Sin(m) + 4*ecc*y*math.Sin(m)*math.Cos(2*l) -
+		0.5*y*y*math.Sin(4*l) - 1.25*ecc*ecc*math.Sin(2*m)
+	return 4 * deg(e)
+}
+
+// refraction 大气折射修正（度）
+func refraction(elevation float64) float64 {
+	if elevation > 85 {
+		return 0
+	}
+	te := math.Tan(rad(elevation))
+	var arcsec float64
+	switch {
+	case elevation > 5:
+		arcsec = 58.1/te - 0.07/math.Pow(te, 3) + 0.000086/math.Pow(te, 5)
+	case elevation > -0.575:
+		arcsec = 1735 + elevation*(-518.2+elevation*(103.4+elevation*(-12.79+elevation*0.711)))
+	default:
+		arcsec = -20.772 / te
+	}
+	return arcsec / 3600
+}
+
+func rad(d float64) float64 { return d * math.Pi / 180 }
+func deg(r float64) float64 { return r * 180 / math.Pi }
+
+func clamp(v float64) float64 { return math.Max(-1, math.Min(1, v)) }
+
+func round2(v float64) float64 { return math.Round(v*100) / 100 }
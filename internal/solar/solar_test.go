@@ -0,0 +1,183 @@
+package solar
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// 参考值取自 NOAA Solar Calculator（精确到分钟）；方位角与高度角另用 NREL SPA 论文的算例
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name     string
+		date     time.Time
+		lat, lng float64
+
+		sunrise, noon, sunset string
+		// civilDawn / civilDusk 民用晨昏蒙影（-6°），即蓝调时刻的起止
+		civilDawn, civilDusk string
+		noonElevation        float64
+		sunriseAzimuth       float64
+	}{
+		{
+			name: "london summer solstice",
+			date: time.Date(2024, 6, 21, 0, 0, 0, 0, time.FixedZone("BST", 3600)),
+			lat:  51.5074, lng: -0.1278,
+			sunrise: "04:43", noon: "13:02", sunset: "21:21",
+			civilDawn: "03:56", civilDusk: "22:09",
+			noonElevation: 61.9, sunriseAzimuth: 48.9,
+		},
+		{
+			name: "beijing summer solstice",
+			date: time.Date(2024, 6, 21, 0, 0, 0, 0, time.FixedZone("CST", 8*3600)),
+			lat:  39.9042, lng: 116.4074,
+			sunrise: "04:46", noon: "12:16", sunset: "19:46",
+			civilDawn: "04:13", civilDusk: "20:19",
+			noonElevation: 73.5, sunriseAzimuth: 57.9,
+		},
+		{
+			name: "beijing winter solstice",
+			date: time.Date(2024, 12, 21, 0, 0, 0, 0, time.FixedZone("CST", 8*3600)),
+			lat:  39.9042, lng: 116.4074,
+			sunrise: "07:33", noon: "12:12", sunset: "16:53",
+			civilDawn: "07:02", civilDusk: "17:23",
+			noonElevation: 26.7, sunriseAzimuth: 120.4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := Compute(tt.date, tt.lat, tt.lng)
+			if l.Polar != "" || l.Sunrise == nil || l.Sunset == nil {
+				t.Fatalf("Polar = %q, Sunrise = %v, Sunset = %v", l.Polar, l.Sunrise, l.Sunset)
+			}
+			checkTime(t, "sunrise", l.Sunrise.Time, tt.date, tt.sunrise)
+			checkTime(t, "solar noon", l.SolarNoon.Time, tt.date, tt.noon)
+			checkTime(t, "sunset", l.Sunset.Time, tt.date, tt.sunset)
+			checkAngle(t, "noon elevation", l.SolarNoon.Elevation, tt.noonElevation)
+			checkAngle(t, "noon azimuth", l.SolarNoon.Azimuth, 180)
+			checkAngle(t, "sunrise azimuth", l.Sunrise.Azimuth, tt.sunriseAzimuth)
+			checkAngle(t, "sunset azimuth", l.Sunset.Azimuth, 360-tt.sunriseAzimuth)
+
+			if l.BlueHour.Morning == nil || l.BlueHour.Evening == nil || l.GoldenHour.Morning == nil || l.GoldenHour.Evening == nil {
+				t.Fatalf("missing windows: golden %+v, blue %+v", l.GoldenHour, l.BlueHour)
+			}
+			checkTime(t, "civil dawn", l.BlueHour.Morning.Start, tt.date, tt.civilDawn)
+			checkTime(t, "civil dusk", l.BlueHour.Evening.End, tt.date, tt.civilDusk)
+			// 蓝调与黄金时刻在 -4° 处相接，黄金时刻在 6° 处结束
+			if !l.BlueHour.Morning.End.Equal(l.GoldenHour.Morning.Start) || !l.GoldenHour.Evening.End.Equal(l.BlueHour.Evening.Start) {
+				t.Errorf("blue and golden hours do not meet: golden %+v, blue %+v", l.GoldenHour, l.BlueHour)
+			}
+			for _, b := range []struct {
+				name string
+				at   time.Time
+				want float64
+			}{
+				{"golden hour start", l.GoldenHour.Morning.Start, AltitudeGoldenLow},
+				{"golden hour end", l.GoldenHour.Morning.End, AltitudeGoldenHigh},
+				{"evening golden hour start", l.GoldenHour.Evening.Start, AltitudeGoldenHigh},
+				{"evening golden hour end", l.GoldenHour.Evening.End, AltitudeGoldenLow},
+			} {
+				// 边界按几何高度计算，PositionAt 含大气折射，低仰角时相差不到 0.5°
+				if got := PositionAt(b.at, tt.lat, tt.lng).Elevation; math.Abs(got-b.want) > 0.5 {
+					t.Errorf("%s at %s: elevation %.2f, want %.1f", b.name, b.at.Format("15:04:05"), got, b.want)
+				}
+			}
+		})
+	}
+}
+
+// NREL SPA 论文的算例：2003-10-17 12:30:30（UTC-7），天顶角 50.11162°，方位角 194.34024°
+func TestPositionAt(t *testing.T) {
+	p := PositionAt(time.Date(2003, 10, 17, 12, 30, 30, 0, time.FixedZone("", -7*3600)), 39.742476, -105.1786)
+	checkAngle(t, "azimuth", p.Azimuth, 194.34024)
+	checkAngle(t, "elevation", p.Elevation, 90-50.11162)
+}
+
+func TestComputePolar(t *testing.T) {
+	tromso, svalbard := [2]float64{69.6492, 18.9553}, [2]float64{78.2232, 15.6267}
+	tests := []struct {
+		name     string
+		date     time.Time
+		lat, lng float64
+		polar    string
+		// golden / blue 两侧窗口是否存在
+		golden, blue bool
+		dayLength    int
+	}{
+		{"tromso midnight sun", time.Date(2024, 6, 21, 0, 0, 0, 0, time.FixedZone("", 2*3600)), tromso[0], tromso[1], PolarDay, true, false, 24 * 60},
+		{"tromso polar night", time.Date(2024, 12, 21, 0, 0, 0, 0, time.FixedZone("", 3600)), tromso[0], tromso[1], PolarNight, true, true, 0},
+		{"svalbard polar night", time.Date(2024, 12, 21, 0, 0, 0, 0, time.FixedZone("", 3600)), svalbard[0], svalbard[1], PolarNight, false, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := Compute(tt.date, tt.lat, tt.lng)
+			if l.Polar != tt.polar || l.Sunrise != nil || l.Sunset != nil || l.DayLengthM != tt.dayLength {
+				t.Errorf("Polar = %q, Sunrise = %v, Sunset = %v, day length %d; want %q, %d", l.Polar, l.Sunrise, l.Sunset, l.DayLengthM, tt.polar, tt.dayLength)
+			}
+			if got := l.GoldenHour.Morning != nil && l.GoldenHour.Evening != nil; got != tt.golden {
+				t.Errorf("golden hour = %+v, want present %v", l.GoldenHour, tt.golden)
+			}
+			if !tt.golden && (l.GoldenHour.Morning != nil || l.GoldenHour.Evening != nil) {
+				t.Errorf("golden hour = %+v, want none", l.GoldenHour)
+			}
+			if got := l.BlueHour.Morning != nil && l.BlueHour.Evening != nil; got != tt.blue {
+				t.Errorf("blue hour = %+v, want present %v", l.BlueHour, tt.blue)
+			}
+			if !tt.blue && (l.BlueHour.Morning != nil || l.BlueHour.Evening != nil) {
+				t.Errorf("blue hour = %+v, want none", l.BlueHour)
+			}
+		})
+	}
+
+	// 极昼时太阳不落到 -4° 以下，黄金时刻从零点到升过 6°、从落到 6° 到次日零点
+	l := Compute(tests[0].date, tromso[0], tromso[1])
+	if !l.GoldenHour.Morning.Start.Equal(tests[0].date) || !l.GoldenHour.Evening.End.Equal(tests[0].date.AddDate(0, 0, 1)) {
+		t.Errorf("midnight sun golden hour = %+v, want the whole night", l.GoldenHour)
+	}
+	// 极夜时太阳升不到 6°，两段黄金时刻在正午相接
+	l = Compute(tests[1].date, tromso[0], tromso[1])
+	if !l.GoldenHour.Morning.End.Equal(l.SolarNoon.Time) || !l.GoldenHour.Evening.Start.Equal(l.SolarNoon.Time) {
+		t.Errorf("polar night golden hour = %+v, want to meet at solar noon %v", l.GoldenHour, l.SolarNoon.Time)
+	}
+}
+
+func TestPhaseOf(t *testing.T) {
+	tests := []struct {
+		elevation float64
+		want      string
+	}{
+		{-10, PhaseNight},
+		{-6, PhaseBlueHour},
+		{-5, PhaseBlueHour},
+		{-4, PhaseGoldenHour},
+		{6, PhaseGoldenHour},
+		{6.1, PhaseDaylight},
+	}
+	for _, tt := range tests {
+		if got := PhaseOf(tt.elevation); got != tt.want {
+			t.Errorf("PhaseOf(%v) = %q, want %q", tt.elevation, got, tt.want)
+		}
+	}
+}
+
+// checkTime 参考值精确到分钟，允许 1 分钟误差
+func checkTime(t *testing.T, name string, got, date time.Time, want string) {
+	t.Helper()
+	clock, err := time.Parse("15:04", want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	y, m, d := date.Date()
+	ref := time.Date(y, m, d, clock.Hour(), clock.Minute(), 0, 0, date.Location())
+	if diff := got.Sub(ref); diff < -time.Minute || diff > time.Minute {
+		t.Errorf("%s = %s, want %s ±1m", name, got.In(date.Location()).Format("15:04:05"), want)
+	}
+}
+
+// checkAngle 允许 0.1° 误差
+func checkAngle(t *testing.T, name string, got, want float64) {
+	t.Helper()
+	if math.Abs(got-want) > 0.1 {
+		t.Errorf("%s = %.2f°, want %.2f°", name, got, want)
+	}
+}
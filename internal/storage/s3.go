@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Config S3 兼容存储（AWS S3、MinIO、OSS、COS 等）的连接参数
+type S3Config struct {
+	// Endpoint 不含协议，如 s3.amazonaws.com 或 localhost:9000
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+	// Prefix 所有 key 的公共前缀，用于多个环境共用一个 bucket
+	Prefix string
+	// CreateBucket bucket 不存在时创建，便于本地 MinIO 开发
+	CreateBucket bool
+}
+
+// S3Store S3 兼容的对象存储
+type S3Store struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+func NewS3Store(ctx context.Context, cfg S3Config) (*S3Store, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("S3_ENDPOINT and S3_BUCKET are required")
+	}
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	exists, err := client.BucketExists(ctx, cfg.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("check bucket %s: %w", cfg.Bucket, err)
+	}
+	if !exists {
+		if !cfg.CreateBucket {
+			return nil, fmt.Errorf("bucket %s does not exist", cfg.Bucket)
+		}
+		if err := client.MakeBucket(ctx, cfg.Bucket, minio.MakeBucketOptions{Region: cfg.Region}); err != nil {
+			return nil, fmt.Errorf("create bucket %s: %w", cfg.Bucket, err)
+		}
+	}
+	prefix := strings.Trim(cfg.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &S3Store{client: client, bucket: cfg.Bucket, prefix: prefix}, nil
+}
+
+func (s *S3Store) object(key string) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	return s.prefix + key, nil
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (Info, error) {
+	name, err := s.object(key)
+	if err != nil {
+		return Info{}, err
+	}
+	// minio 以 -1 表示大小未知，按分片流式上传
+	size := opts.Size
+	if size < 0 {
+		size = -1
+	}
+	_, err = s.client.PutObject(ctx, s.bucket, name, r, size, minio.PutObjectOptions{
+		ContentType:  opts.ContentType,
+		CacheControl: opts.CacheControl,
+	})
+	if err != nil {
+		return Info{}, err
+	}
+	return s.Stat(ctx, key)
+}
+
+// Get 返回的 *minio.Object 支持 Seek，可直接用于 http.ServeContent
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, Info, error) {
+	name, err := s.object(key)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	obj, err := s.client.GetObject(ctx, s.bucket, name, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, Info{}, s3Error(err)
+	}
+	st, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		return nil, Info{}, s3Error(err)
+	}
+	return obj, s.info(st), nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	name, err := s.object(key)
+	if err != nil {
+		return err
+	}
+	return s.client.RemoveObject(ctx, s.bucket, name, minio.RemoveObjectOptions{})
+}
+
+func (s *S3Store) Stat(ctx context.Context, key string) (Info, error) {
+	name, err := s.object(key)
+	if err != nil {
+		return Info{}, err
+	}
+	st, err := s.client.StatObject(ctx, s.bucket, name, minio.StatObjectOptions{})
+	if err != nil {
+		return Info{}, s3Error(err)
+	}
+	return s.info(st), nil
+}
+
+func (s *S3Store) List(ctx context.Context, prefix string, fn func(Info) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // fn 提前返回时结束后台的列举
+	for obj := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: s.prefix + prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return obj.Err
+		}
+		if err := fn(s.info(obj)); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+func (s *S3Store) info(obj minio.ObjectInfo) Info {
+	return Info{
+		Key:         strings.TrimPrefix(obj.Key, s.prefix),
+		Size:        obj.Size,
+		ContentType: obj.ContentType,
+		ModTime:     obj.LastModified,
+		ETag:        `"` + strings.Trim(obj.ETag, `"`) + `"`,
+	}
+}
+
+func s3Error(err error) error {
+	resp := minio.ToErrorResponse(err)
+	if resp.Code == "NoSuchKey" || resp.StatusCode == 404 {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestS3Store 需要 S3 兼容服务，未设置 S3_TEST_ENDPOINT 时跳过。
+// 使用 docker-compose 中的 MinIO：
+//
+//	docker compose up -d minio
+//	S3_TEST_ENDPOINT=localhost:9000 go test ./internal/storage -run S3
+func TestS3Store(t *testing.T) {
+	endpoint := os.Getenv("S3_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("S3_TEST_ENDPOINT not set")
+	}
+	ctx := context.Background()
+	cfg := S3Config{
+		Endpoint:     endpoint,
+		Bucket:       envOr("S3_TEST_BUCKET", "photo-backend-test"),
+		AccessKey:    envOr("S3_TEST_ACCESS_KEY", "minioadmin"),
+		SecretKey:    envOr("S3_TEST_SECRET_KEY", "minioadmin"),
+		UseSSL:       os.Getenv("S3_TEST_USE_SSL") == "true",
+		CreateBucket: true,
+		// 每次运行使用独立前缀，互不干扰
+		Prefix: fmt.Sprintf("test-%d", time.Now().UnixNano()),
+	}
+	store, err := NewS3Store(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testBlobStore(t, store)
+
+	cfg.Bucket += "-missing"
+	cfg.CreateBucket = false
+	if _, err := NewS3Store(ctx, cfg); err == nil {
+		t.Error("NewS3Store with a missing bucket and CreateBucket=false succeeded")
+	}
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
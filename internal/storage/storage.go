@@ -0,0 +1,113 @@
+// Package storage 照片等二进制对象的存储
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/huangqi/photo-backend/internal/config"
+)
+
+// 支持的存储后端
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("blob not found")
+
+// Info 对象元信息
+type Info struct {
+	Key         string    `json:"key"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type,omitempty"`
+	ModTime     time.Time `json:"mod_time"`
+	// ETag 本地存储为大小与修改时间的组合，S3 为服务端返回的 ETag
+	ETag string `json:"etag,omitempty"`
+}
+
+// UnknownSize PutOptions.Size 的取值，表示写入前不知道大小
+const UnknownSize int64 = -1
+
+// PutOptions 写入选项。Size 为对象字节数，0 表示空对象，负数（UnknownSize）表示未知
+type PutOptions struct {
+	ContentType  string
+	CacheControl string
+	Size         int64
+}
+
+// BlobStore 以 key 寻址的对象存储。key 使用 / 分隔，不能以 / 开头，不能包含 .. 段
+type BlobStore interface {
+	// Put 流式写入，同名对象被覆盖
+	Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (Info, error)
+	// Get 返回的 ReadCloser 由调用方关闭；本地与 S3 实现同时实现 io.Seeker
+	Get(ctx context.Context, key string) (io.ReadCloser, Info, error)
+	// Delete 删除不存在的对象不报错
+	Delete(ctx context.Context, key string) error
+	// Stat 不存在时返回 ErrNotFound
+	Stat(ctx context.Context, key string) (Info, error)
+	// List 按 key 前缀遍历，fn 返回错误时停止并返回该错误
+	List(ctx context.Context, prefix string, fn func(Info) error) error
+}
+
+// New 按 cfg.StorageBackend 创建存储
+func New(ctx context.Context, cfg config.Config) (BlobStore, error) {
+	switch cfg.StorageBackend {
+	case "", BackendLocal:
+		store, err := NewLocalStore(cfg.UploadDir)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case BackendS3:
+		store, err := NewS3Store(ctx, S3Config{
+			Endpoint:     cfg.S3Endpoint,
+			Region:       cfg.S3Region,
+			Bucket:       cfg.S3Bucket,
+			AccessKey:    cfg.S3AccessKey,
+			SecretKey:    cfg.S3SecretKey,
+			UseSSL:       cfg.S3UseSSL,
+			Prefix:       cfg.S3Prefix,
+			CreateBucket: cfg.S3CreateBucket,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unsupported STORAGE_BACKEND %q (want %s or %s)", cfg.StorageBackend, BackendLocal, BackendS3)
+	}
+}
+
+// ContentKey 内容寻址的 key：<prefix>/<sha256 hex><ext>
+func ContentKey(prefix string, sum [sha256.Size]byte, ext string) string {
+	name := hex.EncodeToString(sum[:]) + ext
+	if prefix == "" {
+		return name
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + name
+}
+
+// validKey 拒绝绝对路径、.. 段与空段
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.HasSuffix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	if path.Clean(key) != key {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	for _, seg := range strings.Split(key, "/") {
+		if seg == ".." || seg == "." || seg == "" {
+			return fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// testBlobStore 本地与 S3 实现共用的行为测试
+func testBlobStore(t *testing.T, store BlobStore) {
+	ctx := context.Background()
+	content := []byte("hello blob")
+	key := ContentKey("originals", sha256.Sum256(content), ".jpg")
+
+	if _, err := store.Stat(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Stat before Put: err = %v, want ErrNotFound", err)
+	}
+	if _, _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get before Put: err = %v, want ErrNotFound", err)
+	}
+
+	info, err := store.Put(ctx, key, bytes.NewReader(content), PutOptions{ContentType: "image/jpeg", Size: int64(len(content))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Key != key || info.Size != int64(len(content)) || info.ETag == "" {
+		t.Errorf("Put info = %+v", info)
+	}
+	if info.ContentType != "image/jpeg" {
+		t.Errorf("ContentType = %q, want image/jpeg", info.ContentType)
+	}
+
+	rc, got, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, content) || got.Size != int64(len(content)) {
+		t.Errorf("Get = %q (%d bytes), want %q", data, got.Size, content)
+	}
+	if _, ok := rc.(io.Seeker); !ok {
+		t.Errorf("Get returned %T, want an io.Seeker", rc)
+	}
+
+	// 大小未知时也能流式写入，同名对象被覆盖
+	if _, err := store.Put(ctx, key, strings.NewReader("replaced"), PutOptions{Size: UnknownSize}); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := store.Stat(ctx, key); err != nil || info.Size != int64(len("replaced")) {
+		t.Errorf("Stat after overwrite = %+v, %v", info, err)
+	}
+
+	// 空对象的 Size 为 0，不当作未知
+	if _, err := store.Put(ctx, "empty", bytes.NewReader(nil), PutOptions{Size: 0}); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := store.Stat(ctx, "empty"); err != nil || info.Size != 0 {
+		t.Errorf("Stat of empty object = %+v, %v", info, err)
+	}
+
+	if _, err := store.Put(ctx, "variants/a.webp", strings.NewReader("a"), PutOptions{Size: 1}); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	if err := store.List(ctx, "originals/", func(i Info) error {
+		keys = append(keys, i.Key)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != key {
+		t.Errorf("List(originals/) = %v, want [%s]", keys, key)
+	}
+	stop := errors.New("stop")
+	if err := store.List(ctx, "", func(Info) error { return stop }); !errors.Is(err, stop) {
+		t.Errorf("List with failing callback: err = %v, want stop", err)
+	}
+
+	for _, k := range []string{key, "empty", "variants/a.webp"} {
+		if err := store.Delete(ctx, k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.Stat(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat after Delete: err = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of missing object: %v", err)
+	}
+
+	for _, bad := range []string{"", "/abs", "a/../b", "a//b", "a/", `a\b`, "./a"} {
+		if _, err := store.Put(ctx, bad, strings.NewReader("x"), PutOptions{Size: 1}); err == nil {
+			t.Errorf("Put(%q) succeeded, want invalid key error", bad)
+		}
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testBlobStore(t, store)
+}
+
+func TestContentKey(t *testing.T) {
+	sum := sha256.Sum256([]byte("x"))
+	tests := []struct {
+		prefix, ext, want string
+	}{
+		{"", ".jpg", "2d711642b726b04401627ca9fbac32f5c8530fb1903cc4db02258717921a4881.jpg"},
+		{"originals", ".png", "originals/2d711642b726b04401627ca9fbac32f5c8530fb1903cc4db02258717921a4881.png"},
+		{"originals/", "", "originals/2d711642b726b04401627ca9fbac32f5c8530fb1903cc4db02258717921a4881"},
+	}
+	for _, tt := range tests {
+		if got := ContentKey(tt.prefix, sum, tt.ext); got != tt.want {
+			t.Errorf("ContentKey(%q, %q) = %q, want %q", tt.prefix, tt.ext, got, tt.want)
+		}
+	}
+}